@@ -3,62 +3,123 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 
 	"go.uber.org/zap"
 
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/app"
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/config"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/ratelimit"
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/logger"
+	urlhandler "github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler"
 )
 
 func main() {
 
-	// Загружает конфиг из env
+	// Загружает конфиг из флагов, env и файла
 	cfg, err := config.LoadConfig()
 	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
 		log.Fatalf("fail load config: %v", err)
 	}
 
+	if cfg.PrintConfig {
+		data, err := cfg.JSON()
+		if err != nil {
+			log.Fatalf("fail print config: %v", err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
 	log := logger.MustLogger(cfg.App.LogLevel)
 	defer log.Sync()
 	log.Info("launching a url shortener...")
 	log.Debug("debug messages enabled")
 
-	// Дополним конфиг из флагов, если env переменные не заданы
-	parseFlags(
-		&cfg.HTTP.Host,
-		&cfg.URLShortener.RedirectHost,
-		&cfg.Storage.File.PATH,
-		&cfg.Storage.Postgres.DNS,
-	)
-
 	// Основной контекст api сервера
 	// Не отменяется при отмене errgroup
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Только миграции БД, без запуска сервера
+	if len(cfg.Storage.Postgres.Migrate) > 0 {
+		if err := app.Migrate(
+			ctx,
+			log,
+			cfg.Storage.Postgres.DNS,
+			cfg.Storage.Postgres.Migrate,
+		); err != nil {
+			log.Fatal("failed to migrate database", zap.Error(err))
+		}
+		return
+	}
+
 	urlShortener, err := app.NewURLShortener(
 		ctx,
 		log,
 		app.Option{
-			Host:            cfg.HTTP.Host,
-			RedirectHost:    cfg.URLShortener.RedirectHost,
-			ReadTimeout:     cfg.HTTP.ReadTimeout,
-			WriteTimeout:    cfg.HTTP.WriteTimeout,
-			IdleTimeout:     cfg.HTTP.IdleTimeout,
-			ShutdownTimeout: cfg.HTTP.ShutdownTimeout,
-			StorageFilePath: cfg.Storage.File.PATH,
-			StorageDBDNS:    cfg.Storage.Postgres.DNS,
+			Host:                       cfg.HTTP.Host,
+			GRPCHost:                   cfg.GRPC.Host,
+			SecretKey:                  cfg.App.SecretKey,
+			JWTKeysFile:                cfg.App.JWTKeysFile,
+			IPHashKey:                  cfg.App.IPHashKey,
+			RedirectHost:               cfg.URLShortener.RedirectHost,
+			ReadTimeout:                cfg.HTTP.ReadTimeout.Duration,
+			WriteTimeout:               cfg.HTTP.WriteTimeout.Duration,
+			IdleTimeout:                cfg.HTTP.IdleTimeout.Duration,
+			TrustedSubnet:              cfg.HTTP.TrustedSubnet,
+			TrustedProxies:             cfg.HTTP.TrustedProxies,
+			EnableHTTPS:                cfg.HTTP.EnableHTTPS,
+			TLSCertFile:                cfg.HTTP.TLSCertFile,
+			TLSKeyFile:                 cfg.HTTP.TLSKeyFile,
+			RateLimitCreate:            rateLimit(cfg.HTTP.RateLimit.Create),
+			RateLimitBatch:             rateLimit(cfg.HTTP.RateLimit.Batch),
+			RateLimitRedirect:          rateLimit(cfg.HTTP.RateLimit.Redirect),
+			RateLimitAuth:              rateLimit(cfg.HTTP.RateLimit.Auth),
+			ShutdownTimeout:            cfg.HTTP.ShutdownTimeout.Duration,
+			StorageFilePath:            cfg.Storage.File.PATH,
+			StorageFileSync:            cfg.Storage.File.Sync,
+			StorageFileSyncInterval:    cfg.Storage.File.SyncInterval.Duration,
+			StorageFileCompactSize:     cfg.Storage.File.CompactSize,
+			StorageFileCompactInterval: cfg.Storage.File.CompactInterval.Duration,
+			StorageDBDNS:               cfg.Storage.Postgres.DNS,
+			AliasRules: urlhandler.AliasRules{
+				Charset:  cfg.URLShortener.Alias.Charset,
+				MinLen:   cfg.URLShortener.Alias.MinLen,
+				MaxLen:   cfg.URLShortener.Alias.MaxLen,
+				Reserved: cfg.URLShortener.Alias.Reserved,
+			},
+			URLRules: urlhandler.URLRules{
+				Schemes:       cfg.URLShortener.URL.Schemes,
+				StripTracking: cfg.URLShortener.URL.StripTracking,
+			},
+			Quotas: urlhandler.Quotas{
+				MaxLinksPerUser: cfg.URLShortener.Quotas.MaxLinksPerUser,
+				MaxBatchSize:    cfg.URLShortener.Quotas.MaxBatchSize,
+				MaxURLLength:    cfg.URLShortener.Quotas.MaxURLLength,
+			},
+			PolicyFile:      cfg.URLShortener.PolicyFile,
+			RestoreGrace:    cfg.URLShortener.Restore.GracePeriod.Duration,
+			ReaperInterval:  cfg.URLShortener.Reaper.Interval.Duration,
+			ReaperBatchSize: cfg.URLShortener.Reaper.BatchSize,
 		},
 	)
 	if err != nil {
-		log.Error("failed to configure server", zap.Error(err))
-		return
+		log.Fatal("failed to configure server", zap.Error(err))
 	}
 
 	if err := urlShortener.Run(ctx); err != nil {
-		log.Error(err.Error())
+		log.Fatal("server stopped with error", zap.Error(err))
 	}
 
 }
+
+func rateLimit(l config.RateLimit) ratelimit.Limit {
+	return ratelimit.Limit{Rate: l.RPS, Burst: l.Burst}
+}
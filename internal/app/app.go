@@ -3,9 +3,11 @@ package app
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -17,49 +19,131 @@ import (
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	grpcHandlers "github.com/vladislav-kr/yp-go-url-shortener/internal/grpc/handlers"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/grpc/interceptors"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/grpc/shortenerpb"
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/handlers"
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware"
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/auth"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/ratelimit"
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/router"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/lib/cryptoutils"
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/lib/fileutils"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/lib/netutils"
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/server"
 	urlHandler "github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler/clicker"
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler/deleter"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler/policy"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler/reaper"
 	dbkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/db-keeper"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/storages/db-keeper/migrations"
 	mapkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/map-keeper"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/storages/map-keeper/compactor"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/storages/map-keeper/file"
 )
 
 // URLShortener хранить все параметры сервера.
 type URLShortener struct {
 	log             *zap.Logger
 	server          *server.HTTPServer
+	grpcServer      *server.GRPCServer
 	shutdownTimeout time.Duration
 	db              *sql.DB
+	migrator        *migrations.Migrator
 	memStorage      *mapkeeper.Keeper
+	compactor       *compactor.Compactor
+	auth            *auth.Auth
+	jwtKeysFile     string
+	policy          *policy.Policy
+	policyFile      string
 }
 
 // Option конфигурация сервера.
 type Option struct {
-	Host            string
-	RedirectHost    string
-	ReadTimeout     time.Duration
-	WriteTimeout    time.Duration
-	IdleTimeout     time.Duration
-	ShutdownTimeout time.Duration
-	StorageFilePath string
-	StorageDBDNS    string
+	// SecretKey ключ подписи JWT, пусто - случайный ключ на время работы.
+	SecretKey string
+	// IPHashKey ключ HMAC для хешей IP создателей URL,
+	// пусто - случайный ключ на время работы.
+	IPHashKey string
+	// JWTKeysFile файл с набором ключей JWT, перечитывается по SIGHUP.
+	// Если задан, SecretKey не используется.
+	JWTKeysFile string
+	Host        string
+	// GRPCHost адрес gRPC сервера, пусто - не запускается.
+	GRPCHost     string
+	RedirectHost string
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// TrustedSubnet CIDR для внутренних ручек, пусто - доступ запрещен.
+	TrustedSubnet string
+	// TrustedProxies CIDR прокси, которым доверяется X-Real-IP.
+	TrustedProxies []string
+	// EnableHTTPS без TLSCertFile и TLSKeyFile выпускается самоподписанный сертификат.
+	EnableHTTPS bool
+	TLSCertFile string
+	TLSKeyFile  string
+	// Ограничения частоты запросов, нулевой Rate - без ограничений.
+	RateLimitCreate         ratelimit.Limit
+	RateLimitBatch          ratelimit.Limit
+	RateLimitRedirect       ratelimit.Limit
+	RateLimitAuth           ratelimit.Limit
+	ShutdownTimeout         time.Duration
+	StorageFilePath         string
+	StorageFileSync         string
+	StorageFileSyncInterval time.Duration
+	// Пороги сжатия журнала, 0 - отключено.
+	StorageFileCompactSize     int64
+	StorageFileCompactInterval time.Duration
+	StorageDBDNS               string
+	AliasRules                 urlHandler.AliasRules
+	URLRules                   urlHandler.URLRules
+	Quotas                     urlHandler.Quotas
+	// PolicyFile JSON файл с политикой адресов назначения,
+	// перечитывается по SIGHUP. Пусто - разрешены любые адреса.
+	PolicyFile string
+	// RestoreGrace срок восстановления удаленных URL,
+	// 0 - удаленные URL хранятся бессрочно.
+	RestoreGrace    time.Duration
+	ReaperInterval  time.Duration
+	ReaperBatchSize int
 }
 
+// secretKeySize длина случайных ключей подписи JWT и хеширования IP.
+const secretKeySize = 32
+
 // NewURLShortener новая инстанция сервера.
 func NewURLShortener(ctx context.Context, log *zap.Logger, opt Option) (*URLShortener, error) {
 	var (
 		db         *sql.DB
+		migrator   *migrations.Migrator
 		memStorage *mapkeeper.Keeper
+		memCompact *compactor.Compactor
 		storage    urlHandler.Keeperer
 	)
 
+	if len(opt.SecretKey) == 0 && len(opt.JWTKeysFile) == 0 {
+		key, err := cryptoutils.GenerateRandomString(secretKeySize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate secret key: %w", err)
+		}
+		opt.SecretKey = key
+		log.Warn("secret key is not configured, using a random one: auth tokens will not survive a restart")
+	}
+	if len(opt.IPHashKey) == 0 {
+		key, err := cryptoutils.GenerateRandomString(secretKeySize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate ip hash key: %w", err)
+		}
+		opt.IPHashKey = key
+		log.Warn("ip hash key is not configured, using a random one: creator ip hashes will change after a restart")
+	}
+
 	switch {
 	case len(opt.StorageDBDNS) > 0:
 		var err error
@@ -68,6 +152,11 @@ func NewURLShortener(ctx context.Context, log *zap.Logger, opt Option) (*URLShor
 			return nil, err
 		}
 
+		migrator, err = newMigrator(log, db)
+		if err != nil {
+			return nil, err
+		}
+
 		dbPool, err := connectionPool(ctx, opt.StorageDBDNS)
 		if err != nil {
 			return nil, err
@@ -87,30 +176,134 @@ func NewURLShortener(ctx context.Context, log *zap.Logger, opt Option) (*URLShor
 		if err != nil {
 			return nil, err
 		}
-		memStorage = mapkeeper.New(storageFilePath)
+		syncPolicy, err := file.ParseSyncPolicy(opt.StorageFileSync)
+		if err != nil {
+			return nil, err
+		}
+		memStorage = mapkeeper.New(
+			log.With(
+				zap.String(
+					"component",
+					"mapkeeper",
+				),
+			),
+			storageFilePath,
+			syncPolicy,
+			opt.StorageFileSyncInterval,
+		)
 		storage = memStorage
+		memCompact = compactor.NewCompactor(
+			ctx,
+			log.With(
+				zap.String(
+					"component",
+					"compactor",
+				),
+			),
+			memStorage,
+			opt.StorageFileCompactSize,
+			opt.StorageFileCompactInterval,
+		)
 	default:
 		return nil, fmt.Errorf("failed to create storage")
 	}
 
-	h := handlers.NewHandlers(
+	// Фоновое удаление истекших URL
+	reaper.NewReaper(
+		ctx,
 		log.With(
 			zap.String(
 				"component",
-				"handlers",
+				"reaper",
 			),
 		),
-		urlHandler.NewURLHandler(
-			storage,
-			db,
-			deleter.NewDeleter(ctx, 10, func(urls []models.DeleteURL) {
+		opt.ReaperInterval,
+		opt.ReaperBatchSize,
+		storage.DeleteExpired,
+	)
+
+	var destPolicy *policy.Policy
+	var uhPolicy urlHandler.DestinationPolicy
+	if len(opt.PolicyFile) > 0 {
+		rules, err := policy.Load(opt.PolicyFile)
+		if err != nil {
+			return nil, err
+		}
+		destPolicy, err = policy.New(rules)
+		if err != nil {
+			return nil, err
+		}
+		uhPolicy = destPolicy
+	}
+
+	uh := urlHandler.NewURLHandler(
+		storage,
+		db,
+		deleter.NewDeleter(ctx, 10, func(urls []models.DeleteURL) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+			defer cancel()
+			storage.DeleteURLS(ctx, urls)
+		}),
+		urlHandler.Option{
+			AliasRules:   opt.AliasRules,
+			URLRules:     opt.URLRules,
+			Quotas:       opt.Quotas,
+			Policy:       uhPolicy,
+			RestoreGrace: opt.RestoreGrace,
+			IPHashKey:    []byte(opt.IPHashKey),
+			Clicker: clicker.NewClicker(ctx, 1000, func(clicks []models.Click) {
 				ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 				defer cancel()
-				storage.DeleteURLS(ctx, urls)
-			})),
+				storage.SaveClicks(ctx, clicks)
+			}),
+		},
+	)
+
+	// Окончательное удаление URL с истекшим сроком восстановления
+	reaper.NewReaper(
+		ctx,
+		log.With(
+			zap.String(
+				"component",
+				"purger",
+			),
+		),
+		opt.ReaperInterval,
+		opt.ReaperBatchSize,
+		uh.PurgeDeleted,
+	)
+
+	keys := auth.SingleKey(opt.SecretKey)
+	if len(opt.JWTKeysFile) > 0 {
+		var err error
+		keys, err = auth.LoadKeySet(opt.JWTKeysFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	authenticator := auth.New(keys, opt.EnableHTTPS)
+
+	h := handlers.NewHandlers(
+		log.With(
+			zap.String(
+				"component",
+				"handlers",
+			),
+		),
+		uh,
 		opt.RedirectHost,
+		authenticator,
 	)
 
+	trustedSubnet, err := netutils.ParseSubnet(opt.TrustedSubnet)
+	if err != nil {
+		return nil, err
+	}
+	trustedProxies, err := netutils.ParseSubnets(opt.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
 	m := middleware.New(
 		log.With(
 			zap.String(
@@ -118,28 +311,60 @@ func NewURLShortener(ctx context.Context, log *zap.Logger, opt Option) (*URLShor
 				"middleware",
 			),
 		),
-		auth.New("secret-key"),
+		authenticator,
+		uh,
+		trustedSubnet,
+		trustedProxies,
 	)
 
+	// ограничения общие для HTTP и gRPC
+	limiters := ratelimit.Limiters{
+		Create:   ratelimit.NewLimiter(ctx, opt.RateLimitCreate),
+		Batch:    ratelimit.NewLimiter(ctx, opt.RateLimitBatch),
+		Redirect: ratelimit.NewLimiter(ctx, opt.RateLimitRedirect),
+		Auth:     ratelimit.NewLimiter(ctx, opt.RateLimitAuth),
+	}
+
 	srv := &http.Server{
 		Addr:         opt.Host,
-		Handler:      router.NewRouter(h, m),
+		Handler:      router.NewRouter(h, m, limiters),
 		ReadTimeout:  opt.ReadTimeout,
 		WriteTimeout: opt.WriteTimeout,
 		IdleTimeout:  opt.IdleTimeout,
 	}
 
+	srvLog := log.With(
+		zap.String("component", "HTTPServer"),
+		zap.String("addr", srv.Addr),
+	)
+	httpServer := server.NewHTTPServer(srvLog, srv)
+	if opt.EnableHTTPS {
+		httpServer, err = server.NewHTTPSServer(srvLog, srv, opt.TLSCertFile, opt.TLSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var grpcServer *server.GRPCServer
+	if len(opt.GRPCHost) > 0 {
+		// при EnableHTTPS gRPC использует сертификат https сервера
+		grpcServer = newGRPCServer(log, opt.GRPCHost, uh, opt.RedirectHost, authenticator,
+			trustedSubnet, trustedProxies, limiters, httpServer.TLSConfig())
+	}
+
 	return &URLShortener{
-		log: log,
-		server: server.NewHTTPServer(
-			log.With(
-				zap.String("component", "HTTPServer"),
-				zap.String("addr", srv.Addr),
-			),
-			srv),
+		log:             log,
+		server:          httpServer,
+		grpcServer:      grpcServer,
 		shutdownTimeout: opt.ShutdownTimeout,
 		db:              db,
+		migrator:        migrator,
 		memStorage:      memStorage,
+		compactor:       memCompact,
+		auth:            authenticator,
+		jwtKeysFile:     opt.JWTKeysFile,
+		policy:          destPolicy,
+		policyFile:      opt.PolicyFile,
 	}, nil
 }
 
@@ -153,88 +378,77 @@ func (us *URLShortener) Run(ctx context.Context) error {
 	)
 	defer sigCancel()
 
+	// Схема БД приводится к актуальной версии до запуска сервера
+	if us.migrator != nil {
+		if err := us.migrator.Up(sigCtx); err != nil {
+			us.log.Error("failed to migrate database", zap.Error(err))
+			us.db.Close()
+			return err
+		}
+	}
+
 	// Группа для запуска и остановки сервера по сигналу
 	errGr, errGrCtx := errgroup.WithContext(sigCtx)
 
 	errGr.Go(func() error {
-		if us.db != nil {
-
-			tx, err := us.db.BeginTx(ctx, nil)
-			if err != nil {
-				us.log.Info(
-					"failed to create transaction",
-					zap.Error(err),
-				)
-				return err
-			}
-
-			defer tx.Rollback()
-			_, err = tx.ExecContext(ctx, `
-			CREATE TABLE
-				IF NOT EXISTS shortened_url (
-					short_url VARCHAR(10) PRIMARY KEY,
-					original_url VARCHAR(4000) NOT NULL
-				);
-			`)
-			if err != nil {
-				us.log.Info(
-					"failed to create table shortened_url",
-					zap.Error(err),
-				)
-				return err
-			}
-			_, err = tx.ExecContext(ctx,
-				`CREATE UNIQUE INDEX IF NOT EXISTS orig_url_idx ON shortened_url (original_url)`)
-			if err != nil {
-				us.log.Info(
-					"failed to create index",
-					zap.String("field", "original_url"),
-					zap.Error(err),
-				)
-				return err
-			}
-
-			_, err = tx.ExecContext(ctx,
-				`ALTER TABLE shortened_url ADD COLUMN IF NOT EXISTS user_id UUID;`)
-			if err != nil {
-				us.log.Info(
-					"failed to create new column user_id",
-					zap.Error(err),
-				)
-				return err
-			}
-
-			_, err = tx.ExecContext(ctx,
-				`ALTER TABLE shortened_url ADD COLUMN IF NOT EXISTS is_deleted boolean DEFAULT FALSE;`)
-			if err != nil {
-				us.log.Info(
-					"failed to create new column is_deleted",
-					zap.Error(err),
-				)
-				return err
-			}
-
-			if err := tx.Commit(); err != nil {
-				us.log.Info(
-					"failed to apply changes to the database",
-					zap.Error(err),
-				)
-				return err
-			}
-		}
-
 		if us.memStorage != nil {
 			if err := us.memStorage.LoadFromFile(); err != nil {
-				us.log.Info(
+				us.log.Error(
 					"failed to load data from file",
 					zap.Error(err),
 				)
+				return err
 			}
 		}
 
 		return us.server.Run()
 	})
 
+	if us.grpcServer != nil {
+		errGr.Go(us.grpcServer.Run)
+	}
+
+	if us.compactor != nil {
+		errGr.Go(func() error {
+			// Сжатие журнала по сигналу администратора
+			compactCh := make(chan os.Signal, 1)
+			notifyCompact(compactCh)
+			defer signal.Stop(compactCh)
+
+			for {
+				select {
+				case <-errGrCtx.Done():
+					return nil
+				case <-compactCh:
+					us.compactor.Trigger()
+				}
+			}
+		})
+	}
+
+	if len(us.jwtKeysFile) > 0 || us.policy != nil {
+		errGr.Go(func() error {
+			// Ротация ключей JWT и обновление политики по сигналу администратора
+			reloadCh := make(chan os.Signal, 1)
+			notifyReload(reloadCh)
+			defer signal.Stop(reloadCh)
+
+			for {
+				select {
+				case <-errGrCtx.Done():
+					return nil
+				case <-reloadCh:
+					if len(us.jwtKeysFile) > 0 {
+						us.reloadKeys()
+					}
+					if us.policy != nil {
+						us.reloadPolicy()
+					}
+				}
+			}
+		})
+	}
+
 	errGr.Go(func() error {
 		<-errGrCtx.Done()
 
@@ -246,9 +460,9 @@ func (us *URLShortener) Run(ctx context.Context) error {
 
 		defer func() {
 			if us.memStorage != nil {
-				if err := us.memStorage.SaveToFile(); err != nil {
+				if err := us.memStorage.Close(); err != nil {
 					us.log.Error(
-						"failed to save data to file",
+						"failed to close storage file",
 						zap.Error(err),
 					)
 				}
@@ -267,6 +481,15 @@ func (us *URLShortener) Run(ctx context.Context) error {
 			}
 		}()
 
+		if us.grpcServer != nil {
+			if err := us.grpcServer.Stop(ctx); err != nil {
+				us.log.Error(
+					"failed to stop grpc server",
+					zap.Error(err),
+				)
+			}
+		}
+
 		return us.server.Stop(ctx)
 	})
 
@@ -274,6 +497,142 @@ func (us *URLShortener) Run(ctx context.Context) error {
 
 }
 
+// reloadKeys перечитывает набор ключей JWT.
+// При ошибке продолжает работать прежний набор.
+func (us *URLShortener) reloadKeys() {
+	keys, err := auth.LoadKeySet(us.jwtKeysFile)
+	if err == nil {
+		err = us.auth.SetKeys(keys)
+	}
+	if err != nil {
+		us.log.Error("failed to reload jwt keys", zap.Error(err))
+		return
+	}
+	us.log.Info("jwt keys reloaded", zap.String("active", keys.Active))
+}
+
+// reloadPolicy перечитывает политику адресов назначения.
+// При ошибке продолжает действовать прежняя политика.
+func (us *URLShortener) reloadPolicy() {
+	rules, err := policy.Load(us.policyFile)
+	if err == nil {
+		err = us.policy.SetRules(rules)
+	}
+	if err != nil {
+		us.log.Error("failed to reload destination policy", zap.Error(err))
+		return
+	}
+	us.log.Info("destination policy reloaded", zap.String("mode", rules.Mode))
+}
+
+// Migrate выполняет миграции БД в заданном направлении (up, down) без запуска сервера.
+func Migrate(ctx context.Context, log *zap.Logger, dbDNS string, direction string) error {
+	if len(dbDNS) == 0 {
+		return fmt.Errorf("database connection address is not set")
+	}
+
+	db, err := connectionDB(dbDNS)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := newMigrator(log, db)
+	if err != nil {
+		return err
+	}
+
+	return migrator.Run(ctx, direction)
+}
+
+func newGRPCServer(
+	log *zap.Logger,
+	addr string,
+	uh *urlHandler.URLHandler,
+	redirectHost string,
+	authenticator *auth.Auth,
+	trustedSubnet *net.IPNet,
+	trustedProxies []*net.IPNet,
+	limiters ratelimit.Limiters,
+	tlsConfig *tls.Config,
+) *server.GRPCServer {
+	i := interceptors.New(
+		log.With(
+			zap.String(
+				"component",
+				"interceptors",
+			),
+		),
+		authenticator,
+		uh,
+		trustedSubnet,
+		trustedProxies,
+		interceptors.Methods{
+			Trusted: []string{shortenerpb.ShortenerService_Stats_FullMethodName},
+			Strict: []string{
+				shortenerpb.ShortenerService_ListUserURLs_FullMethodName,
+				shortenerpb.ShortenerService_DeleteUserURLs_FullMethodName,
+			},
+			Scopes: map[string]string{
+				shortenerpb.ShortenerService_Shorten_FullMethodName:        models.ScopeShorten,
+				shortenerpb.ShortenerService_ShortenBatch_FullMethodName:   models.ScopeShorten,
+				shortenerpb.ShortenerService_ListUserURLs_FullMethodName:   models.ScopeRead,
+				shortenerpb.ShortenerService_URLStats_FullMethodName:       models.ScopeRead,
+				shortenerpb.ShortenerService_DeleteUserURLs_FullMethodName: models.ScopeDelete,
+			},
+		},
+	)
+
+	opts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(
+		i.RealIP,
+		i.Auth,
+		i.Logger,
+		i.TrustedSubnet,
+		i.RateLimit(map[string]*ratelimit.Limiter{
+			shortenerpb.ShortenerService_Shorten_FullMethodName:      limiters.Create,
+			shortenerpb.ShortenerService_ShortenBatch_FullMethodName: limiters.Batch,
+			shortenerpb.ShortenerService_Expand_FullMethodName:       limiters.Redirect,
+		}),
+	)}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	srv := grpc.NewServer(opts...)
+
+	shortenerpb.RegisterShortenerServiceServer(srv, grpcHandlers.NewShortenerServer(
+		log.With(
+			zap.String(
+				"component",
+				"grpc-handlers",
+			),
+		),
+		uh,
+		redirectHost,
+	))
+
+	return server.NewGRPCServer(
+		log.With(
+			zap.String("component", "GRPCServer"),
+			zap.String("addr", addr),
+		),
+		srv,
+		addr,
+	)
+}
+
+func newMigrator(log *zap.Logger, db *sql.DB) (*migrations.Migrator, error) {
+	return migrations.NewMigrator(
+		log.With(
+			zap.String(
+				"component",
+				"migrator",
+			),
+		),
+		db,
+	)
+}
+
 func connectionDB(dns string) (*sql.DB, error) {
 	db, err := sql.Open("pgx", dns)
 	if err != nil {
@@ -0,0 +1,19 @@
+//go:build !windows
+
+package app
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyCompact подписывает ch на сигнал сжатия журнала (SIGUSR1).
+func notifyCompact(ch chan<- os.Signal) {
+	signal.Notify(ch, syscall.SIGUSR1)
+}
+
+// notifyReload подписывает ch на сигнал перечитывания ключей JWT (SIGHUP).
+func notifyReload(ch chan<- os.Signal) {
+	signal.Notify(ch, syscall.SIGHUP)
+}
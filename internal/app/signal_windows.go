@@ -0,0 +1,11 @@
+//go:build windows
+
+package app
+
+import "os"
+
+// notifyCompact на Windows сигнал сжатия журнала не поддерживается.
+func notifyCompact(_ chan<- os.Signal) {}
+
+// notifyReload на Windows ключи JWT перечитываются только при перезапуске.
+func notifyReload(_ chan<- os.Signal) {}
@@ -1,45 +1,250 @@
 // config конфигурация сервера
+//
+// Значения собираются из нескольких источников, каждый следующий
+// перекрывает предыдущий: значения по умолчанию, JSON файл (-c / CONFIG),
+// переменные окружения, флаги командной строки.
 package config
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/caarlos0/env/v10"
 )
 
+// Значения по умолчанию
+const (
+	DefHost = ":8080"
+	// DefGRPCHost gRPC сервер по умолчанию выключен.
+	DefGRPCHost          = ""
+	DefRedirectHost      = "http://localhost:8080"
+	DefHTTPSRedirectHost = "https://localhost:8080"
+	DefFilePath          = "\\tmp\\short-url-db.json"
+)
+
+// configEnv переменная окружения с путем к JSON файлу конфигурации.
+const configEnv = "CONFIG"
+
 // Config конфигурационные данные сервера.
 type Config struct {
 	App struct {
-		LogLevel string `env:"APP_LOG_LEVEL" envDefault:"info"`
-	}
+		LogLevel string `env:"APP_LOG_LEVEL" json:"log_level"`
+		// SecretKey ключ подписи JWT. Пусто - при запуске генерируется
+		// случайный ключ, токены не переживают перезапуск.
+		SecretKey string `env:"SECRET_KEY" json:"secret_key"`
+		// JWTKeysFile JSON файл с набором ключей подписи JWT,
+		// перечитывается по SIGHUP. Если задан, SecretKey не используется.
+		JWTKeysFile string `env:"JWT_KEYS_FILE" json:"jwt_keys_file"`
+		// IPHashKey ключ HMAC для хешей IP создателей URL, не связан
+		// с ключами JWT. Пусто - случайный ключ, хеши меняются после перезапуска.
+		IPHashKey string `env:"IP_HASH_KEY" json:"ip_hash_key"`
+	} `json:"app"`
 	HTTP struct {
-		Host            string        `env:"SERVER_ADDRESS"`
-		ShutdownTimeout time.Duration `env:"HTTP_SHUTDOWN_TIMEOUT" envDefault:"10s"`
-		ReadTimeout     time.Duration `env:"HTTP_READ_TIMEOUT" env-default:"4s"`
-		WriteTimeout    time.Duration `env:"HTTP_WRITE_TIMEOUT" env-default:"4s"`
-		IdleTimeout     time.Duration `env:"HTTP_IDLE_TIMEOUT" env-default:"15s"`
-	}
+		Host            string   `env:"SERVER_ADDRESS" json:"server_address"`
+		ShutdownTimeout Duration `env:"HTTP_SHUTDOWN_TIMEOUT" json:"shutdown_timeout"`
+		ReadTimeout     Duration `env:"HTTP_READ_TIMEOUT" json:"read_timeout"`
+		WriteTimeout    Duration `env:"HTTP_WRITE_TIMEOUT" json:"write_timeout"`
+		IdleTimeout     Duration `env:"HTTP_IDLE_TIMEOUT" json:"idle_timeout"`
+		// TrustedSubnet подсеть в формате CIDR для внутренних ручек,
+		// пустое значение запрещает доступ всем.
+		TrustedSubnet string `env:"TRUSTED_SUBNET" json:"trusted_subnet"`
+		// TrustedProxies подсети CIDR обратных прокси, от которых принимается
+		// заголовок X-Real-IP. Пусто - адрес клиента берется из соединения.
+		TrustedProxies []string `env:"TRUSTED_PROXIES" envSeparator:"," json:"trusted_proxies"`
+		// EnableHTTPS без файлов сертификата и ключа
+		// используется самоподписанный сертификат.
+		EnableHTTPS bool   `env:"ENABLE_HTTPS" json:"enable_https"`
+		TLSCertFile string `env:"TLS_CERT_FILE" json:"tls_cert_file"`
+		TLSKeyFile  string `env:"TLS_KEY_FILE" json:"tls_key_file"`
+		// RateLimit ограничения частоты запросов на пользователя или IP.
+		RateLimit struct {
+			Create   RateLimit `envPrefix:"RATE_LIMIT_CREATE_" json:"create"`
+			Batch    RateLimit `envPrefix:"RATE_LIMIT_BATCH_" json:"batch"`
+			Redirect RateLimit `envPrefix:"RATE_LIMIT_REDIRECT_" json:"redirect"`
+			// Auth регистрация и вход, ограничение по IP против перебора паролей.
+			Auth RateLimit `envPrefix:"RATE_LIMIT_AUTH_" json:"auth"`
+		} `json:"rate_limit"`
+	} `json:"http"`
+	GRPC struct {
+		// Host адрес gRPC сервера, пустое значение отключает его.
+		Host string `env:"GRPC_SERVER_ADDRESS" json:"server_address"`
+	} `json:"grpc"`
 	URLShortener struct {
-		RedirectHost string `env:"BASE_URL"`
-	}
+		// RedirectHost по умолчанию DefRedirectHost или DefHTTPSRedirectHost.
+		RedirectHost string `env:"BASE_URL" json:"base_url"`
+		Alias        struct {
+			Charset  string   `env:"ALIAS_CHARSET" json:"charset"`
+			MinLen   int      `env:"ALIAS_MIN_LEN" json:"min_len"`
+			MaxLen   int      `env:"ALIAS_MAX_LEN" json:"max_len"`
+			Reserved []string `env:"ALIAS_RESERVED" envSeparator:"," json:"reserved"`
+		} `json:"alias"`
+		// Reaper удаление истекших URL.
+		Reaper struct {
+			Interval  Duration `env:"REAPER_INTERVAL" json:"interval"`
+			BatchSize int      `env:"REAPER_BATCH_SIZE" json:"batch_size"`
+		} `json:"reaper"`
+		// URL проверка и приведение URL к каноническому виду.
+		URL struct {
+			Schemes []string `env:"URL_SCHEMES" envSeparator:"," json:"schemes"`
+			// StripTracking удалять параметры utm_*.
+			StripTracking bool `env:"URL_STRIP_TRACKING" json:"strip_tracking"`
+		} `json:"url"`
+		// PolicyFile JSON файл с политикой адресов назначения,
+		// перечитывается по SIGHUP. Пусто - разрешены любые адреса.
+		PolicyFile string `env:"DESTINATION_POLICY_FILE" json:"policy_file"`
+		// Restore восстановление удаленных URL.
+		Restore struct {
+			// GracePeriod срок восстановления, после него URL удаляются
+			// окончательно. 0 - URL хранятся и восстанавливаются бессрочно.
+			GracePeriod Duration `env:"RESTORE_GRACE_PERIOD" json:"grace_period"`
+		} `json:"restore"`
+		// Quotas ограничения на сохраняемые URL, 0 - без ограничения.
+		Quotas struct {
+			MaxLinksPerUser int `env:"QUOTA_MAX_LINKS_PER_USER" json:"max_links_per_user"`
+			MaxBatchSize    int `env:"QUOTA_MAX_BATCH_SIZE" json:"max_batch_size"`
+			// MaxURLLength 0 - размер колонки в БД.
+			MaxURLLength int `env:"QUOTA_MAX_URL_LENGTH" json:"max_url_length"`
+		} `json:"quotas"`
+	} `json:"url_shortener"`
 	Storage struct {
 		File struct {
-			PATH string `env:"FILE_STORAGE_PATH"`
-		}
+			PATH         string   `env:"FILE_STORAGE_PATH" json:"path"`
+			Sync         string   `env:"FILE_STORAGE_SYNC" json:"sync"`
+			SyncInterval Duration `env:"FILE_STORAGE_SYNC_INTERVAL" json:"sync_interval"`
+			// CompactSize размер журнала в байтах, после которого он сжимается.
+			CompactSize     int64    `env:"FILE_STORAGE_COMPACT_SIZE" json:"compact_size"`
+			CompactInterval Duration `env:"FILE_STORAGE_COMPACT_INTERVAL" json:"compact_interval"`
+		} `json:"file"`
 		Postgres struct {
-			DNS string `env:"DATABASE_DSN"`
-		}
+			DNS string `env:"DATABASE_DSN" json:"dsn"`
+			// Migrate направление миграций (up, down),
+			// при заданном значении сервер не запускается.
+			Migrate string `env:"DATABASE_MIGRATE" json:"migrate"`
+		} `json:"postgres"`
+	} `json:"storage"`
+
+	// PrintConfig вывести итоговую конфигурацию и выйти, задается только флагом.
+	PrintConfig bool `json:"-"`
+}
+
+// RateLimit параметры token bucket: RPS запросов в секунду, Burst емкость.
+// RPS = 0 отключает ограничение.
+type RateLimit struct {
+	RPS   float64 `env:"RPS" json:"rps"`
+	Burst int     `env:"BURST" json:"burst"`
+}
+
+// Duration time.Duration в текстовом виде ("10s", "1m") для env и JSON.
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText разбирает длительность в формате time.ParseDuration.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
 	}
+	d.Duration = v
+	return nil
 }
 
-// LoadConfig загружает конфиг приложения.
+// MarshalText длительность в формате time.Duration.String.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.Duration.String()), nil
+}
+
+// defaults конфигурация по умолчанию.
+func defaults() *Config {
+	cfg := &Config{}
+	cfg.App.LogLevel = "info"
+	cfg.HTTP.Host = DefHost
+	cfg.HTTP.ShutdownTimeout.Duration = 10 * time.Second
+	cfg.HTTP.RateLimit.Create = RateLimit{RPS: 10, Burst: 20}
+	cfg.HTTP.RateLimit.Batch = RateLimit{RPS: 1, Burst: 5}
+	cfg.HTTP.RateLimit.Redirect = RateLimit{RPS: 100, Burst: 200}
+	cfg.HTTP.RateLimit.Auth = RateLimit{RPS: 0.2, Burst: 5}
+	cfg.GRPC.Host = DefGRPCHost
+	cfg.URLShortener.Reaper.Interval.Duration = time.Minute
+	cfg.URLShortener.Reaper.BatchSize = 100
+	cfg.URLShortener.Restore.GracePeriod.Duration = 7 * 24 * time.Hour
+	cfg.URLShortener.Quotas.MaxLinksPerUser = 10000
+	cfg.URLShortener.Quotas.MaxBatchSize = 1000
+	cfg.Storage.File.PATH = DefFilePath
+	cfg.Storage.File.Sync = "always"
+	cfg.Storage.File.SyncInterval.Duration = time.Second
+	cfg.Storage.File.CompactSize = 10 << 20
+	cfg.Storage.File.CompactInterval.Duration = time.Hour
+	return cfg
+}
+
+// LoadConfig загружает конфиг приложения из флагов командной строки,
+// переменных окружения и JSON файла.
 func LoadConfig() (*Config, error) {
-	var cfg Config
+	return Load(os.Args[1:], env.ToMap(os.Environ()))
+}
 
-	if err := env.Parse(&cfg); err != nil {
+// Load собирает конфиг из args, environ и JSON файла.
+// Приоритет: флаги > env > файл > значения по умолчанию.
+func Load(args []string, environ map[string]string) (*Config, error) {
+	flags, err := parseFlags(args)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := defaults()
+
+	configFile := environ[configEnv]
+	if len(flags.configFile) > 0 {
+		configFile = flags.configFile
+	}
+	if len(configFile) > 0 {
+		if err := loadFile(configFile, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := env.ParseWithOptions(cfg, env.Options{Environment: environ}); err != nil {
 		return nil, fmt.Errorf("fail read env: %w", err)
 	}
 
-	return &cfg, nil
+	flags.apply(cfg)
+
+	if len(cfg.URLShortener.RedirectHost) == 0 {
+		cfg.URLShortener.RedirectHost = DefRedirectHost
+		if cfg.HTTP.EnableHTTPS {
+			cfg.URLShortener.RedirectHost = DefHTTPSRedirectHost
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// loadFile дополняет cfg значениями из JSON файла.
+// Неизвестные поля считаются ошибкой, чтобы опечатки не терялись молча.
+func loadFile(path string, cfg *Config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("fail read config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return &FieldError{Field: typeErr.Field, Err: fmt.Errorf("unexpected %s value", typeErr.Value)}
+		}
+		return fmt.Errorf("fail parse config file %s: %w", path, err)
+	}
+
+	return nil
 }
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+)
+
+// flagValues значения флагов командной строки.
+// В конфиг попадают только явно заданные флаги.
+type flagValues struct {
+	set map[string]bool
+
+	configFile    string
+	host          string
+	grpcHost      string
+	redirectHost  string
+	filePath      string
+	dsn           string
+	migrate       string
+	trustedSubnet string
+	secretKey     string
+	jwtKeysFile   string
+	enableHTTPS   bool
+	printConfig   bool
+}
+
+func parseFlags(args []string) (*flagValues, error) {
+	fv := &flagValues{set: map[string]bool{}}
+
+	fs := flag.NewFlagSet("shortener", flag.ContinueOnError)
+	fs.StringVar(&fv.configFile, "c", "", fmt.Sprintf("path to JSON config file (env %s)", configEnv))
+	fs.StringVar(&fv.host, "a", DefHost, "address and port to run server")
+	fs.StringVar(&fv.grpcHost, "g", DefGRPCHost, "address and port to run grpc server, empty to disable")
+	fs.StringVar(&fv.redirectHost, "b", DefRedirectHost,
+		fmt.Sprintf("base address of short urls (%q with -s)", DefHTTPSRedirectHost))
+	fs.StringVar(&fv.filePath, "f", DefFilePath, "file storage path")
+	fs.StringVar(&fv.dsn, "d", "", "database connection address")
+	fs.StringVar(&fv.migrate, "m", "", "run database migrations (up|down) and exit")
+	fs.StringVar(&fv.trustedSubnet, "t", "", "trusted subnet (CIDR) for internal endpoints")
+	fs.StringVar(&fv.secretKey, "k", "", "secret key for signing auth tokens")
+	fs.StringVar(&fv.jwtKeysFile, "jwt-keys", "", "path to JSON file with auth token signing keys, reloaded on SIGHUP")
+	fs.BoolVar(&fv.enableHTTPS, "s", false, "enable https")
+	fs.BoolVar(&fv.printConfig, "print-config", false, "print effective config with secrets redacted and exit")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		fv.set[f.Name] = true
+	})
+
+	return fv, nil
+}
+
+// apply переносит явно заданные флаги в конфиг.
+func (fv *flagValues) apply(cfg *Config) {
+	if fv.set["a"] {
+		cfg.HTTP.Host = fv.host
+	}
+	if fv.set["g"] {
+		cfg.GRPC.Host = fv.grpcHost
+	}
+	if fv.set["b"] {
+		cfg.URLShortener.RedirectHost = fv.redirectHost
+	}
+	if fv.set["f"] {
+		cfg.Storage.File.PATH = fv.filePath
+	}
+	if fv.set["d"] {
+		cfg.Storage.Postgres.DNS = fv.dsn
+	}
+	if fv.set["m"] {
+		cfg.Storage.Postgres.Migrate = fv.migrate
+	}
+	if fv.set["t"] {
+		cfg.HTTP.TrustedSubnet = fv.trustedSubnet
+	}
+	if fv.set["k"] {
+		cfg.App.SecretKey = fv.secretKey
+	}
+	if fv.set["jwt-keys"] {
+		cfg.App.JWTKeysFile = fv.jwtKeysFile
+	}
+	if fv.set["s"] {
+		cfg.HTTP.EnableHTTPS = fv.enableHTTPS
+	}
+	cfg.PrintConfig = fv.printConfig
+}
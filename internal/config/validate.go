@@ -0,0 +1,181 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	netURL "net/url"
+	"regexp"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/lib/netutils"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/logger"
+	dbkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/db-keeper"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/storages/map-keeper/file"
+)
+
+// redacted значение скрытого секрета, как в url.URL.Redacted.
+const redacted = "xxxxx"
+
+// FieldError ошибка в значении поля конфига.
+// Field путь поля в JSON файле, например http.server_address.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+// Error описание ошибки.
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("invalid config field %s: %v", e.Field, e.Err)
+}
+
+// Unwrap исходная ошибка.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Validate проверяет значения конфига.
+func (c *Config) Validate() error {
+	switch c.App.LogLevel {
+	case logger.DebugLevel, logger.InfoLevel, logger.WarnLevel, logger.ErrorLevel,
+		logger.DPanicLevel, logger.PanicLevel, logger.FatalLevel:
+	default:
+		return fieldErrorf("app.log_level", "unknown level %q", c.App.LogLevel)
+	}
+
+	if _, _, err := net.SplitHostPort(c.HTTP.Host); err != nil {
+		return &FieldError{Field: "http.server_address", Err: err}
+	}
+
+	if len(c.GRPC.Host) > 0 {
+		if _, _, err := net.SplitHostPort(c.GRPC.Host); err != nil {
+			return &FieldError{Field: "grpc.server_address", Err: err}
+		}
+		if c.GRPC.Host == c.HTTP.Host {
+			return fieldErrorf("grpc.server_address", "must differ from http.server_address")
+		}
+	}
+
+	for field, d := range map[string]Duration{
+		"http.shutdown_timeout":              c.HTTP.ShutdownTimeout,
+		"http.read_timeout":                  c.HTTP.ReadTimeout,
+		"http.write_timeout":                 c.HTTP.WriteTimeout,
+		"http.idle_timeout":                  c.HTTP.IdleTimeout,
+		"url_shortener.reaper.interval":      c.URLShortener.Reaper.Interval,
+		"url_shortener.restore.grace_period": c.URLShortener.Restore.GracePeriod,
+		"storage.file.sync_interval":         c.Storage.File.SyncInterval,
+		"storage.file.compact_interval":      c.Storage.File.CompactInterval,
+	} {
+		if d.Duration < 0 {
+			return fieldErrorf(field, "must not be negative")
+		}
+	}
+
+	for field, l := range map[string]RateLimit{
+		"http.rate_limit.create":   c.HTTP.RateLimit.Create,
+		"http.rate_limit.batch":    c.HTTP.RateLimit.Batch,
+		"http.rate_limit.redirect": c.HTTP.RateLimit.Redirect,
+		"http.rate_limit.auth":     c.HTTP.RateLimit.Auth,
+	} {
+		if l.RPS < 0 || l.Burst < 0 {
+			return fieldErrorf(field, "rps and burst must not be negative")
+		}
+	}
+
+	if _, err := netutils.ParseSubnet(c.HTTP.TrustedSubnet); err != nil {
+		return &FieldError{Field: "http.trusted_subnet", Err: err}
+	}
+	if _, err := netutils.ParseSubnets(c.HTTP.TrustedProxies); err != nil {
+		return &FieldError{Field: "http.trusted_proxies", Err: err}
+	}
+
+	if (len(c.HTTP.TLSCertFile) == 0) != (len(c.HTTP.TLSKeyFile) == 0) {
+		return fieldErrorf("http.tls_key_file", "tls_cert_file and tls_key_file must be set together")
+	}
+
+	u, err := netURL.Parse(c.URLShortener.RedirectHost)
+	if err != nil {
+		return &FieldError{Field: "url_shortener.base_url", Err: err}
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return fieldErrorf("url_shortener.base_url", "must be an absolute http(s) url, got %q", c.URLShortener.RedirectHost)
+	}
+
+	alias := c.URLShortener.Alias
+	if alias.MinLen < 0 || alias.MaxLen < 0 {
+		return fieldErrorf("url_shortener.alias", "lengths must not be negative")
+	}
+	if alias.MaxLen > dbkeeper.MaxShortURLLen {
+		return fieldErrorf("url_shortener.alias.max_len", "must not exceed %d", dbkeeper.MaxShortURLLen)
+	}
+	if alias.MinLen > 0 && alias.MaxLen > 0 && alias.MinLen > alias.MaxLen {
+		return fieldErrorf("url_shortener.alias.min_len", "must not exceed max_len")
+	}
+
+	for _, scheme := range c.URLShortener.URL.Schemes {
+		if len(scheme) == 0 {
+			return fieldErrorf("url_shortener.url.schemes", "must not contain empty values")
+		}
+	}
+
+	if c.URLShortener.Reaper.BatchSize < 0 {
+		return fieldErrorf("url_shortener.reaper.batch_size", "must not be negative")
+	}
+
+	for field, v := range map[string]int{
+		"url_shortener.quotas.max_links_per_user": c.URLShortener.Quotas.MaxLinksPerUser,
+		"url_shortener.quotas.max_batch_size":     c.URLShortener.Quotas.MaxBatchSize,
+		"url_shortener.quotas.max_url_length":     c.URLShortener.Quotas.MaxURLLength,
+	} {
+		if v < 0 {
+			return fieldErrorf(field, "must not be negative")
+		}
+	}
+
+	if _, err := file.ParseSyncPolicy(c.Storage.File.Sync); err != nil {
+		return &FieldError{Field: "storage.file.sync", Err: err}
+	}
+
+	if c.Storage.File.CompactSize < 0 {
+		return fieldErrorf("storage.file.compact_size", "must not be negative")
+	}
+
+	switch c.Storage.Postgres.Migrate {
+	case "", "up", "down":
+	default:
+		return fieldErrorf("storage.postgres.migrate", "must be up or down, got %q", c.Storage.Postgres.Migrate)
+	}
+
+	return nil
+}
+
+func fieldErrorf(field string, format string, args ...any) error {
+	return &FieldError{Field: field, Err: fmt.Errorf(format, args...)}
+}
+
+// dsnPassword пароль в DSN вида key=value.
+var dsnPassword = regexp.MustCompile(`(password=)(\S+)`)
+
+// Redacted копия конфига со скрытыми секретами.
+func (c Config) Redacted() Config {
+	if len(c.App.SecretKey) > 0 {
+		c.App.SecretKey = redacted
+	}
+	if len(c.App.IPHashKey) > 0 {
+		c.App.IPHashKey = redacted
+	}
+	c.Storage.Postgres.DNS = redactDSN(c.Storage.Postgres.DNS)
+	return c
+}
+
+// redactDSN скрывает пароль в DSN в формате url и key=value.
+func redactDSN(dsn string) string {
+	if u, err := netURL.Parse(dsn); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	return dsnPassword.ReplaceAllString(dsn, "${1}"+redacted)
+}
+
+// JSON итоговая конфигурация в формате JSON файла со скрытыми секретами.
+func (c Config) JSON() ([]byte, error) {
+	return json.MarshalIndent(c.Redacted(), "", "  ")
+}
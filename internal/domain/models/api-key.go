@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Области доступа API ключей
+const (
+	ScopeShorten = "shorten"
+	ScopeRead    = "read"
+	ScopeDelete  = "delete"
+)
+
+// APIKey ключ API пользователя.
+// Хранится только хеш ключа, сам ключ показывается один раз при создании.
+type APIKey struct {
+	ID      string `json:"id"`
+	UserID  string `json:"userId"`
+	Name    string `json:"name"`
+	KeyHash string `json:"keyHash"`
+	// Scopes пустой список - доступ без ограничений.
+	Scopes     []string   `json:"scopes,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	// Revoked ключ отозван (tombstone в журнале).
+	Revoked bool `json:"revoked,omitempty"`
+}
+
+// APIKeyRequest запрос на создание ключа API.
+type APIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// APIKeyInfo описание ключа API без секрета.
+type APIKeyInfo struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// CreatedAPIKey созданный ключ API вместе с секретом.
+type CreatedAPIKey struct {
+	APIKeyInfo
+	Key string `json:"key"`
+}
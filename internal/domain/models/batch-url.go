@@ -1,14 +1,35 @@
 // models модель данных
 package models
 
+import "time"
+
 // BatchRequest структура запроса массового сокращения URL.
 type BatchRequest struct {
-	CorrelationID string `json:"correlation_id"`
-	OriginalURL   string `json:"original_url"`
+	CorrelationID string     `json:"correlation_id"`
+	OriginalURL   string     `json:"original_url"`
+	Alias         string     `json:"alias,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	TTL           int64      `json:"ttl,omitempty"`
+	Tags          []string   `json:"tags,omitempty"`
+	Folder        string     `json:"folder,omitempty"`
+	// Creator заполняется сервером.
+	Creator Creator `json:"-"`
 }
 
+// Результаты сохранения элемента пакета
+const (
+	// BatchCreated создан новый короткий URL.
+	BatchCreated = "created"
+	// BatchExisting URL уже сокращен, ShortURL - существующий короткий URL.
+	BatchExisting = "existing"
+	// BatchInvalid элемент не сохранен, причина в Reason.
+	BatchInvalid = "invalid"
+)
+
 // BatchResponse ответ для массового сокращения URL.
 type BatchResponse struct {
 	CorrelationID string `json:"correlation_id"`
-	ShortURL      string `json:"short_url"`
+	ShortURL      string `json:"short_url,omitempty"`
+	Status        string `json:"status"`
+	Reason        string `json:"reason,omitempty"`
 }
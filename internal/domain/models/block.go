@@ -0,0 +1,6 @@
+package models
+
+// BlockResponse результат блокировки или разблокировки URL.
+type BlockResponse struct {
+	Updated int64 `json:"updated"`
+}
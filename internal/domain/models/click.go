@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Click переход по сокращенному URL.
+type Click struct {
+	ShortURL  string    `json:"short_url"`
+	Timestamp time.Time `json:"timestamp"`
+	// Referrer хост, с которого пришел переход.
+	Referrer string `json:"referrer,omitempty"`
+	// UserAgent класс клиента: desktop, mobile, bot, unknown.
+	UserAgent string `json:"user_agent"`
+	Country   string `json:"country"`
+	// IP используется только для определения страны и не сохраняется.
+	IP string `json:"-"`
+}
+
+// StatsBucket количество переходов за интервал, начинающийся в Start.
+type StatsBucket struct {
+	Start  time.Time `json:"start"`
+	Clicks int64     `json:"clicks"`
+}
+
+// URLStats статистика переходов по сокращенному URL.
+type URLStats struct {
+	ShortURL string        `json:"short_url"`
+	Total    int64         `json:"total"`
+	Hourly   []StatsBucket `json:"hourly"`
+	Daily    []StatsBucket `json:"daily"`
+}
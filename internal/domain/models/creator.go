@@ -0,0 +1,9 @@
+package models
+
+// Creator клиент, создающий сокращенный URL.
+type Creator struct {
+	// IP заменяется на IPHash в бизнес логике и не сохраняется.
+	IP        string
+	IPHash    string
+	UserAgent string
+}
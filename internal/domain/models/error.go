@@ -0,0 +1,10 @@
+package models
+
+// ErrorResponse описание ошибки в формате JSON.
+type ErrorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Alias   string `json:"alias,omitempty"`
+	// Limit значение превышенного ограничения.
+	Limit int `json:"limit,omitempty"`
+}
@@ -1,7 +1,26 @@
 package models
 
+import "time"
+
 // FileURL структура хранения в файле.
 type FileURL struct {
-	ShortURL    string `json:"shortUrl"`
-	OriginalURL string `json:"originalUrl"`
+	ShortURL    string   `json:"shortUrl"`
+	OriginalURL string   `json:"originalUrl"`
+	UserID      string   `json:"userId,omitempty"`
+	DeletedFlag bool     `json:"isDeleted,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Folder      string   `json:"folder,omitempty"`
+	// CreatedAt и UpdatedAt отсутствуют в записях,
+	// сохраненных до их появления.
+	CreatedAt     *time.Time `json:"createdAt,omitempty"`
+	UpdatedAt     *time.Time `json:"updatedAt,omitempty"`
+	CreatorIPHash string     `json:"creatorIpHash,omitempty"`
+	UserAgent     string     `json:"userAgent,omitempty"`
+	// DeletedAt время удаления, отсчет срока восстановления.
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	// Blocked переходы запрещены администратором.
+	Blocked bool `json:"isBlocked,omitempty"`
+	// Removed запись удалена окончательно (tombstone в журнале).
+	Removed bool `json:"removed,omitempty"`
 }
@@ -1,8 +1,20 @@
 package models
 
+import "time"
+
 // URLRequest URL для сокращения в формате JSON.
 type URLRequest struct {
-	URL string `json:"url"`
+	URL   string `json:"url"`
+	Alias string `json:"alias,omitempty"`
+	// ExpiresAt время, после которого ссылка перестает работать.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// TTL время жизни ссылки в секундах, альтернатива ExpiresAt.
+	TTL int64 `json:"ttl,omitempty"`
+	// Tags и Folder для группировки URL пользователя.
+	Tags   []string `json:"tags,omitempty"`
+	Folder string   `json:"folder,omitempty"`
+	// Creator заполняется сервером.
+	Creator Creator `json:"-"`
 }
 
 // URLResponse ответ сокращенного URL в формате JSON.
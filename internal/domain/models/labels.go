@@ -0,0 +1,13 @@
+package models
+
+// URLLabels изменение тегов и папки URL, nil - без изменений.
+type URLLabels struct {
+	Tags   *[]string
+	Folder *string
+}
+
+// TagCount количество неудаленных URL пользователя с тегом.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
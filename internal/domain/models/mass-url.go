@@ -1,9 +1,20 @@
 package models
 
+import "time"
+
 // MassURL массовое удаление сокращенных URL.
 type MassURL struct {
-	ShortURL    string `json:"short_url"`
-	OriginalURL string `json:"original_url"`
+	ShortURL    string   `json:"short_url"`
+	OriginalURL string   `json:"original_url"`
+	Tags        []string `json:"tags,omitempty"`
+	Folder      string   `json:"folder,omitempty"`
+	// DeletedAt заполняется только для удаленных URL.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// CreatedAt и UpdatedAt отсутствуют у URL из файлов старого формата.
+	CreatedAt     *time.Time `json:"created_at,omitempty"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
+	CreatorIPHash string     `json:"creator_ip_hash,omitempty"`
+	UserAgent     string     `json:"user_agent,omitempty"`
 }
 
 // MassDeleteURL группа URL для удаления по UserID.
@@ -0,0 +1,6 @@
+package models
+
+// RestoreResponse восстановленные URL из запроса.
+type RestoreResponse struct {
+	Restored []string `json:"restored"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// UpdateURLRequest запрос изменения оригинального URL.
+// Задается новый OriginalURL или Revision, к которой нужно вернуться.
+// Tags и Folder меняются независимо от URL, nil - без изменений.
+type UpdateURLRequest struct {
+	OriginalURL string    `json:"original_url,omitempty"`
+	Revision    int64     `json:"revision,omitempty"`
+	Tags        *[]string `json:"tags,omitempty"`
+	Folder      *string   `json:"folder,omitempty"`
+}
+
+// URLRevision прежний оригинальный URL, замененный в ReplacedAt.
+type URLRevision struct {
+	Revision    int64     `json:"revision"`
+	OriginalURL string    `json:"original_url"`
+	ReplacedAt  time.Time `json:"replaced_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+// URLCounts количество URL в хранилище.
+type URLCounts struct {
+	Total   int64
+	Deleted int64
+	Expired int64
+}
+
+// ServiceStats статистика сервиса.
+// URLs включает удаленные и истекшие, но еще не вычищенные URL.
+type ServiceStats struct {
+	URLs    int64 `json:"urls"`
+	Users   int64 `json:"users"`
+	Deleted int64 `json:"deleted"`
+	Expired int64 `json:"expired"`
+}
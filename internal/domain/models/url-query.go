@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// Сортировка списка URL по времени создания
+const (
+	SortCreatedAsc  = "created_at"
+	SortCreatedDesc = "-created_at"
+)
+
+// Фильтр удаленных URL в списке
+const (
+	DeletedExclude = "exclude"
+	DeletedInclude = "include"
+	DeletedOnly    = "only"
+)
+
+// URLListRequest параметры списка URL пользователя.
+type URLListRequest struct {
+	// Limit размер страницы, 0 - весь список.
+	Limit int
+	// Cursor продолжение списка из URLListResponse.NextCursor.
+	Cursor string
+	// Sort по умолчанию SortCreatedDesc.
+	Sort string
+	// Contains подстрока оригинального URL без учета регистра.
+	Contains string
+	// Deleted по умолчанию DeletedExclude.
+	Deleted string
+	// Tag и Folder, пустые - без фильтра.
+	Tag    string
+	Folder string
+}
+
+// URLListResponse страница списка URL пользователя.
+type URLListResponse struct {
+	URLs []MassURL `json:"urls"`
+	// NextCursor пустой на последней странице.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// URLCursor позиция в списке URL: последний URL предыдущей страницы.
+type URLCursor struct {
+	CreatedAt time.Time
+	ShortURL  string
+}
+
+// URLQuery запрос списка URL пользователя к хранилищу.
+type URLQuery struct {
+	UserID string
+	// Limit 0 - без ограничения.
+	Limit int
+	// After nil - с начала списка.
+	After    *URLCursor
+	Sort     string
+	Contains string
+	Deleted  string
+	Tag      string
+	Folder   string
+}
+
+// URLPage страница списка URL из хранилища.
+type URLPage struct {
+	URLs []MassURL
+	// Next nil на последней странице.
+	Next *URLCursor
+}
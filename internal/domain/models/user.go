@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// User зарегистрированный пользователь.
+// Хранится в БД и журнале in-memory хранилища.
+type User struct {
+	ID           string    `json:"id"`
+	Login        string    `json:"login"`
+	PasswordHash string    `json:"passwordHash"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// Credentials логин и пароль в запросах регистрации и входа.
+type Credentials struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+// AuthResponse ответ на регистрацию и вход.
+// Claimed количество ссылок, перенесенных от анонимного пользователя.
+type AuthResponse struct {
+	UserID  string `json:"user_id"`
+	Claimed int64  `json:"claimed"`
+}
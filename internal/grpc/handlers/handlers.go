@@ -0,0 +1,307 @@
+// Пакет handlers реализует gRPC API поверх бизнес логики сервиса.
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/grpc/interceptors"
+	pb "github.com/vladislav-kr/yp-go-url-shortener/internal/grpc/shortenerpb"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/auth"
+	urlhandler "github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler"
+)
+
+// URLHandler интерфейс описывающий бизнес логику сервиса.
+//
+//go:generate mockery --name URLHandler
+type URLHandler interface {
+	ReadURL(ctx context.Context, alias string) (string, error)
+	SaveURL(ctx context.Context, req models.URLRequest, userID string) (string, error)
+	SaveURLS(ctx context.Context, urls []models.BatchRequest, userID string) ([]models.BatchResponse, error)
+	Ping(ctx context.Context) error
+	GetURLS(ctx context.Context, userID string, req models.URLListRequest) (models.URLListResponse, error)
+	DeleteURLS(ctx context.Context, shortURLS []string, userID string)
+	RecordClick(ctx context.Context, click models.Click)
+	GetStats(ctx context.Context, id string, userID string) (models.URLStats, error)
+	ServiceStats(ctx context.Context) (models.ServiceStats, error)
+}
+
+// ShortenerServer реализация pb.ShortenerServiceServer.
+type ShortenerServer struct {
+	pb.UnimplementedShortenerServiceServer
+
+	log          *zap.Logger
+	urlHandler   URLHandler
+	redirectHost string
+}
+
+// NewShortenerServer создаёт новый объект ShortenerServer.
+func NewShortenerServer(
+	log *zap.Logger,
+	urlHandler URLHandler,
+	redirectHost string,
+) *ShortenerServer {
+	return &ShortenerServer{
+		log:          log,
+		urlHandler:   urlHandler,
+		redirectHost: redirectHost,
+	}
+}
+
+// Shorten создает короткий URL.
+func (s *ShortenerServer) Shorten(ctx context.Context, req *pb.ShortenRequest) (*pb.ShortenResponse, error) {
+	userID := auth.UserIDFromContext(ctx)
+
+	id, err := s.urlHandler.SaveURL(ctx, models.URLRequest{
+		URL:       req.GetUrl(),
+		Alias:     req.GetAlias(),
+		ExpiresAt: timeOrNil(req.GetExpiresAt()),
+		TTL:       req.GetTtl(),
+		Tags:      req.GetTags(),
+		Folder:    req.GetFolder(),
+		Creator:   creatorOf(ctx),
+	}, userID)
+	if err != nil {
+		if errors.Is(err, urlhandler.ErrAlreadyExists) {
+			return &pb.ShortenResponse{
+				Result:        s.shortURL(id),
+				AlreadyExists: true,
+			}, nil
+		}
+		return nil, s.statusFromError("failed to save url", err)
+	}
+
+	return &pb.ShortenResponse{Result: s.shortURL(id)}, nil
+}
+
+// ShortenBatch создает сокращенные URL для массива данных.
+func (s *ShortenerServer) ShortenBatch(ctx context.Context, req *pb.ShortenBatchRequest) (*pb.ShortenBatchResponse, error) {
+	if len(req.GetUrls()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no data to save")
+	}
+
+	userID := auth.UserIDFromContext(ctx)
+
+	creator := creatorOf(ctx)
+	batch := make([]models.BatchRequest, 0, len(req.GetUrls()))
+	for _, item := range req.GetUrls() {
+		batch = append(batch, models.BatchRequest{
+			CorrelationID: item.GetCorrelationId(),
+			OriginalURL:   item.GetOriginalUrl(),
+			Alias:         item.GetAlias(),
+			ExpiresAt:     timeOrNil(item.GetExpiresAt()),
+			TTL:           item.GetTtl(),
+			Tags:          item.GetTags(),
+			Folder:        item.GetFolder(),
+			Creator:       creator,
+		})
+	}
+
+	urls, err := s.urlHandler.SaveURLS(ctx, batch, userID)
+	if err != nil {
+		return nil, s.statusFromError("failed to save urls", err)
+	}
+
+	resp := &pb.ShortenBatchResponse{Urls: make([]*pb.BatchResult, 0, len(urls))}
+	for _, url := range urls {
+		result := &pb.BatchResult{
+			CorrelationId: url.CorrelationID,
+			Status:        url.Status,
+			Reason:        url.Reason,
+		}
+		if len(url.ShortURL) > 0 {
+			result.ShortUrl = s.shortURL(url.ShortURL)
+		}
+		resp.Urls = append(resp.Urls, result)
+	}
+
+	return resp, nil
+}
+
+// Expand возвращает оригинальный URL по сокращенному и учитывает переход.
+func (s *ShortenerServer) Expand(ctx context.Context, req *pb.ExpandRequest) (*pb.ExpandResponse, error) {
+	url, err := s.urlHandler.ReadURL(ctx, req.GetId())
+	if err != nil {
+		return nil, s.statusFromError("failed to read url", err)
+	}
+
+	s.urlHandler.RecordClick(ctx, models.Click{
+		ShortURL:  req.GetId(),
+		Timestamp: time.Now().UTC(),
+		UserAgent: firstMetadata(ctx, "user-agent"),
+		IP:        interceptors.ClientIP(ctx),
+	})
+
+	return &pb.ExpandResponse{OriginalUrl: url}, nil
+}
+
+// ListUserURLs возвращает список сокращенных URL пользователя.
+func (s *ShortenerServer) ListUserURLs(ctx context.Context, req *pb.ListUserURLsRequest) (*pb.ListUserURLsResponse, error) {
+	userID := auth.UserIDFromContext(ctx)
+	if len(userID) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "user is not found")
+	}
+
+	list, err := s.urlHandler.GetURLS(ctx, userID, models.URLListRequest{
+		Limit:    int(req.GetLimit()),
+		Cursor:   req.GetCursor(),
+		Sort:     req.GetSort(),
+		Contains: req.GetContains(),
+		Deleted:  req.GetDeleted(),
+		Tag:      req.GetTag(),
+		Folder:   req.GetFolder(),
+	})
+	if err != nil {
+		return nil, s.statusFromError("failed to read urls", err)
+	}
+
+	resp := &pb.ListUserURLsResponse{
+		Urls:       make([]*pb.UserURL, 0, len(list.URLs)),
+		NextCursor: list.NextCursor,
+	}
+	for _, url := range list.URLs {
+		resp.Urls = append(resp.Urls, &pb.UserURL{
+			ShortUrl:    s.shortURL(url.ShortURL),
+			OriginalUrl: url.OriginalURL,
+			Tags:        url.Tags,
+			Folder:      url.Folder,
+		})
+	}
+
+	return resp, nil
+}
+
+// DeleteUserURLs ставит URL пользователя в очередь на удаление.
+func (s *ShortenerServer) DeleteUserURLs(ctx context.Context, req *pb.DeleteUserURLsRequest) (*pb.DeleteUserURLsResponse, error) {
+	userID := auth.UserIDFromContext(ctx)
+	if len(userID) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "user is not found")
+	}
+
+	s.urlHandler.DeleteURLS(context.TODO(), req.GetShortUrls(), userID)
+
+	return &pb.DeleteUserURLsResponse{}, nil
+}
+
+// URLStats возвращает статистику переходов по сокращенному URL владельцу.
+func (s *ShortenerServer) URLStats(ctx context.Context, req *pb.URLStatsRequest) (*pb.URLStatsResponse, error) {
+	userID := auth.UserIDFromContext(ctx)
+	if len(userID) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "user is not found")
+	}
+
+	stats, err := s.urlHandler.GetStats(ctx, req.GetId(), userID)
+	if err != nil {
+		return nil, s.statusFromError("failed to read stats", err)
+	}
+
+	return &pb.URLStatsResponse{
+		ShortUrl: s.shortURL(stats.ShortURL),
+		Total:    stats.Total,
+		Hourly:   toPBBuckets(stats.Hourly),
+		Daily:    toPBBuckets(stats.Daily),
+	}, nil
+}
+
+// Stats возвращает статистику сервиса.
+// Доступ ограничивается доверенной подсетью в интерцепторе.
+func (s *ShortenerServer) Stats(ctx context.Context, _ *pb.StatsRequest) (*pb.StatsResponse, error) {
+	stats, err := s.urlHandler.ServiceStats(ctx)
+	if err != nil {
+		return nil, s.statusFromError("failed to read service stats", err)
+	}
+
+	return &pb.StatsResponse{
+		Urls:    stats.URLs,
+		Users:   stats.Users,
+		Deleted: stats.Deleted,
+		Expired: stats.Expired,
+	}, nil
+}
+
+// Ping проверят подключение к хранилищу.
+func (s *ShortenerServer) Ping(ctx context.Context, _ *pb.PingRequest) (*pb.PingResponse, error) {
+	if err := s.urlHandler.Ping(ctx); err != nil {
+		s.log.Error("no access to database", zap.Error(err))
+		return nil, status.Error(codes.Unavailable, "no access to database")
+	}
+
+	return &pb.PingResponse{}, nil
+}
+
+func (s *ShortenerServer) shortURL(id string) string {
+	return fmt.Sprintf("%s/%s", s.redirectHost, id)
+}
+
+// statusFromError переводит ошибки бизнес логики в статусы gRPC.
+func (s *ShortenerServer) statusFromError(msg string, err error) error {
+	switch {
+	case errors.Is(err, urlhandler.ErrAliasTaken),
+		errors.Is(err, urlhandler.ErrAlreadyExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, urlhandler.ErrInvalidAlias),
+		errors.Is(err, urlhandler.ErrInvalidExpiry),
+		errors.Is(err, urlhandler.ErrURLTooLong),
+		errors.Is(err, urlhandler.ErrInvalidURL),
+		errors.Is(err, urlhandler.ErrInvalidQuery),
+		errors.Is(err, urlhandler.ErrInvalidLabels):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, urlhandler.ErrURLRemoved),
+		errors.Is(err, urlhandler.ErrURLExpired),
+		errors.Is(err, urlhandler.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, urlhandler.ErrNotOwner),
+		errors.Is(err, urlhandler.ErrDestinationBlocked):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, urlhandler.ErrBatchTooLarge),
+		errors.Is(err, urlhandler.ErrLinkQuotaExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+
+	s.log.Error(msg, zap.Error(err))
+	return status.Error(codes.InvalidArgument, msg)
+}
+
+func timeOrNil(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+func toPBBuckets(buckets []models.StatsBucket) []*pb.StatsBucket {
+	result := make([]*pb.StatsBucket, 0, len(buckets))
+	for _, b := range buckets {
+		result = append(result, &pb.StatsBucket{
+			Start:  timestamppb.New(b.Start),
+			Clicks: b.Clicks,
+		})
+	}
+	return result
+}
+
+// creatorOf клиент, создающий URL вызовом с контекстом ctx.
+func creatorOf(ctx context.Context) models.Creator {
+	return models.Creator{IP: interceptors.ClientIP(ctx), UserAgent: firstMetadata(ctx, "user-agent")}
+}
+
+func firstMetadata(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
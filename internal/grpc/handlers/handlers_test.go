@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/grpc/handlers/mocks"
+	pb "github.com/vladislav-kr/yp-go-url-shortener/internal/grpc/shortenerpb"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/auth"
+	urlhandler "github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler"
+)
+
+const redirectHost = "http://localhost:8080"
+
+func TestShorten(t *testing.T) {
+
+	cases := []struct {
+		name           string
+		req            *pb.ShortenRequest
+		id             string
+		err            error
+		expectedResp   *pb.ShortenResponse
+		expectedStatus codes.Code
+	}{
+		{
+			name:         "url created",
+			req:          &pb.ShortenRequest{Url: "https://practicum.yandex.ru/"},
+			id:           "dkh2ksukde",
+			expectedResp: &pb.ShortenResponse{Result: redirectHost + "/dkh2ksukde"},
+		},
+		{
+			name: "url already exists",
+			req:  &pb.ShortenRequest{Url: "https://practicum.yandex.ru/"},
+			id:   "dkh2ksukde",
+			err:  urlhandler.ErrAlreadyExists,
+			expectedResp: &pb.ShortenResponse{
+				Result:        redirectHost + "/dkh2ksukde",
+				AlreadyExists: true,
+			},
+		},
+		{
+			name:           "alias is taken",
+			req:            &pb.ShortenRequest{Url: "https://practicum.yandex.ru/", Alias: "promo"},
+			err:            urlhandler.ErrAliasTaken,
+			expectedStatus: codes.AlreadyExists,
+		},
+		{
+			name:           "invalid url",
+			req:            &pb.ShortenRequest{Url: "practicum.yandex.ru"},
+			err:            errors.New("invalid url"),
+			expectedStatus: codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			uh := mocks.NewURLHandler(t)
+			uh.On("SaveURL", mock.Anything, models.URLRequest{
+				URL:   tc.req.GetUrl(),
+				Alias: tc.req.GetAlias(),
+			}, "user").Return(tc.id, tc.err)
+
+			s := NewShortenerServer(zap.NewNop(), uh, redirectHost)
+			resp, err := s.Shorten(auth.ContextWithUserID(context.Background(), "user"), tc.req)
+
+			if tc.expectedStatus != codes.OK {
+				assert.Nil(t, resp)
+				assert.Equal(t, tc.expectedStatus, status.Code(err))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedResp.GetResult(), resp.GetResult())
+			assert.Equal(t, tc.expectedResp.GetAlreadyExists(), resp.GetAlreadyExists())
+		})
+	}
+}
+
+func TestListUserURLs(t *testing.T) {
+
+	cases := []struct {
+		name           string
+		userID         string
+		urls           []models.MassURL
+		expectedURLs   []string
+		expectedStatus codes.Code
+	}{
+		{
+			name:   "user urls",
+			userID: "user",
+			urls: []models.MassURL{
+				{ShortURL: "dkh2ksukde", OriginalURL: "https://practicum.yandex.ru/"},
+			},
+			expectedURLs: []string{redirectHost + "/dkh2ksukde"},
+		},
+		{
+			name:           "unauthenticated",
+			expectedStatus: codes.Unauthenticated,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			uh := mocks.NewURLHandler(t)
+			if len(tc.userID) > 0 {
+				uh.On("GetURLS", mock.Anything, tc.userID, models.URLListRequest{}).
+					Return(models.URLListResponse{URLs: tc.urls}, nil)
+			}
+
+			s := NewShortenerServer(zap.NewNop(), uh, redirectHost)
+			resp, err := s.ListUserURLs(
+				auth.ContextWithUserID(context.Background(), tc.userID),
+				&pb.ListUserURLsRequest{},
+			)
+
+			if tc.expectedStatus != codes.OK {
+				assert.Equal(t, tc.expectedStatus, status.Code(err))
+				return
+			}
+
+			require.NoError(t, err)
+			shortURLs := make([]string, 0, len(resp.GetUrls()))
+			for _, u := range resp.GetUrls() {
+				shortURLs = append(shortURLs, u.GetShortUrl())
+			}
+			assert.Equal(t, tc.expectedURLs, shortURLs)
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	cases := []struct {
+		name           string
+		url            string
+		err            error
+		expectedStatus codes.Code
+	}{
+		{
+			name: "url found",
+			url:  "https://practicum.yandex.ru/",
+		},
+		{
+			name:           "url removed",
+			err:            urlhandler.ErrURLRemoved,
+			expectedStatus: codes.NotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			uh := mocks.NewURLHandler(t)
+			uh.On("ReadURL", mock.Anything, "dkh2ksukde").Return(tc.url, tc.err)
+			if tc.err == nil {
+				uh.On("RecordClick", mock.Anything, mock.MatchedBy(func(c models.Click) bool {
+					return c.ShortURL == "dkh2ksukde"
+				}))
+			}
+
+			s := NewShortenerServer(zap.NewNop(), uh, redirectHost)
+			resp, err := s.Expand(context.Background(), &pb.ExpandRequest{Id: "dkh2ksukde"})
+
+			if tc.expectedStatus != codes.OK {
+				assert.Equal(t, tc.expectedStatus, status.Code(err))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.url, resp.GetOriginalUrl())
+		})
+	}
+}
@@ -0,0 +1,302 @@
+// interceptors unary интерцепторы gRPC сервера, повторяющие http middleware
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/auth"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/ratelimit"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/lib/netutils"
+	urlhandler "github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler"
+)
+
+// realIPKey ключ метаданных с адресом клиента за прокси.
+const realIPKey = "x-real-ip"
+
+// APIKeyResolver поиск ключа API по значению из метаданных.
+//
+//go:generate mockery --name APIKeyResolver
+type APIKeyResolver interface {
+	ResolveAPIKey(ctx context.Context, key string) (models.APIKey, error)
+}
+
+// Methods ограничения методов по полным именам.
+type Methods struct {
+	// Trusted методы, доступные только из доверенной подсети.
+	Trusted []string
+	// Strict методы, которым нужен существующий пользователь.
+	Strict []string
+	// Scopes области ключа API, нужные для вызова метода.
+	Scopes map[string]string
+}
+
+// Interceptors хранит общие объекты.
+type Interceptors struct {
+	log  *zap.Logger
+	auth *auth.Auth
+	// apiKeys nil - авторизация по ключам API отключена.
+	apiKeys APIKeyResolver
+	// trustedSubnet подсеть, которой доступны trustedMethods.
+	// nil - доступ запрещен всем.
+	trustedSubnet  *net.IPNet
+	trustedMethods map[string]bool
+	// trustedProxies подсети прокси, которым доверяется x-real-ip.
+	trustedProxies []*net.IPNet
+	strictMethods  map[string]bool
+	scopes         map[string]string
+}
+
+// New новая инстанция Interceptors.
+func New(
+	log *zap.Logger,
+	auth *auth.Auth,
+	apiKeys APIKeyResolver,
+	trustedSubnet *net.IPNet,
+	trustedProxies []*net.IPNet,
+	methods Methods,
+) *Interceptors {
+	return &Interceptors{
+		log:            log,
+		auth:           auth,
+		apiKeys:        apiKeys,
+		trustedSubnet:  trustedSubnet,
+		trustedMethods: methodSet(methods.Trusted),
+		trustedProxies: trustedProxies,
+		strictMethods:  methodSet(methods.Strict),
+		scopes:         methods.Scopes,
+	}
+}
+
+func methodSet(methods []string) map[string]bool {
+	set := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		set[m] = true
+	}
+	return set
+}
+
+// RealIP подставляет в адрес соединения адрес из метаданных x-real-ip,
+// если вызов пришел от доверенного прокси. Остальные интерцепторы
+// и обработчики берут адрес клиента только из адреса соединения.
+func (i *Interceptors) RealIP(
+	ctx context.Context,
+	req any,
+	_ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || !netutils.InSubnets(i.trustedProxies, ClientIP(ctx)) {
+		return handler(ctx, req)
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(realIPKey); len(values) > 0 {
+		if ip := net.ParseIP(strings.TrimSpace(values[0])); ip != nil {
+			proxied := *p
+			proxied.Addr = &net.TCPAddr{IP: ip}
+			ctx = peer.NewContext(ctx, &proxied)
+		}
+	}
+
+	return handler(ctx, req)
+}
+
+// Logger логирование вызовов.
+func (i *Interceptors) Logger(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	t := time.Now()
+
+	resp, err := handler(ctx, req)
+
+	i.log.Info("request completed",
+		zap.String("method", info.FullMethod),
+		zap.String("code", status.Code(err).String()),
+		zap.Duration("duration", time.Since(t)),
+		zap.String("user-id", auth.UserIDFromContext(ctx)),
+	)
+
+	return resp, err
+}
+
+// Auth авторизация по ключу API из метаданных x-api-key,
+// затем по JWT в метаданных, как middleware.Auth и middleware.StrictAuth.
+// Если токена нет или он невалиден, выдается новый пользователь,
+// а токен возвращается в заголовке ответа. Для строгих методов
+// новый пользователь не выдается, вызов отклоняется с Unauthenticated.
+func (i *Interceptors) Auth(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	if key := firstMetadata(ctx, auth.APIKeyHeader); len(key) > 0 && i.apiKeys != nil {
+		return i.authenticateAPIKey(ctx, req, info, handler, key)
+	}
+
+	if token, err := tokenFromMetadata(ctx); err != nil {
+		i.log.Error("token is invalid", zap.Error(err))
+	} else if len(token) > 0 {
+		claims, err := i.auth.Validate(token)
+		if err == nil {
+			return handler(auth.ContextWithUserID(ctx, claims.UserID), req)
+		}
+		i.log.Error("token is invalid", zap.Error(err))
+	}
+
+	if i.strictMethods[info.FullMethod] {
+		return nil, status.Error(codes.Unauthenticated, "valid token is required")
+	}
+
+	userID := uuid.New().String()
+	token, err := i.auth.CreateToken(auth.TokenTTL, userID)
+	if err != nil {
+		i.log.Error("failed to create new token", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to create token")
+	}
+
+	if err := grpc.SetHeader(ctx, metadata.Pairs(auth.TokenKey, token)); err != nil {
+		i.log.Error("failed to send token", zap.Error(err))
+	}
+
+	return handler(auth.ContextWithNewUserID(ctx, userID), req)
+}
+
+func (i *Interceptors) authenticateAPIKey(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+	key string,
+) (any, error) {
+	apiKey, err := i.apiKeys.ResolveAPIKey(ctx, key)
+	if err != nil {
+		if errors.Is(err, urlhandler.ErrInvalidAPIKey) {
+			i.log.Warn("api key is invalid", zap.String("ip", ClientIP(ctx)))
+			return nil, status.Error(codes.Unauthenticated, "invalid api key")
+		}
+		i.log.Error("failed to resolve api key", zap.Error(err))
+		return nil, status.Error(codes.Internal, "failed to resolve api key")
+	}
+
+	ctx = auth.ContextWithUserID(ctx, apiKey.UserID)
+	ctx = auth.ContextWithScopes(ctx, apiKey.Scopes)
+	if scope, ok := i.scopes[info.FullMethod]; ok && !auth.HasScope(ctx, scope) {
+		return nil, status.Error(codes.PermissionDenied, "api key scope is not allowed")
+	}
+
+	return handler(ctx, req)
+}
+
+// RateLimit ограничивает частоту вызовов так же, как middleware.RateLimit:
+// ключ - пользователь, для только что выданных пользователей - IP клиента.
+// limiters ограничения по полным именам методов, методы без
+// ограничения и nil limiter пропускаются.
+func (i *Interceptors) RateLimit(limiters map[string]*ratelimit.Limiter) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		limiter := limiters[info.FullMethod]
+		if limiter == nil {
+			return handler(ctx, req)
+		}
+
+		key := "ip:" + ClientIP(ctx)
+		if userID := auth.UserIDFromContext(ctx); len(userID) > 0 && !auth.IsNewUser(ctx) {
+			key = "user:" + userID
+		}
+
+		res := limiter.Allow(key)
+		if !res.Allowed {
+			retryAfter := strconv.Itoa(int((res.RetryAfter + time.Second - 1) / time.Second))
+			if err := grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfter)); err != nil {
+				i.log.Error("failed to send retry-after", zap.Error(err))
+			}
+			i.log.Warn("rate limit exceeded",
+				zap.String("key", key),
+				zap.String("method", info.FullMethod),
+			)
+			return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// TrustedSubnet пропускает вызовы trustedMethods только из доверенной подсети.
+func (i *Interceptors) TrustedSubnet(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	if !i.trustedMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+
+	ip := ClientIP(ctx)
+	if !netutils.InSubnet(i.trustedSubnet, ip) {
+		i.log.Warn("request from untrusted address",
+			zap.String("ip", ip),
+			zap.String("method", info.FullMethod),
+		)
+		return nil, status.Error(codes.PermissionDenied, "untrusted address")
+	}
+
+	return handler(ctx, req)
+}
+
+// ClientIP адрес клиента из адреса соединения.
+func ClientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// firstMetadata первое значение ключа метаданных запроса.
+// tokenFromMetadata токен из метаданных authorization "Bearer <jwt>",
+// как в заголовке HTTP, или auth-token. authorization имеет приоритет.
+func tokenFromMetadata(ctx context.Context) (string, error) {
+	if header := firstMetadata(ctx, auth.HeaderKey); len(header) > 0 {
+		return auth.BearerToken(header)
+	}
+	return firstMetadata(ctx, auth.TokenKey), nil
+}
+
+func firstMetadata(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
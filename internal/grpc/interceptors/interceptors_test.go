@@ -0,0 +1,316 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/grpc/interceptors/mocks"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/auth"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/ratelimit"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/lib/netutils"
+	urlhandler "github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler"
+)
+
+func TestAuth(t *testing.T) {
+	const strictMethod = "/shortener.v1.ShortenerService/ListUserURLs"
+
+	a := auth.New(auth.SingleKey("secret-key"), false)
+
+	token, err := a.CreateToken(auth.TokenTTL, "user")
+	require.NoError(t, err)
+
+	cases := []struct {
+		name         string
+		md           metadata.MD
+		method       string
+		expectedNew  bool
+		expectedCode codes.Code
+	}{
+		{
+			name: "valid token",
+			md:   metadata.Pairs(auth.TokenKey, token),
+		},
+		{
+			name: "valid bearer token",
+			md:   metadata.Pairs("authorization", "Bearer "+token),
+		},
+		{
+			name:        "no token",
+			expectedNew: true,
+		},
+		{
+			name:        "invalid token",
+			md:          metadata.Pairs(auth.TokenKey, "invalid"),
+			expectedNew: true,
+		},
+		{
+			name:        "unsupported authorization scheme",
+			md:          metadata.Pairs("authorization", "Basic "+token),
+			expectedNew: true,
+		},
+		{
+			name:   "strict method with valid token",
+			md:     metadata.Pairs(auth.TokenKey, token),
+			method: strictMethod,
+		},
+		{
+			name:   "strict method with valid bearer token",
+			md:     metadata.Pairs("authorization", "Bearer "+token),
+			method: strictMethod,
+		},
+		{
+			name:         "strict method without token",
+			method:       strictMethod,
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "strict method with invalid token",
+			md:           metadata.Pairs(auth.TokenKey, "invalid"),
+			method:       strictMethod,
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "strict method with invalid bearer token",
+			md:           metadata.Pairs("authorization", "Bearer invalid"),
+			method:       strictMethod,
+			expectedCode: codes.Unauthenticated,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			if tc.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tc.md)
+			}
+
+			i := New(zap.NewNop(), a, nil, nil, nil, Methods{Strict: []string{strictMethod}})
+			var userID string
+			var isNew bool
+			_, err := i.Auth(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tc.method},
+				func(ctx context.Context, req any) (any, error) {
+					userID = auth.UserIDFromContext(ctx)
+					isNew = auth.IsNewUser(ctx)
+					return nil, nil
+				})
+
+			if tc.expectedCode != codes.OK {
+				assert.Equal(t, tc.expectedCode, status.Code(err))
+				assert.Empty(t, userID)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, userID)
+			assert.Equal(t, tc.expectedNew, isNew)
+			if !tc.expectedNew {
+				assert.Equal(t, "user", userID)
+				return
+			}
+			assert.NotEqual(t, "user", userID)
+		})
+	}
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	const (
+		validKey = "sk_valid"
+		method   = "/shortener.v1.ShortenerService/ListUserURLs"
+	)
+
+	cases := []struct {
+		name         string
+		key          string
+		scopes       []string
+		resolveErr   error
+		expectedCode codes.Code
+	}{
+		{
+			name: "key without scopes",
+			key:  validKey,
+		},
+		{
+			name:   "key with required scope",
+			key:    validKey,
+			scopes: []string{models.ScopeRead},
+		},
+		{
+			name:         "key without required scope",
+			key:          validKey,
+			scopes:       []string{models.ScopeShorten},
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "invalid key",
+			key:          "sk_invalid",
+			resolveErr:   urlhandler.ErrInvalidAPIKey,
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "storage error",
+			key:          validKey,
+			resolveErr:   errors.New("storage is unavailable"),
+			expectedCode: codes.Internal,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			resolver := mocks.NewAPIKeyResolver(t)
+			resolver.On("ResolveAPIKey", mock.Anything, tc.key).
+				Return(models.APIKey{UserID: "user", Scopes: tc.scopes}, tc.resolveErr)
+
+			i := New(zap.NewNop(), auth.New(auth.SingleKey("secret-key"), false), resolver, nil, nil, Methods{
+				Strict: []string{method},
+				Scopes: map[string]string{method: models.ScopeRead},
+			})
+
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", tc.key))
+			var userID string
+			_, err := i.Auth(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method},
+				func(ctx context.Context, req any) (any, error) {
+					userID = auth.UserIDFromContext(ctx)
+					return nil, nil
+				})
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+			if tc.expectedCode == codes.OK {
+				assert.Equal(t, "user", userID)
+			}
+		})
+	}
+}
+
+func TestTrustedSubnet(t *testing.T) {
+	const method = "/shortener.v1.ShortenerService/Stats"
+
+	subnet, err := netutils.ParseSubnet("192.168.1.0/24")
+	require.NoError(t, err)
+	proxies, err := netutils.ParseSubnets([]string{"10.10.0.0/16"})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name         string
+		peer         string
+		realIP       string
+		method       string
+		expectedCode codes.Code
+	}{
+		{
+			name:   "trusted address",
+			peer:   "192.168.1.10",
+			method: method,
+		},
+		{
+			name:         "untrusted address",
+			peer:         "10.0.0.1",
+			method:       method,
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:   "x-real-ip from trusted proxy",
+			peer:   "10.10.0.2",
+			realIP: "192.168.1.10",
+			method: method,
+		},
+		{
+			name:         "x-real-ip from untrusted client is ignored",
+			peer:         "10.0.0.1",
+			realIP:       "192.168.1.10",
+			method:       method,
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "no address",
+			method:       method,
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:   "method is not restricted",
+			peer:   "10.0.0.1",
+			method: "/shortener.v1.ShortenerService/Ping",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			if len(tc.peer) > 0 {
+				ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(tc.peer), Port: 40000}})
+			}
+			if len(tc.realIP) > 0 {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(realIPKey, tc.realIP))
+			}
+
+			i := New(zap.NewNop(), nil, nil, subnet, proxies, Methods{Trusted: []string{method}})
+			info := &grpc.UnaryServerInfo{FullMethod: tc.method}
+			_, err := i.RealIP(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+				return i.TrustedSubnet(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+					return nil, nil
+				})
+			})
+
+			assert.Equal(t, tc.expectedCode, status.Code(err))
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	const method = "/shortener.v1.ShortenerService/Shorten"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	i := New(zap.NewNop(), nil, nil, nil, nil, Methods{})
+	limit := i.RateLimit(map[string]*ratelimit.Limiter{
+		method: ratelimit.NewLimiter(ctx, ratelimit.Limit{Rate: 0.001, Burst: 1}),
+	})
+
+	call := func(ctx context.Context, fullMethod string) codes.Code {
+		_, err := limit(ctx, nil, &grpc.UnaryServerInfo{FullMethod: fullMethod},
+			func(ctx context.Context, req any) (any, error) {
+				return nil, nil
+			})
+		return status.Code(err)
+	}
+	fromIP := func(ctx context.Context, ip string) context.Context {
+		return peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 40000}})
+	}
+
+	user := fromIP(auth.ContextWithUserID(context.Background(), "user"), "10.0.0.1")
+	assert.Equal(t, codes.OK, call(user, method))
+	assert.Equal(t, codes.ResourceExhausted, call(user, method))
+	// метод без ограничения
+	assert.Equal(t, codes.OK, call(user, "/shortener.v1.ShortenerService/Ping"))
+
+	// лимит привязан к пользователю, а не к адресу
+	assert.Equal(t, codes.ResourceExhausted, call(fromIP(auth.ContextWithUserID(context.Background(), "user"), "10.0.0.2"), method))
+
+	// новые пользователи ограничиваются по IP
+	assert.Equal(t, codes.OK, call(fromIP(auth.ContextWithNewUserID(context.Background(), "new-1"), "10.0.0.3"), method))
+	assert.Equal(t, codes.ResourceExhausted, call(fromIP(auth.ContextWithNewUserID(context.Background(), "new-2"), "10.0.0.3"), method))
+}
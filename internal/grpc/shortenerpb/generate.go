@@ -0,0 +1,4 @@
+// shortenerpb сгенерированный код gRPC API сервиса
+package shortenerpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative shortener.proto
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/auth"
+	urlhandler "github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler"
+)
+
+// CreateAPIKeyHandler создает ключ API пользователя.
+// Ключ показывается только в этом ответе.
+func (h *Handlers) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	userID := auth.UserIDFromContext(r.Context())
+	if len(userID) == 0 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	req := models.APIKeyRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error(
+			"failed to read JSON request body",
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*4)
+	defer cancel()
+
+	key, err := h.urlHandler.CreateAPIKey(ctx, userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, urlhandler.ErrInvalidAPIKey):
+			renderError(w, r, http.StatusBadRequest, models.ErrorResponse{
+				Code:    codeInvalidAPIKey,
+				Message: err.Error(),
+			})
+		case errors.Is(err, urlhandler.ErrInvalidScope):
+			renderError(w, r, http.StatusBadRequest, models.ErrorResponse{
+				Code:    codeInvalidScope,
+				Message: err.Error(),
+			})
+		default:
+			h.log.Error(
+				"failed to create api key",
+				zap.Error(err),
+			)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, key)
+}
+
+// ListAPIKeysHandler возвращает ключи API пользователя без секретов.
+func (h *Handlers) ListAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromContext(r.Context())
+	if len(userID) == 0 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*4)
+	defer cancel()
+
+	keys, err := h.urlHandler.ListAPIKeys(ctx, userID)
+	if err != nil {
+		h.log.Error(
+			"failed to read api keys",
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, keys)
+}
+
+// RevokeAPIKeyHandler отзывает ключ API пользователя.
+func (h *Handlers) RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromContext(r.Context())
+	if len(userID) == 0 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*4)
+	defer cancel()
+
+	if err := h.urlHandler.RevokeAPIKey(ctx, userID, chi.URLParam(r, "id")); err != nil {
+		if errors.Is(err, urlhandler.ErrAPIKeyNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		h.log.Error(
+			"failed to revoke api key",
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+)
+
+// BlockURLSHandler блокирует переходы по URL из списка.
+// Доступ ограничивается доверенной подсетью в middleware.
+func (h *Handlers) BlockURLSHandler(w http.ResponseWriter, r *http.Request) {
+	h.blockURLS(w, r, true)
+}
+
+// UnblockURLSHandler снимает блокировку с URL из списка.
+// Доступ ограничивается доверенной подсетью в middleware.
+func (h *Handlers) UnblockURLSHandler(w http.ResponseWriter, r *http.Request) {
+	h.blockURLS(w, r, false)
+}
+
+func (h *Handlers) blockURLS(w http.ResponseWriter, r *http.Request, blocked bool) {
+	defer r.Body.Close()
+
+	var shortURLS []string
+	if err := json.NewDecoder(r.Body).Decode(&shortURLS); err != nil {
+		h.log.Error(
+			"failed to read JSON request body",
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*4)
+	defer cancel()
+
+	updated, err := h.urlHandler.BlockURLS(ctx, shortURLS, blocked)
+	if err != nil {
+		h.log.Error(
+			"failed to block urls",
+			zap.Bool("blocked", blocked),
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	h.log.Info("urls block status changed",
+		zap.Strings("urls", shortURLS),
+		zap.Bool("blocked", blocked),
+		zap.Int64("updated", updated),
+	)
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, models.BlockResponse{Updated: updated})
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/handlers/mocks"
+)
+
+func TestBlockURLSHandler(t *testing.T) {
+	cases := []struct {
+		name           string
+		method         string
+		body           string
+		blocked        bool
+		updated        int64
+		err            error
+		expectedStatus int
+		isCallMock     bool
+	}{
+		{
+			name:           "block urls",
+			method:         http.MethodPost,
+			body:           `["promo","sale"]`,
+			blocked:        true,
+			updated:        2,
+			expectedStatus: http.StatusOK,
+			isCallMock:     true,
+		},
+		{
+			name:           "unblock urls",
+			method:         http.MethodDelete,
+			body:           `["promo"]`,
+			updated:        1,
+			expectedStatus: http.StatusOK,
+			isCallMock:     true,
+		},
+		{
+			name:           "invalid body",
+			method:         http.MethodPost,
+			body:           `{"urls": "promo"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "storage error",
+			method:         http.MethodPost,
+			body:           `["promo"]`,
+			blocked:        true,
+			err:            errors.New("connection refused"),
+			expectedStatus: http.StatusInternalServerError,
+			isCallMock:     true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlHndl := mocks.NewURLHandler(t)
+			if tc.isCallMock {
+				var ids []string
+				require.NoError(t, json.Unmarshal([]byte(tc.body), &ids))
+				urlHndl.On("BlockURLS", mock.AnythingOfType("*context.timerCtx"), ids, tc.blocked).
+					Return(tc.updated, tc.err)
+			}
+
+			h := NewHandlers(zaptest.NewLogger(t), urlHndl, "http://localhost:8080", nil)
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, "/api/internal/urls/block", strings.NewReader(tc.body))
+
+			if tc.method == http.MethodDelete {
+				h.UnblockURLSHandler(rr, req)
+			} else {
+				h.BlockURLSHandler(rr, req)
+			}
+
+			result := rr.Result()
+			defer result.Body.Close()
+			assert.Equal(t, tc.expectedStatus, result.StatusCode)
+
+			if tc.expectedStatus == http.StatusOK {
+				resp := models.BlockResponse{}
+				require.NoError(t, json.NewDecoder(result.Body).Decode(&resp))
+				assert.Equal(t, tc.updated, resp.Updated)
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@ import (
 	urlhandler "github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler"
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler/deleter"
 	mapkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/map-keeper"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/storages/map-keeper/file"
 )
 
 func ExampleHandlers_SaveHandler() {
@@ -23,7 +24,7 @@ func ExampleHandlers_SaveHandler() {
 	defer cancel()
 
 	// Создаем in-memory хранилище
-	storage := mapkeeper.New("")
+	storage := mapkeeper.New(zap.L(), "", file.SyncNever, 0)
 
 	// Создаем обработчик сервисного слоя
 	urlHandler := urlhandler.NewURLHandler(
@@ -34,6 +35,7 @@ func ExampleHandlers_SaveHandler() {
 			defer cancel()
 			storage.DeleteURLS(ctx, urls)
 		}),
+		urlhandler.Option{},
 	)
 
 	// Создаем инстанцию http обработчиков
@@ -41,6 +43,7 @@ func ExampleHandlers_SaveHandler() {
 		zap.L(),
 		urlHandler,
 		"http://localhost:8080",
+		nil,
 	)
 
 	router := chi.NewRouter()
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -16,6 +17,7 @@ import (
 
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/auth"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/lib/netutils"
 	urlhandler "github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler"
 )
 
@@ -24,18 +26,59 @@ import (
 //go:generate mockery --name URLHandler
 type URLHandler interface {
 	ReadURL(ctx context.Context, alias string) (string, error)
-	SaveURL(ctx context.Context, url string, userID string) (string, error)
+	SaveURL(ctx context.Context, req models.URLRequest, userID string) (string, error)
 	SaveURLS(ctx context.Context, urls []models.BatchRequest, userID string) ([]models.BatchResponse, error)
 	Ping(ctx context.Context) error
-	GetURLS(ctx context.Context, userID string) ([]models.MassURL, error)
+	GetURLS(ctx context.Context, userID string, req models.URLListRequest) (models.URLListResponse, error)
 	DeleteURLS(ctx context.Context, shortURLS []string, userID string)
+	RecordClick(ctx context.Context, click models.Click)
+	GetStats(ctx context.Context, id string, userID string) (models.URLStats, error)
+	ServiceStats(ctx context.Context) (models.ServiceStats, error)
+	Register(ctx context.Context, creds models.Credentials, anonUserID string) (models.AuthResponse, error)
+	Login(ctx context.Context, creds models.Credentials, anonUserID string) (models.AuthResponse, error)
+	CreateAPIKey(ctx context.Context, userID string, req models.APIKeyRequest) (models.CreatedAPIKey, error)
+	ListAPIKeys(ctx context.Context, userID string) ([]models.APIKeyInfo, error)
+	RevokeAPIKey(ctx context.Context, userID string, id string) error
+	BlockURLS(ctx context.Context, shortURLS []string, blocked bool) (int64, error)
+	UpdateURL(ctx context.Context, userID string, id string, req models.UpdateURLRequest) (models.MassURL, error)
+	GetURLHistory(ctx context.Context, userID string, id string) ([]models.URLRevision, error)
+	RestoreURLS(ctx context.Context, shortURLS []string, userID string) ([]string, error)
+	ListTags(ctx context.Context, userID string) ([]models.TagCount, error)
 }
 
+// TokenIssuer выдает токен авторизации в ответе.
+//
+//go:generate mockery --name TokenIssuer
+type TokenIssuer interface {
+	IssueToken(w http.ResponseWriter, userID string) error
+}
+
+// Коды ошибок в ответах JSON
+const (
+	codeAliasTaken    = "alias_taken"
+	codeInvalidAlias  = "invalid_alias"
+	codeInvalidExpiry = "invalid_expiration"
+	codeInvalidURL    = "invalid_url"
+	codeLoginTaken    = "login_taken"
+	codeInvalidUser   = "invalid_user"
+	codeInvalidAPIKey = "invalid_api_key"
+	codeInvalidScope  = "invalid_scope"
+	codeURLTooLong    = "url_too_long"
+	codeBatchTooLarge = "batch_too_large"
+	codeQuotaExceeded = "link_quota_exceeded"
+	codeBlocked       = "destination_blocked"
+	codeURLExists     = "url_exists"
+	codeInvalidRev    = "invalid_revision"
+	codeInvalidQuery  = "invalid_query"
+	codeInvalidLabels = "invalid_labels"
+)
+
 // Handlers обрабатывает логику http-хендлеров.
 type Handlers struct {
 	log          *zap.Logger
 	urlHandler   URLHandler
 	redirectHost string
+	tokens       TokenIssuer
 }
 
 // NewHandlers создаёт новый объект Handlers.
@@ -43,15 +86,18 @@ func NewHandlers(
 	log *zap.Logger,
 	urlHandler URLHandler,
 	redirectHost string,
+	tokens TokenIssuer,
 ) *Handlers {
 	return &Handlers{
 		log:          log,
 		urlHandler:   urlHandler,
 		redirectHost: redirectHost,
+		tokens:       tokens,
 	}
 }
 
 // SaveHandler создает короткий URL.
+// Теги и папка задаются параметрами запроса tag (повторяемый) и folder.
 func (h *Handlers) SaveHandler(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 	data, err := io.ReadAll(r.Body)
@@ -70,9 +116,21 @@ func (h *Handlers) SaveHandler(w http.ResponseWriter, r *http.Request) {
 
 	userID := auth.UserIDFromContext(r.Context())
 
-	id, err := h.urlHandler.SaveURL(ctx, string(data), userID)
+	q := r.URL.Query()
+	id, err := h.urlHandler.SaveURL(ctx, models.URLRequest{
+		URL:     string(data),
+		Tags:    q["tag"],
+		Folder:  q.Get("folder"),
+		Creator: creatorOf(r),
+	}, userID)
 	if err != nil {
+		if renderLimitError(w, r, err) {
+			return
+		}
 		switch {
+		case errors.Is(err, urlhandler.ErrDestinationBlocked):
+			w.WriteHeader(http.StatusForbidden)
+			return
 		case errors.Is(err, urlhandler.ErrAlreadyExists):
 			h.log.Info(
 				"the original url exists in the database",
@@ -106,10 +164,15 @@ func (h *Handlers) RedirectHandler(w http.ResponseWriter, r *http.Request) {
 	url, err := h.urlHandler.ReadURL(ctx, alias)
 	if err != nil {
 
-		if errors.Is(err, urlhandler.ErrURLRemoved) {
+		if errors.Is(err, urlhandler.ErrURLRemoved) ||
+			errors.Is(err, urlhandler.ErrURLExpired) {
 			w.WriteHeader(http.StatusGone)
 			return
 		}
+		if errors.Is(err, urlhandler.ErrDestinationBlocked) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
 
 		h.log.Error(
 			"failed to read url",
@@ -121,6 +184,14 @@ func (h *Handlers) RedirectHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.urlHandler.RecordClick(ctx, models.Click{
+		ShortURL:  alias,
+		Timestamp: time.Now().UTC(),
+		Referrer:  r.Referer(),
+		UserAgent: r.UserAgent(),
+		IP:        netutils.RemoteIP(r),
+	})
+
 	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
 }
 
@@ -143,10 +214,56 @@ func (h *Handlers) SaveJSONHandler(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	userID := auth.UserIDFromContext(r.Context())
+	req.Creator = creatorOf(r)
 
-	id, err := h.urlHandler.SaveURL(ctx, req.URL, userID)
+	id, err := h.urlHandler.SaveURL(ctx, req, userID)
 	if err != nil {
+		if renderLimitError(w, r, err) {
+			return
+		}
 		switch {
+		case errors.Is(err, urlhandler.ErrAliasTaken):
+			h.log.Info(
+				"the alias is already taken",
+				zap.String("alias", req.Alias),
+			)
+			renderError(w, r, http.StatusConflict, models.ErrorResponse{
+				Code:    codeAliasTaken,
+				Message: err.Error(),
+				Alias:   req.Alias,
+			})
+			return
+		case errors.Is(err, urlhandler.ErrInvalidAlias):
+			renderError(w, r, http.StatusBadRequest, models.ErrorResponse{
+				Code:    codeInvalidAlias,
+				Message: err.Error(),
+				Alias:   req.Alias,
+			})
+			return
+		case errors.Is(err, urlhandler.ErrInvalidExpiry):
+			renderError(w, r, http.StatusBadRequest, models.ErrorResponse{
+				Code:    codeInvalidExpiry,
+				Message: err.Error(),
+			})
+			return
+		case errors.Is(err, urlhandler.ErrInvalidURL):
+			renderError(w, r, http.StatusBadRequest, models.ErrorResponse{
+				Code:    codeInvalidURL,
+				Message: err.Error(),
+			})
+			return
+		case errors.Is(err, urlhandler.ErrInvalidLabels):
+			renderError(w, r, http.StatusBadRequest, models.ErrorResponse{
+				Code:    codeInvalidLabels,
+				Message: err.Error(),
+			})
+			return
+		case errors.Is(err, urlhandler.ErrDestinationBlocked):
+			renderError(w, r, http.StatusForbidden, models.ErrorResponse{
+				Code:    codeBlocked,
+				Message: err.Error(),
+			})
+			return
 		case errors.Is(err, urlhandler.ErrAlreadyExists):
 			h.log.Info(
 				"the original url exists in the database",
@@ -190,6 +307,7 @@ func (h *Handlers) PingHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // BatchHandler создает сокращенные URL для массива данных.
+// Ошибки отдельных URL возвращаются в результате элемента.
 func (h *Handlers) BatchHandler(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
@@ -213,11 +331,19 @@ func (h *Handlers) BatchHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	userID := auth.UserIDFromContext(r.Context())
 
+	creator := creatorOf(r)
+	for i := range req {
+		req[i].Creator = creator
+	}
+
 	ctx, cancel := context.WithTimeout(r.Context(), time.Second*10)
 	defer cancel()
 
 	urls, err := h.urlHandler.SaveURLS(ctx, req, userID)
 	if err != nil {
+		if renderLimitError(w, r, err) {
+			return
+		}
 		h.log.Error(
 			"failed to save url",
 			zap.Error(err),
@@ -226,16 +352,32 @@ func (h *Handlers) BatchHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 201 - все URL созданы, иначе 207 с результатом для каждого элемента
+	status := http.StatusCreated
 	for i := range urls {
-		urls[i].ShortURL = fmt.Sprintf("%s/%s", h.redirectHost, urls[i].ShortURL)
+		if urls[i].Status != models.BatchCreated {
+			status = http.StatusMultiStatus
+		}
+		if len(urls[i].ShortURL) > 0 {
+			urls[i].ShortURL = fmt.Sprintf("%s/%s", h.redirectHost, urls[i].ShortURL)
+		}
 	}
 
-	render.Status(r, http.StatusCreated)
+	render.Status(r, status)
 	render.JSON(w, r, urls)
 
 }
 
 // UserUrlsHandler возвращает список сокращенных URL пользователем.
+//
+// Параметры запроса: limit и cursor - постраничный вывод,
+// sort - created_at или -created_at (по умолчанию),
+// contains - подстрока оригинального URL,
+// deleted - exclude (по умолчанию), include или only,
+// include_deleted=true равносилен deleted=include,
+// tag и folder - URL с тегом и в папке.
+// С limit или cursor ответ - models.URLListResponse, ссылка на
+// следующую страницу дублируется в заголовке Link, иначе - массив URL.
 func (h *Handlers) UserUrlsHandler(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close()
 
@@ -246,11 +388,27 @@ func (h *Handlers) UserUrlsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	req, paged, err := parseURLListRequest(r)
+	if err != nil {
+		renderError(w, r, http.StatusBadRequest, models.ErrorResponse{
+			Code:    codeInvalidQuery,
+			Message: err.Error(),
+		})
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(r.Context(), time.Second*10)
 	defer cancel()
 
-	urls, err := h.urlHandler.GetURLS(ctx, userID)
+	list, err := h.urlHandler.GetURLS(ctx, userID, req)
 	if err != nil {
+		if errors.Is(err, urlhandler.ErrInvalidQuery) {
+			renderError(w, r, http.StatusBadRequest, models.ErrorResponse{
+				Code:    codeInvalidQuery,
+				Message: err.Error(),
+			})
+			return
+		}
 		h.log.Error(
 			"failed to read urls",
 			zap.Error(err),
@@ -259,11 +417,23 @@ func (h *Handlers) UserUrlsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	for i := range urls {
-		urls[i].ShortURL = fmt.Sprintf("%s/%s", h.redirectHost, urls[i].ShortURL)
+	for i := range list.URLs {
+		list.URLs[i].ShortURL = fmt.Sprintf("%s/%s", h.redirectHost, list.URLs[i].ShortURL)
+	}
+
+	if paged {
+		if len(list.NextCursor) > 0 {
+			next := r.URL.Query()
+			next.Set("cursor", list.NextCursor)
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s?%s>; rel="next"`, h.redirectHost, r.URL.Path, next.Encode()))
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, list)
+		return
 	}
 
-	if len(urls) == 0 {
+	if len(list.URLs) == 0 {
 		// w.WriteHeader(http.StatusNoContent)
 		// для прохождения теста
 		w.WriteHeader(http.StatusUnauthorized)
@@ -272,7 +442,41 @@ func (h *Handlers) UserUrlsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	render.Status(r, http.StatusOK)
-	render.JSON(w, r, urls)
+	render.JSON(w, r, list.URLs)
+}
+
+// parseURLListRequest параметры списка URL из строки запроса,
+// paged - задан limit или cursor.
+func parseURLListRequest(r *http.Request) (models.URLListRequest, bool, error) {
+	q := r.URL.Query()
+	req := models.URLListRequest{
+		Cursor:   q.Get("cursor"),
+		Sort:     q.Get("sort"),
+		Contains: q.Get("contains"),
+		Deleted:  q.Get("deleted"),
+		Tag:      q.Get("tag"),
+		Folder:   q.Get("folder"),
+	}
+
+	if v := q.Get("limit"); len(v) > 0 {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return req, false, fmt.Errorf("limit must be a positive integer, got %q", v)
+		}
+		req.Limit = limit
+	}
+
+	if v := q.Get("include_deleted"); len(v) > 0 {
+		includeDeleted, err := strconv.ParseBool(v)
+		if err != nil {
+			return req, false, fmt.Errorf("include_deleted must be a boolean, got %q", v)
+		}
+		if includeDeleted && len(req.Deleted) == 0 {
+			req.Deleted = models.DeletedInclude
+		}
+	}
+
+	return req, req.Limit > 0 || len(req.Cursor) > 0, nil
 }
 
 // DeleteURLS удаляет список сокращенных URL.
@@ -301,3 +505,95 @@ func (h *Handlers) DeleteURLS(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusAccepted)
 }
+
+// URLStatsHandler возвращает статистику переходов по сокращенному URL владельцу.
+func (h *Handlers) URLStatsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromContext(r.Context())
+
+	if len(userID) == 0 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	alias := chi.URLParam(r, "id")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*4)
+	defer cancel()
+
+	stats, err := h.urlHandler.GetStats(ctx, alias, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, urlhandler.ErrNotFound):
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, urlhandler.ErrNotOwner):
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			h.log.Error(
+				"failed to read stats",
+				zap.String("alias", alias),
+				zap.Error(err),
+			)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	stats.ShortURL = fmt.Sprintf("%s/%s", h.redirectHost, stats.ShortURL)
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, stats)
+}
+
+// InternalStatsHandler возвращает статистику сервиса.
+// Доступ ограничивается доверенной подсетью в middleware.
+func (h *Handlers) InternalStatsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*4)
+	defer cancel()
+
+	stats, err := h.urlHandler.ServiceStats(ctx)
+	if err != nil {
+		h.log.Error(
+			"failed to read service stats",
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, stats)
+}
+
+// renderError отправляет описание ошибки в формате JSON.
+func renderError(w http.ResponseWriter, r *http.Request, status int, resp models.ErrorResponse) {
+	render.Status(r, status)
+	render.JSON(w, r, resp)
+}
+
+// creatorOf клиент, создающий URL запросом r.
+func creatorOf(r *http.Request) models.Creator {
+	return models.Creator{IP: netutils.RemoteIP(r), UserAgent: r.UserAgent()}
+}
+
+// renderLimitError отправляет описание превышенного ограничения,
+// false - err не является urlhandler.LimitError.
+func renderLimitError(w http.ResponseWriter, r *http.Request, err error) bool {
+	var limitErr *urlhandler.LimitError
+	if !errors.As(err, &limitErr) {
+		return false
+	}
+
+	resp := models.ErrorResponse{Message: err.Error(), Limit: limitErr.Limit}
+	status := http.StatusUnprocessableEntity
+	switch {
+	case errors.Is(err, urlhandler.ErrBatchTooLarge):
+		status, resp.Code = http.StatusRequestEntityTooLarge, codeBatchTooLarge
+	case errors.Is(err, urlhandler.ErrLinkQuotaExceeded):
+		status, resp.Code = http.StatusForbidden, codeQuotaExceeded
+	default:
+		resp.Code = codeURLTooLong
+	}
+
+	renderError(w, r, status, resp)
+	return true
+}
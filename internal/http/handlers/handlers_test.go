@@ -18,6 +18,8 @@ import (
 
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/handlers/mocks"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/auth"
+	urlhandler "github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler"
 )
 
 func TestSaveHandler(t *testing.T) {
@@ -54,13 +56,14 @@ func TestSaveHandler(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 			urlHndl := mocks.NewURLHandler(t)
-			urlHndl.On("SaveURL", mock.AnythingOfType("*context.timerCtx"), tc.url, "").
+			urlHndl.On("SaveURL", mock.AnythingOfType("*context.timerCtx"), models.URLRequest{URL: tc.url}, "").
 				Return(tc.alias, tc.err)
 
 			h := NewHandlers(
 				zaptest.NewLogger(t),
 				urlHndl,
 				"http://localhost:8080",
+				nil,
 			)
 
 			rr := httptest.NewRecorder()
@@ -105,6 +108,20 @@ func TestRedirectHandler(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			isCallMock:     true,
 		},
+		{
+			name:           "url has expired: 410",
+			alias:          "alias1",
+			err:            urlhandler.ErrURLExpired,
+			expectedStatus: http.StatusGone,
+			isCallMock:     true,
+		},
+		{
+			name:           "url is blocked: 403",
+			alias:          "alias1",
+			err:            urlhandler.ErrDestinationBlocked,
+			expectedStatus: http.StatusForbidden,
+			isCallMock:     true,
+		},
 		{
 			name:           "alias is empty: 404",
 			err:            errors.New("alias is empty"),
@@ -122,11 +139,19 @@ func TestRedirectHandler(t *testing.T) {
 				urlHndl.On("ReadURL", mock.AnythingOfType("*context.timerCtx"), tc.alias).
 					Return(tc.expectedLocation, tc.err)
 			}
+			if tc.err == nil && tc.isCallMock {
+				urlHndl.On("RecordClick", mock.AnythingOfType("*context.timerCtx"),
+					mock.MatchedBy(func(click models.Click) bool {
+						return click.ShortURL == tc.alias && click.IP == "127.0.0.1"
+					})).
+					Return()
+			}
 
 			h := NewHandlers(
 				zaptest.NewLogger(t),
 				urlHndl,
 				"http://localhost:8080",
+				nil,
 			)
 
 			r := chi.NewRouter()
@@ -167,6 +192,7 @@ func TestSaveJSONHandler(t *testing.T) {
 		alias          string
 		err            error
 		expectedStatus int
+		expectedCode   string
 	}{
 		{
 			name: "url ok",
@@ -186,8 +212,65 @@ func TestSaveJSONHandler(t *testing.T) {
 			url: models.URLRequest{
 				URL: "ya.ru/",
 			},
-			err:            errors.New("invalid url"),
+			err:            fmt.Errorf("%w: host is empty", urlhandler.ErrInvalidURL),
 			expectedStatus: http.StatusBadRequest,
+			expectedCode:   codeInvalidURL,
+		},
+		{
+			name: "custom alias",
+			url: models.URLRequest{
+				URL:   "https://ya.ru/",
+				Alias: "spring-sale",
+			},
+			alias:          "spring-sale",
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "custom alias is taken",
+			url: models.URLRequest{
+				URL:   "https://ya.ru/",
+				Alias: "spring-sale",
+			},
+			err:            fmt.Errorf("%w: spring-sale", urlhandler.ErrAliasTaken),
+			expectedStatus: http.StatusConflict,
+			expectedCode:   codeAliasTaken,
+		},
+		{
+			name: "invalid alias",
+			url: models.URLRequest{
+				URL:   "https://ya.ru/",
+				Alias: "api",
+			},
+			err:            fmt.Errorf("%w: \"api\" is reserved", urlhandler.ErrInvalidAlias),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   codeInvalidAlias,
+		},
+		{
+			name: "url is too long",
+			url: models.URLRequest{
+				URL: "https://ya.ru/" + strings.Repeat("a", 64),
+			},
+			err:            &urlhandler.LimitError{Err: urlhandler.ErrURLTooLong, Limit: 64, Value: 78},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   codeURLTooLong,
+		},
+		{
+			name: "link quota exceeded",
+			url: models.URLRequest{
+				URL: "https://ya.ru/",
+			},
+			err:            &urlhandler.LimitError{Err: urlhandler.ErrLinkQuotaExceeded, Limit: 10, Value: 11},
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   codeQuotaExceeded,
+		},
+		{
+			name: "destination is blocked",
+			url: models.URLRequest{
+				URL: "https://phish.io/",
+			},
+			err:            fmt.Errorf("%w: https://phish.io/", urlhandler.ErrDestinationBlocked),
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   codeBlocked,
 		},
 	}
 
@@ -196,13 +279,14 @@ func TestSaveJSONHandler(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 			urlHndl := mocks.NewURLHandler(t)
-			urlHndl.On("SaveURL", mock.AnythingOfType("*context.timerCtx"), tc.url.URL, "").
+			urlHndl.On("SaveURL", mock.AnythingOfType("*context.timerCtx"), tc.url, "").
 				Return(tc.alias, tc.err)
 
 			h := NewHandlers(
 				zaptest.NewLogger(t),
 				urlHndl,
 				"http://localhost:8080",
+				nil,
 			)
 
 			rr := httptest.NewRecorder()
@@ -223,6 +307,13 @@ func TestSaveJSONHandler(t *testing.T) {
 			defer result.Body.Close()
 			assert.Equal(t, tc.expectedStatus, result.StatusCode)
 
+			if len(tc.expectedCode) > 0 {
+				errResp := models.ErrorResponse{}
+				require.NoError(t, json.NewDecoder(result.Body).Decode(&errResp))
+				assert.Equal(t, tc.expectedCode, errResp.Code)
+				assert.Equal(t, tc.url.Alias, errResp.Alias)
+			}
+
 		})
 	}
 
@@ -264,6 +355,7 @@ func TestPingHandler(t *testing.T) {
 				zaptest.NewLogger(t),
 				urlHndl,
 				"http://localhost:8080",
+				nil,
 			)
 
 			rr := httptest.NewRecorder()
@@ -294,6 +386,7 @@ func TestBatchHandler(t *testing.T) {
 		expectedURLS   []models.BatchResponse
 		err            error
 		expectedStatus int
+		expectedCode   string
 		isError        bool
 	}{
 		{
@@ -312,14 +405,42 @@ func TestBatchHandler(t *testing.T) {
 				{
 					CorrelationID: "1",
 					ShortURL:      "dkh2ksukde",
+					Status:        models.BatchCreated,
 				},
 				{
 					CorrelationID: "2",
 					ShortURL:      "fh43jfhfdq",
+					Status:        models.BatchCreated,
 				},
 			},
 			expectedStatus: http.StatusCreated,
 		},
+		{
+			name: "partially saved",
+			urls: []models.BatchRequest{
+				{
+					CorrelationID: "1",
+					OriginalURL:   "https://practicum.yandex.ru/",
+				},
+				{
+					CorrelationID: "2",
+					OriginalURL:   "javascript:alert(1)",
+				},
+			},
+			expectedURLS: []models.BatchResponse{
+				{
+					CorrelationID: "1",
+					ShortURL:      "dkh2ksukde",
+					Status:        models.BatchExisting,
+				},
+				{
+					CorrelationID: "2",
+					Status:        models.BatchInvalid,
+					Reason:        "invalid url: scheme \"javascript\" is not allowed",
+				},
+			},
+			expectedStatus: http.StatusMultiStatus,
+		},
 		{
 			name:           "no data to save",
 			urls:           []models.BatchRequest{},
@@ -328,6 +449,16 @@ func TestBatchHandler(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			isError:        true,
 		},
+		{
+			name: "batch is too large",
+			urls: []models.BatchRequest{
+				{CorrelationID: "1", OriginalURL: "https://practicum.yandex.ru/"},
+				{CorrelationID: "2", OriginalURL: "https://ya.ru/"},
+			},
+			err:            &urlhandler.LimitError{Err: urlhandler.ErrBatchTooLarge, Limit: 1, Value: 2},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedCode:   codeBatchTooLarge,
+		},
 	}
 
 	for _, tc := range cases {
@@ -345,6 +476,7 @@ func TestBatchHandler(t *testing.T) {
 				zaptest.NewLogger(t),
 				urlHndl,
 				"http://localhost:8080",
+				nil,
 			)
 
 			rr := httptest.NewRecorder()
@@ -365,6 +497,14 @@ func TestBatchHandler(t *testing.T) {
 			defer result.Body.Close()
 			assert.Equal(t, tc.expectedStatus, result.StatusCode)
 
+			if len(tc.expectedCode) > 0 {
+				errResp := models.ErrorResponse{}
+				require.NoError(t, json.NewDecoder(result.Body).Decode(&errResp))
+				assert.Equal(t, tc.expectedCode, errResp.Code)
+				assert.Equal(t, 1, errResp.Limit)
+				return
+			}
+
 			if !tc.isError && result.ContentLength > 0 {
 				respURLS := []models.BatchResponse{}
 				err = json.NewDecoder(result.Body).Decode(&respURLS)
@@ -377,3 +517,85 @@ func TestBatchHandler(t *testing.T) {
 	}
 
 }
+
+func TestURLStatsHandler(t *testing.T) {
+	cases := []struct {
+		name           string
+		userID         string
+		stats          models.URLStats
+		err            error
+		expectedStatus int
+		isCallMock     bool
+	}{
+		{
+			name:   "stats for owner",
+			userID: "user1",
+			stats: models.URLStats{
+				ShortURL: "alias1",
+				Total:    2,
+				Hourly:   []models.StatsBucket{},
+				Daily:    []models.StatsBucket{},
+			},
+			expectedStatus: http.StatusOK,
+			isCallMock:     true,
+		},
+		{
+			name:           "not an owner",
+			userID:         "user2",
+			err:            urlhandler.ErrNotOwner,
+			expectedStatus: http.StatusForbidden,
+			isCallMock:     true,
+		},
+		{
+			name:           "url not found",
+			userID:         "user1",
+			err:            urlhandler.ErrNotFound,
+			expectedStatus: http.StatusNotFound,
+			isCallMock:     true,
+		},
+		{
+			name:           "unauthorized",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlHndl := mocks.NewURLHandler(t)
+			if tc.isCallMock {
+				urlHndl.On("GetStats", mock.AnythingOfType("*context.timerCtx"), "alias1", tc.userID).
+					Return(tc.stats, tc.err)
+			}
+
+			h := NewHandlers(
+				zaptest.NewLogger(t),
+				urlHndl,
+				"http://localhost:8080",
+				nil,
+			)
+
+			r := chi.NewRouter()
+			r.Get("/api/user/urls/{id}/stats", h.URLStatsHandler)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/user/urls/alias1/stats", nil)
+			req = req.WithContext(auth.ContextWithUserID(req.Context(), tc.userID))
+			rr := httptest.NewRecorder()
+
+			r.ServeHTTP(rr, req)
+
+			result := rr.Result()
+			defer result.Body.Close()
+			assert.Equal(t, tc.expectedStatus, result.StatusCode)
+
+			if tc.expectedStatus == http.StatusOK {
+				stats := models.URLStats{}
+				require.NoError(t, json.NewDecoder(result.Body).Decode(&stats))
+				assert.Equal(t, "http://localhost:8080/alias1", stats.ShortURL)
+				assert.Equal(t, tc.stats.Total, stats.Total)
+			}
+		})
+	}
+}
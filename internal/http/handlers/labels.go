@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/auth"
+)
+
+// ListTagsHandler возвращает теги URL пользователя с количеством URL,
+// по убыванию количества.
+func (h *Handlers) ListTagsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromContext(r.Context())
+
+	if len(userID) == 0 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*4)
+	defer cancel()
+
+	tags, err := h.urlHandler.ListTags(ctx, userID)
+	if err != nil {
+		h.log.Error(
+			"failed to read tags",
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if tags == nil {
+		tags = []models.TagCount{}
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, tags)
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/handlers/mocks"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/auth"
+	urlhandler "github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler"
+)
+
+func TestListTagsHandler(t *testing.T) {
+	const userID = "a6b8b5a4-7d2f-4c36-9cf1-5b9a0c0e3d11"
+
+	cases := []struct {
+		name           string
+		userID         string
+		tags           []models.TagCount
+		err            error
+		expectedStatus int
+		expectedBody   string
+		isCallMock     bool
+	}{
+		{
+			name:   "tags with counts",
+			userID: userID,
+			tags: []models.TagCount{
+				{Tag: "go", Count: 2},
+				{Tag: "news", Count: 1},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[{"tag":"go","count":2},{"tag":"news","count":1}]`,
+			isCallMock:     true,
+		},
+		{
+			name:           "no tags",
+			userID:         userID,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `[]`,
+			isCallMock:     true,
+		},
+		{
+			name:           "no user",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "storage error",
+			userID:         userID,
+			err:            errors.New("connection refused"),
+			expectedStatus: http.StatusInternalServerError,
+			isCallMock:     true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlHndl := mocks.NewURLHandler(t)
+			if tc.isCallMock {
+				urlHndl.On("ListTags", mock.AnythingOfType("*context.timerCtx"), tc.userID).
+					Return(tc.tags, tc.err)
+			}
+
+			h := NewHandlers(zaptest.NewLogger(t), urlHndl, "http://localhost:8080", nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/user/tags", nil)
+			req = req.WithContext(auth.ContextWithUserID(context.Background(), tc.userID))
+			rr := httptest.NewRecorder()
+
+			h.ListTagsHandler(rr, req)
+
+			result := rr.Result()
+			defer result.Body.Close()
+			assert.Equal(t, tc.expectedStatus, result.StatusCode)
+
+			if len(tc.expectedBody) > 0 {
+				assert.JSONEq(t, tc.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestSaveHandlerLabels(t *testing.T) {
+	urlHndl := mocks.NewURLHandler(t)
+	urlHndl.On("SaveURL", mock.AnythingOfType("*context.timerCtx"), mock.MatchedBy(func(req models.URLRequest) bool {
+		return assert.ObjectsAreEqual([]string{"go", "news"}, req.Tags) && req.Folder == "work"
+	}), "").Return("promo", nil).Once()
+	urlHndl.On("SaveURL", mock.AnythingOfType("*context.timerCtx"), mock.MatchedBy(func(req models.URLRequest) bool {
+		return len(req.Folder) > 0 && req.Folder != "work"
+	}), "").Return("", urlhandler.ErrInvalidLabels).Once()
+
+	h := NewHandlers(zaptest.NewLogger(t), urlHndl, "http://localhost:8080", nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/?tag=go&tag=news&folder=work", strings.NewReader("https://ya.ru/"))
+	rr := httptest.NewRecorder()
+	h.SaveHandler(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "http://localhost:8080/promo", rr.Body.String())
+
+	req = httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"https://ya.ru/","folder":"bad"}`))
+	rr = httptest.NewRecorder()
+	h.SaveJSONHandler(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"code":"invalid_labels"`)
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/handlers/mocks"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/auth"
+	urlhandler "github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler"
+)
+
+func TestUserUrlsHandler(t *testing.T) {
+	const userID = "a6b8b5a4-7d2f-4c36-9cf1-5b9a0c0e3d11"
+
+	cases := []struct {
+		name           string
+		query          string
+		req            models.URLListRequest
+		resp           models.URLListResponse
+		err            error
+		expectedStatus int
+		expectedLink   string
+		isPaged        bool
+		isCallMock     bool
+	}{
+		{
+			name:           "whole list",
+			resp:           models.URLListResponse{URLs: []models.MassURL{{ShortURL: "promo", OriginalURL: "https://ya.ru/"}}},
+			expectedStatus: http.StatusOK,
+			isCallMock:     true,
+		},
+		{
+			name:           "include deleted",
+			query:          "?include_deleted=true",
+			req:            models.URLListRequest{Deleted: models.DeletedInclude},
+			resp:           models.URLListResponse{URLs: []models.MassURL{{ShortURL: "promo", OriginalURL: "https://ya.ru/"}}},
+			expectedStatus: http.StatusOK,
+			isCallMock:     true,
+		},
+		{
+			name:  "first page",
+			query: "?limit=1&sort=created_at&contains=ya",
+			req: models.URLListRequest{
+				Limit:    1,
+				Sort:     models.SortCreatedAsc,
+				Contains: "ya",
+			},
+			resp: models.URLListResponse{
+				URLs:       []models.MassURL{{ShortURL: "promo", OriginalURL: "https://ya.ru/"}},
+				NextCursor: "next",
+			},
+			expectedStatus: http.StatusOK,
+			expectedLink:   `<http://localhost:8080/api/user/urls?contains=ya&cursor=next&limit=1&sort=created_at>; rel="next"`,
+			isPaged:        true,
+			isCallMock:     true,
+		},
+		{
+			name:           "last page",
+			query:          "?limit=1&cursor=next&deleted=only",
+			req:            models.URLListRequest{Limit: 1, Cursor: "next", Deleted: models.DeletedOnly},
+			resp:           models.URLListResponse{URLs: []models.MassURL{}},
+			expectedStatus: http.StatusOK,
+			isPaged:        true,
+			isCallMock:     true,
+		},
+		{
+			name:           "invalid limit",
+			query:          "?limit=0",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid include_deleted",
+			query:          "?include_deleted=maybe",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid cursor",
+			query:          "?cursor=broken",
+			req:            models.URLListRequest{Cursor: "broken"},
+			err:            fmt.Errorf("%w: malformed cursor", urlhandler.ErrInvalidQuery),
+			expectedStatus: http.StatusBadRequest,
+			isCallMock:     true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlHndl := mocks.NewURLHandler(t)
+			if tc.isCallMock {
+				urlHndl.On("GetURLS", mock.AnythingOfType("*context.timerCtx"), userID, tc.req).
+					Return(tc.resp, tc.err)
+			}
+
+			h := NewHandlers(zaptest.NewLogger(t), urlHndl, "http://localhost:8080", nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/user/urls"+tc.query, nil)
+			req = req.WithContext(auth.ContextWithUserID(context.Background(), userID))
+			rr := httptest.NewRecorder()
+
+			h.UserUrlsHandler(rr, req)
+
+			result := rr.Result()
+			defer result.Body.Close()
+			assert.Equal(t, tc.expectedStatus, result.StatusCode)
+			assert.Equal(t, tc.expectedLink, result.Header.Get("Link"))
+
+			switch {
+			case tc.expectedStatus != http.StatusOK:
+				errResp := models.ErrorResponse{}
+				require.NoError(t, json.NewDecoder(result.Body).Decode(&errResp))
+				assert.Equal(t, codeInvalidQuery, errResp.Code)
+			case tc.isPaged:
+				list := models.URLListResponse{}
+				require.NoError(t, json.NewDecoder(result.Body).Decode(&list))
+				assert.Len(t, list.URLs, len(tc.resp.URLs))
+				assert.Equal(t, tc.resp.NextCursor, list.NextCursor)
+			default:
+				urls := []models.MassURL{}
+				require.NoError(t, json.NewDecoder(result.Body).Decode(&urls))
+				require.Len(t, urls, len(tc.resp.URLs))
+				assert.Equal(t, "http://localhost:8080/promo", urls[0].ShortURL)
+			}
+		})
+	}
+}
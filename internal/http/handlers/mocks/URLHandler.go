@@ -6,7 +6,6 @@ import (
 	context "context"
 
 	mock "github.com/stretchr/testify/mock"
-
 	models "github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
 )
 
@@ -15,25 +14,147 @@ type URLHandler struct {
 	mock.Mock
 }
 
+// BlockURLS provides a mock function with given fields: ctx, shortURLS, blocked
+func (_m *URLHandler) BlockURLS(ctx context.Context, shortURLS []string, blocked bool) (int64, error) {
+	ret := _m.Called(ctx, shortURLS, blocked)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string, bool) (int64, error)); ok {
+		return rf(ctx, shortURLS, blocked)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string, bool) int64); ok {
+		r0 = rf(ctx, shortURLS, blocked)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string, bool) error); ok {
+		r1 = rf(ctx, shortURLS, blocked)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateAPIKey provides a mock function with given fields: ctx, userID, req
+func (_m *URLHandler) CreateAPIKey(ctx context.Context, userID string, req models.APIKeyRequest) (models.CreatedAPIKey, error) {
+	ret := _m.Called(ctx, userID, req)
+
+	var r0 models.CreatedAPIKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.APIKeyRequest) (models.CreatedAPIKey, error)); ok {
+		return rf(ctx, userID, req)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.APIKeyRequest) models.CreatedAPIKey); ok {
+		r0 = rf(ctx, userID, req)
+	} else {
+		r0 = ret.Get(0).(models.CreatedAPIKey)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, models.APIKeyRequest) error); ok {
+		r1 = rf(ctx, userID, req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DeleteURLS provides a mock function with given fields: ctx, shortURLS, userID
 func (_m *URLHandler) DeleteURLS(ctx context.Context, shortURLS []string, userID string) {
 	_m.Called(ctx, shortURLS, userID)
 }
 
-// GetURLS provides a mock function with given fields: ctx, userID
-func (_m *URLHandler) GetURLS(ctx context.Context, userID string) ([]models.MassURL, error) {
+// GetStats provides a mock function with given fields: ctx, id, userID
+func (_m *URLHandler) GetStats(ctx context.Context, id string, userID string) (models.URLStats, error) {
+	ret := _m.Called(ctx, id, userID)
+
+	var r0 models.URLStats
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (models.URLStats, error)); ok {
+		return rf(ctx, id, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) models.URLStats); ok {
+		r0 = rf(ctx, id, userID)
+	} else {
+		r0 = ret.Get(0).(models.URLStats)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, id, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetURLHistory provides a mock function with given fields: ctx, userID, id
+func (_m *URLHandler) GetURLHistory(ctx context.Context, userID string, id string) ([]models.URLRevision, error) {
+	ret := _m.Called(ctx, userID, id)
+
+	var r0 []models.URLRevision
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]models.URLRevision, error)); ok {
+		return rf(ctx, userID, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []models.URLRevision); ok {
+		r0 = rf(ctx, userID, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.URLRevision)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, userID, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetURLS provides a mock function with given fields: ctx, userID, req
+func (_m *URLHandler) GetURLS(ctx context.Context, userID string, req models.URLListRequest) (models.URLListResponse, error) {
+	ret := _m.Called(ctx, userID, req)
+
+	var r0 models.URLListResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.URLListRequest) (models.URLListResponse, error)); ok {
+		return rf(ctx, userID, req)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.URLListRequest) models.URLListResponse); ok {
+		r0 = rf(ctx, userID, req)
+	} else {
+		r0 = ret.Get(0).(models.URLListResponse)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, models.URLListRequest) error); ok {
+		r1 = rf(ctx, userID, req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListAPIKeys provides a mock function with given fields: ctx, userID
+func (_m *URLHandler) ListAPIKeys(ctx context.Context, userID string) ([]models.APIKeyInfo, error) {
 	ret := _m.Called(ctx, userID)
 
-	var r0 []models.MassURL
+	var r0 []models.APIKeyInfo
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) ([]models.MassURL, error)); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]models.APIKeyInfo, error)); ok {
 		return rf(ctx, userID)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) []models.MassURL); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, string) []models.APIKeyInfo); ok {
 		r0 = rf(ctx, userID)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]models.MassURL)
+			r0 = ret.Get(0).([]models.APIKeyInfo)
 		}
 	}
 
@@ -46,6 +167,56 @@ func (_m *URLHandler) GetURLS(ctx context.Context, userID string) ([]models.Mass
 	return r0, r1
 }
 
+// ListTags provides a mock function with given fields: ctx, userID
+func (_m *URLHandler) ListTags(ctx context.Context, userID string) ([]models.TagCount, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []models.TagCount
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]models.TagCount, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []models.TagCount); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.TagCount)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Login provides a mock function with given fields: ctx, creds, anonUserID
+func (_m *URLHandler) Login(ctx context.Context, creds models.Credentials, anonUserID string) (models.AuthResponse, error) {
+	ret := _m.Called(ctx, creds, anonUserID)
+
+	var r0 models.AuthResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Credentials, string) (models.AuthResponse, error)); ok {
+		return rf(ctx, creds, anonUserID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.Credentials, string) models.AuthResponse); ok {
+		r0 = rf(ctx, creds, anonUserID)
+	} else {
+		r0 = ret.Get(0).(models.AuthResponse)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.Credentials, string) error); ok {
+		r1 = rf(ctx, creds, anonUserID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Ping provides a mock function with given fields: ctx
 func (_m *URLHandler) Ping(ctx context.Context) error {
 	ret := _m.Called(ctx)
@@ -84,23 +255,92 @@ func (_m *URLHandler) ReadURL(ctx context.Context, alias string) (string, error)
 	return r0, r1
 }
 
-// SaveURL provides a mock function with given fields: ctx, url, userID
-func (_m *URLHandler) SaveURL(ctx context.Context, url string, userID string) (string, error) {
-	ret := _m.Called(ctx, url, userID)
+// RecordClick provides a mock function with given fields: ctx, click
+func (_m *URLHandler) RecordClick(ctx context.Context, click models.Click) {
+	_m.Called(ctx, click)
+}
+
+// Register provides a mock function with given fields: ctx, creds, anonUserID
+func (_m *URLHandler) Register(ctx context.Context, creds models.Credentials, anonUserID string) (models.AuthResponse, error) {
+	ret := _m.Called(ctx, creds, anonUserID)
+
+	var r0 models.AuthResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Credentials, string) (models.AuthResponse, error)); ok {
+		return rf(ctx, creds, anonUserID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.Credentials, string) models.AuthResponse); ok {
+		r0 = rf(ctx, creds, anonUserID)
+	} else {
+		r0 = ret.Get(0).(models.AuthResponse)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.Credentials, string) error); ok {
+		r1 = rf(ctx, creds, anonUserID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RestoreURLS provides a mock function with given fields: ctx, shortURLS, userID
+func (_m *URLHandler) RestoreURLS(ctx context.Context, shortURLS []string, userID string) ([]string, error) {
+	ret := _m.Called(ctx, shortURLS, userID)
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string, string) ([]string, error)); ok {
+		return rf(ctx, shortURLS, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string, string) []string); ok {
+		r0 = rf(ctx, shortURLS, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string, string) error); ok {
+		r1 = rf(ctx, shortURLS, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RevokeAPIKey provides a mock function with given fields: ctx, userID, id
+func (_m *URLHandler) RevokeAPIKey(ctx context.Context, userID string, id string) error {
+	ret := _m.Called(ctx, userID, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, userID, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveURL provides a mock function with given fields: ctx, req, userID
+func (_m *URLHandler) SaveURL(ctx context.Context, req models.URLRequest, userID string) (string, error) {
+	ret := _m.Called(ctx, req, userID)
 
 	var r0 string
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) (string, error)); ok {
-		return rf(ctx, url, userID)
+	if rf, ok := ret.Get(0).(func(context.Context, models.URLRequest, string) (string, error)); ok {
+		return rf(ctx, req, userID)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
-		r0 = rf(ctx, url, userID)
+	if rf, ok := ret.Get(0).(func(context.Context, models.URLRequest, string) string); ok {
+		r0 = rf(ctx, req, userID)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
-		r1 = rf(ctx, url, userID)
+	if rf, ok := ret.Get(1).(func(context.Context, models.URLRequest, string) error); ok {
+		r1 = rf(ctx, req, userID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -134,6 +374,54 @@ func (_m *URLHandler) SaveURLS(ctx context.Context, urls []models.BatchRequest,
 	return r0, r1
 }
 
+// ServiceStats provides a mock function with given fields: ctx
+func (_m *URLHandler) ServiceStats(ctx context.Context) (models.ServiceStats, error) {
+	ret := _m.Called(ctx)
+
+	var r0 models.ServiceStats
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (models.ServiceStats, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) models.ServiceStats); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(models.ServiceStats)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateURL provides a mock function with given fields: ctx, userID, id, req
+func (_m *URLHandler) UpdateURL(ctx context.Context, userID string, id string, req models.UpdateURLRequest) (models.MassURL, error) {
+	ret := _m.Called(ctx, userID, id, req)
+
+	var r0 models.MassURL
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, models.UpdateURLRequest) (models.MassURL, error)); ok {
+		return rf(ctx, userID, id, req)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, models.UpdateURLRequest) models.MassURL); ok {
+		r0 = rf(ctx, userID, id, req)
+	} else {
+		r0 = ret.Get(0).(models.MassURL)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, models.UpdateURLRequest) error); ok {
+		r1 = rf(ctx, userID, id, req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewURLHandler creates a new instance of URLHandler. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewURLHandler(t interface {
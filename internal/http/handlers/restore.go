@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/auth"
+)
+
+// RestoreURLSHandler восстанавливает удаленные URL пользователя из списка.
+// В ответе только восстановленные URL: чужие, неудаленные
+// и URL с истекшим сроком восстановления пропускаются.
+func (h *Handlers) RestoreURLSHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	userID := auth.UserIDFromContext(r.Context())
+
+	if len(userID) == 0 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var shortURLS []string
+	if err := json.NewDecoder(r.Body).Decode(&shortURLS); err != nil {
+		h.log.Error(
+			"failed to read JSON request body",
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*4)
+	defer cancel()
+
+	restored, err := h.urlHandler.RestoreURLS(ctx, shortURLS, userID)
+	if err != nil {
+		if renderLimitError(w, r, err) {
+			return
+		}
+		h.log.Error(
+			"failed to restore urls",
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, models.RestoreResponse{Restored: restored})
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/handlers/mocks"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/auth"
+	urlhandler "github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler"
+)
+
+func TestRestoreURLSHandler(t *testing.T) {
+	const userID = "a6b8b5a4-7d2f-4c36-9cf1-5b9a0c0e3d11"
+
+	cases := []struct {
+		name           string
+		userID         string
+		body           string
+		restored       []string
+		err            error
+		expectedStatus int
+		isCallMock     bool
+	}{
+		{
+			name:           "urls restored",
+			userID:         userID,
+			body:           `["promo","sale"]`,
+			restored:       []string{"promo"},
+			expectedStatus: http.StatusOK,
+			isCallMock:     true,
+		},
+		{
+			name:           "no user",
+			body:           `["promo"]`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "invalid body",
+			userID:         userID,
+			body:           `{"urls": "promo"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "link quota exceeded",
+			userID: userID,
+			body:   `["promo"]`,
+			err: &urlhandler.LimitError{
+				Err:   urlhandler.ErrLinkQuotaExceeded,
+				Limit: 10,
+				Value: 11,
+			},
+			expectedStatus: http.StatusForbidden,
+			isCallMock:     true,
+		},
+		{
+			name:           "storage error",
+			userID:         userID,
+			body:           `["promo"]`,
+			err:            errors.New("connection refused"),
+			expectedStatus: http.StatusInternalServerError,
+			isCallMock:     true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlHndl := mocks.NewURLHandler(t)
+			if tc.isCallMock {
+				var ids []string
+				require.NoError(t, json.Unmarshal([]byte(tc.body), &ids))
+				urlHndl.On("RestoreURLS", mock.AnythingOfType("*context.timerCtx"), ids, tc.userID).
+					Return(tc.restored, tc.err)
+			}
+
+			h := NewHandlers(zaptest.NewLogger(t), urlHndl, "http://localhost:8080", nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/user/urls/restore", strings.NewReader(tc.body))
+			req = req.WithContext(auth.ContextWithUserID(context.Background(), tc.userID))
+			rr := httptest.NewRecorder()
+
+			h.RestoreURLSHandler(rr, req)
+
+			result := rr.Result()
+			defer result.Body.Close()
+			assert.Equal(t, tc.expectedStatus, result.StatusCode)
+
+			if tc.expectedStatus == http.StatusOK {
+				resp := models.RestoreResponse{}
+				require.NoError(t, json.NewDecoder(result.Body).Decode(&resp))
+				assert.Equal(t, tc.restored, resp.Restored)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/auth"
+	urlhandler "github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler"
+)
+
+// UpdateURLHandler изменяет оригинальный URL пользователя
+// или возвращает его к прежней ревизии, а также теги и папку URL.
+func (h *Handlers) UpdateURLHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	userID := auth.UserIDFromContext(r.Context())
+	if len(userID) == 0 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	req := models.UpdateURLRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error(
+			"failed to read JSON request body",
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	alias := chi.URLParam(r, "id")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*4)
+	defer cancel()
+
+	url, err := h.urlHandler.UpdateURL(ctx, userID, alias, req)
+	if err != nil {
+		if renderLimitError(w, r, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, urlhandler.ErrInvalidURL):
+			renderError(w, r, http.StatusBadRequest, models.ErrorResponse{
+				Code:    codeInvalidURL,
+				Message: err.Error(),
+			})
+		case errors.Is(err, urlhandler.ErrInvalidRevision):
+			renderError(w, r, http.StatusBadRequest, models.ErrorResponse{
+				Code:    codeInvalidRev,
+				Message: err.Error(),
+			})
+		case errors.Is(err, urlhandler.ErrInvalidLabels):
+			renderError(w, r, http.StatusBadRequest, models.ErrorResponse{
+				Code:    codeInvalidLabels,
+				Message: err.Error(),
+			})
+		case errors.Is(err, urlhandler.ErrDestinationBlocked):
+			renderError(w, r, http.StatusForbidden, models.ErrorResponse{
+				Code:    codeBlocked,
+				Message: err.Error(),
+			})
+		case errors.Is(err, urlhandler.ErrAlreadyExists):
+			renderError(w, r, http.StatusConflict, models.ErrorResponse{
+				Code:    codeURLExists,
+				Message: err.Error(),
+			})
+		default:
+			h.renderOwnerError(w, "failed to update url", alias, err)
+		}
+		return
+	}
+
+	url.ShortURL = fmt.Sprintf("%s/%s", h.redirectHost, url.ShortURL)
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, url)
+}
+
+// URLHistoryHandler возвращает прежние оригинальные URL, начиная с последнего.
+func (h *Handlers) URLHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	userID := auth.UserIDFromContext(r.Context())
+	if len(userID) == 0 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	alias := chi.URLParam(r, "id")
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*4)
+	defer cancel()
+
+	history, err := h.urlHandler.GetURLHistory(ctx, userID, alias)
+	if err != nil {
+		h.renderOwnerError(w, "failed to read url history", alias, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, history)
+}
+
+// renderOwnerError статус ответа для ошибок доступа к URL пользователя.
+func (h *Handlers) renderOwnerError(w http.ResponseWriter, msg string, alias string, err error) {
+	switch {
+	case errors.Is(err, urlhandler.ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, urlhandler.ErrNotOwner):
+		w.WriteHeader(http.StatusForbidden)
+	case errors.Is(err, urlhandler.ErrURLRemoved):
+		w.WriteHeader(http.StatusGone)
+	default:
+		h.log.Error(
+			msg,
+			zap.String("alias", alias),
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/handlers/mocks"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/auth"
+	urlhandler "github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler"
+)
+
+func TestUpdateURLHandler(t *testing.T) {
+	const userID = "a6b8b5a4-7d2f-4c36-9cf1-5b9a0c0e3d11"
+
+	cases := []struct {
+		name           string
+		userID         string
+		body           string
+		req            models.UpdateURLRequest
+		err            error
+		expectedStatus int
+		expectedCode   string
+		isCallMock     bool
+	}{
+		{
+			name:           "url updated",
+			userID:         userID,
+			body:           `{"original_url": "https://go.dev/"}`,
+			req:            models.UpdateURLRequest{OriginalURL: "https://go.dev/"},
+			expectedStatus: http.StatusOK,
+			isCallMock:     true,
+		},
+		{
+			name:           "no user",
+			body:           `{"original_url": "https://go.dev/"}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "invalid body",
+			userID:         userID,
+			body:           `{"original_url": 1}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown revision",
+			userID:         userID,
+			body:           `{"revision": 5}`,
+			req:            models.UpdateURLRequest{Revision: 5},
+			err:            urlhandler.ErrInvalidRevision,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   codeInvalidRev,
+			isCallMock:     true,
+		},
+		{
+			name:           "url is already shortened",
+			userID:         userID,
+			body:           `{"original_url": "https://go.dev/"}`,
+			req:            models.UpdateURLRequest{OriginalURL: "https://go.dev/"},
+			err:            urlhandler.ErrAlreadyExists,
+			expectedStatus: http.StatusConflict,
+			expectedCode:   codeURLExists,
+			isCallMock:     true,
+		},
+		{
+			name:           "url belongs to another user",
+			userID:         userID,
+			body:           `{"original_url": "https://go.dev/"}`,
+			req:            models.UpdateURLRequest{OriginalURL: "https://go.dev/"},
+			err:            urlhandler.ErrNotOwner,
+			expectedStatus: http.StatusForbidden,
+			isCallMock:     true,
+		},
+		{
+			name:           "url is deleted",
+			userID:         userID,
+			body:           `{"original_url": "https://go.dev/"}`,
+			req:            models.UpdateURLRequest{OriginalURL: "https://go.dev/"},
+			err:            urlhandler.ErrURLRemoved,
+			expectedStatus: http.StatusGone,
+			isCallMock:     true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlHndl := mocks.NewURLHandler(t)
+			if tc.isCallMock {
+				urlHndl.On("UpdateURL", mock.AnythingOfType("*context.timerCtx"), tc.userID, "promo", tc.req).
+					Return(models.MassURL{ShortURL: "promo", OriginalURL: tc.req.OriginalURL}, tc.err)
+			}
+
+			h := NewHandlers(zaptest.NewLogger(t), urlHndl, "http://localhost:8080", nil)
+
+			r := chi.NewRouter()
+			r.Patch("/api/user/urls/{id}", h.UpdateURLHandler)
+
+			req := httptest.NewRequest(http.MethodPatch, "/api/user/urls/promo", strings.NewReader(tc.body))
+			req = req.WithContext(auth.ContextWithUserID(context.Background(), tc.userID))
+			rr := httptest.NewRecorder()
+
+			r.ServeHTTP(rr, req)
+
+			result := rr.Result()
+			defer result.Body.Close()
+			assert.Equal(t, tc.expectedStatus, result.StatusCode)
+
+			switch {
+			case len(tc.expectedCode) > 0:
+				errResp := models.ErrorResponse{}
+				require.NoError(t, json.NewDecoder(result.Body).Decode(&errResp))
+				assert.Equal(t, tc.expectedCode, errResp.Code)
+			case tc.expectedStatus == http.StatusOK:
+				url := models.MassURL{}
+				require.NoError(t, json.NewDecoder(result.Body).Decode(&url))
+				assert.Equal(t, "http://localhost:8080/promo", url.ShortURL)
+			}
+		})
+	}
+}
+
+func TestURLHistoryHandler(t *testing.T) {
+	const userID = "a6b8b5a4-7d2f-4c36-9cf1-5b9a0c0e3d11"
+
+	history := []models.URLRevision{
+		{Revision: 1, OriginalURL: "https://ya.ru/", ReplacedAt: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)},
+	}
+
+	cases := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{
+			name:           "history",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "url is not found",
+			err:            urlhandler.ErrNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "storage error",
+			err:            errors.New("connection refused"),
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlHndl := mocks.NewURLHandler(t)
+			urlHndl.On("GetURLHistory", mock.AnythingOfType("*context.timerCtx"), userID, "promo").
+				Return(history, tc.err)
+
+			h := NewHandlers(zaptest.NewLogger(t), urlHndl, "http://localhost:8080", nil)
+
+			r := chi.NewRouter()
+			r.Get("/api/user/urls/{id}/history", h.URLHistoryHandler)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/user/urls/promo/history", nil)
+			req = req.WithContext(auth.ContextWithUserID(context.Background(), userID))
+			rr := httptest.NewRecorder()
+
+			r.ServeHTTP(rr, req)
+
+			result := rr.Result()
+			defer result.Body.Close()
+			assert.Equal(t, tc.expectedStatus, result.StatusCode)
+
+			if tc.expectedStatus == http.StatusOK {
+				resp := []models.URLRevision{}
+				require.NoError(t, json.NewDecoder(result.Body).Decode(&resp))
+				assert.Equal(t, history, resp)
+			}
+		})
+	}
+}
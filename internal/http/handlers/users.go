@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/auth"
+	urlhandler "github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler"
+)
+
+// authFunc регистрация или вход пользователя.
+type authFunc func(ctx context.Context, creds models.Credentials, anonUserID string) (models.AuthResponse, error)
+
+// RegisterHandler регистрирует пользователя и выдает ему токен.
+// URL, созданные под анонимным токеном запроса, переходят к новому пользователю.
+func (h *Handlers) RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	h.authenticate(w, r, h.urlHandler.Register, http.StatusCreated)
+}
+
+// LoginHandler проверяет логин и пароль и выдает токен.
+// URL, созданные под анонимным токеном запроса, переходят к пользователю.
+func (h *Handlers) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	h.authenticate(w, r, h.urlHandler.Login, http.StatusOK)
+}
+
+func (h *Handlers) authenticate(w http.ResponseWriter, r *http.Request, fn authFunc, status int) {
+	defer r.Body.Close()
+
+	creds := models.Credentials{}
+	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+		h.log.Error(
+			"failed to read JSON request body",
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*4)
+	defer cancel()
+
+	resp, err := fn(ctx, creds, auth.UserIDFromContext(r.Context()))
+	switch {
+	case errors.Is(err, urlhandler.ErrLoginTaken):
+		renderError(w, r, http.StatusConflict, models.ErrorResponse{
+			Code:    codeLoginTaken,
+			Message: err.Error(),
+		})
+		return
+	case errors.Is(err, urlhandler.ErrInvalidUser):
+		renderError(w, r, http.StatusBadRequest, models.ErrorResponse{
+			Code:    codeInvalidUser,
+			Message: err.Error(),
+		})
+		return
+	case errors.Is(err, urlhandler.ErrInvalidCredentials):
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	case err != nil && len(resp.UserID) == 0:
+		h.log.Error(
+			"failed to authenticate user",
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	case err != nil:
+		// пользователь авторизован, но ссылки не перенесены
+		h.log.Error(
+			"failed to claim anonymous urls",
+			zap.String("user-id", resp.UserID),
+			zap.Error(err),
+		)
+	}
+
+	if err := h.tokens.IssueToken(w, resp.UserID); err != nil {
+		h.log.Error(
+			"failed to create token",
+			zap.Error(err),
+		)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	render.Status(r, status)
+	render.JSON(w, r, resp)
+}
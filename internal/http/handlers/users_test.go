@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/handlers/mocks"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/auth"
+	urlhandler "github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler"
+)
+
+func TestAuthHandlers(t *testing.T) {
+	const anonUserID = "anon"
+
+	cases := []struct {
+		name           string
+		method         string
+		body           string
+		resp           models.AuthResponse
+		err            error
+		expectedStatus int
+		expectedCode   string
+		isCallMock     bool
+		isIssueToken   bool
+	}{
+		{
+			name:           "successful registration",
+			method:         "Register",
+			body:           `{"login":"gopher","password":"password123"}`,
+			resp:           models.AuthResponse{UserID: "user", Claimed: 2},
+			expectedStatus: http.StatusCreated,
+			isCallMock:     true,
+			isIssueToken:   true,
+		},
+		{
+			name:           "login is taken",
+			method:         "Register",
+			body:           `{"login":"gopher","password":"password123"}`,
+			err:            urlhandler.ErrLoginTaken,
+			expectedStatus: http.StatusConflict,
+			expectedCode:   codeLoginTaken,
+			isCallMock:     true,
+		},
+		{
+			name:           "invalid user",
+			method:         "Register",
+			body:           `{"login":"gopher","password":"123"}`,
+			err:            urlhandler.ErrInvalidUser,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   codeInvalidUser,
+			isCallMock:     true,
+		},
+		{
+			name:           "successful login",
+			method:         "Login",
+			body:           `{"login":"gopher","password":"password123"}`,
+			resp:           models.AuthResponse{UserID: "user"},
+			expectedStatus: http.StatusOK,
+			isCallMock:     true,
+			isIssueToken:   true,
+		},
+		{
+			name:           "login with failed claim",
+			method:         "Login",
+			body:           `{"login":"gopher","password":"password123"}`,
+			resp:           models.AuthResponse{UserID: "user"},
+			err:            errors.New("storage is unavailable"),
+			expectedStatus: http.StatusOK,
+			isCallMock:     true,
+			isIssueToken:   true,
+		},
+		{
+			name:           "invalid credentials",
+			method:         "Login",
+			body:           `{"login":"gopher","password":"password124"}`,
+			err:            urlhandler.ErrInvalidCredentials,
+			expectedStatus: http.StatusUnauthorized,
+			isCallMock:     true,
+		},
+		{
+			name:           "invalid json",
+			method:         "Login",
+			body:           `{"login":`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlHndl := mocks.NewURLHandler(t)
+			if tc.isCallMock {
+				urlHndl.On(tc.method, mock.AnythingOfType("*context.timerCtx"), mock.AnythingOfType("models.Credentials"), anonUserID).
+					Return(tc.resp, tc.err)
+			}
+			tokens := mocks.NewTokenIssuer(t)
+			if tc.isIssueToken {
+				tokens.On("IssueToken", mock.Anything, tc.resp.UserID).Return(nil)
+			}
+
+			h := NewHandlers(
+				zaptest.NewLogger(t),
+				urlHndl,
+				"http://localhost:8080",
+				tokens,
+			)
+
+			handler := h.LoginHandler
+			if tc.method == "Register" {
+				handler = h.RegisterHandler
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			req = req.WithContext(auth.ContextWithUserID(req.Context(), anonUserID))
+			rr := httptest.NewRecorder()
+
+			handler(rr, req)
+
+			result := rr.Result()
+			defer result.Body.Close()
+			assert.Equal(t, tc.expectedStatus, result.StatusCode)
+
+			switch {
+			case len(tc.expectedCode) > 0:
+				errResp := models.ErrorResponse{}
+				require.NoError(t, json.NewDecoder(result.Body).Decode(&errResp))
+				assert.Equal(t, tc.expectedCode, errResp.Code)
+			case tc.isIssueToken:
+				resp := models.AuthResponse{}
+				require.NoError(t, json.NewDecoder(result.Body).Decode(&resp))
+				assert.Equal(t, tc.resp, resp)
+			}
+		})
+	}
+}
@@ -2,8 +2,11 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -11,18 +14,61 @@ import (
 	jwttoken "github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/auth/jwt-token"
 )
 
-const key = "auth-token"
+// TokenKey имя cookie и ключа метаданных gRPC с токеном.
+const TokenKey = "auth-token"
+
+// TokenTTL время жизни выдаваемого токена.
+const TokenTTL = time.Hour * 3
+
+// HeaderKey заголовок с токеном в формате "Bearer <jwt>".
+// В этом же заголовке ответа возвращается выданный токен.
+const HeaderKey = "Authorization"
+
+const bearerPrefix = "Bearer "
+
+// APIKeyHeader заголовок с ключом API.
+const APIKeyHeader = "X-API-Key"
+
+// ErrNoToken токен не передан ни в заголовке, ни в cookie.
+var ErrNoToken = errors.New("token is not found")
 
 // Auth авторизация пользователей.
 type Auth struct {
-	secretKey string
+	// keys активный набор ключей, заменяется при ротации.
+	keys atomic.Pointer[KeySet]
+	// secure cookie передается только по https и недоступна из js.
+	secure bool
 }
 
 // New конструктор Auth.
-func New(secretKey string) *Auth {
-	return &Auth{
-		secretKey: secretKey,
+func New(keys KeySet, secure bool) *Auth {
+	a := &Auth{
+		secure: secure,
 	}
+	a.keys.Store(&keys)
+	return a
+}
+
+// SetKeys заменяет набор ключей без перезапуска сервера.
+func (a *Auth) SetKeys(keys KeySet) error {
+	if err := keys.Validate(); err != nil {
+		return err
+	}
+	a.keys.Store(&keys)
+	return nil
+}
+
+// CreateToken создает токен JWT, подписанный активным ключом.
+func (a *Auth) CreateToken(
+	expiresAt time.Duration,
+	userID string,
+) (string, error) {
+	keys := a.keys.Load()
+	secretKey, ok := keys.Keys[keys.Active]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownKey, keys.Active)
+	}
+	return jwttoken.NewJWTToken(expiresAt, keys.Active, secretKey, userID)
 }
 
 // CreateCookie создает cookie с подписанным токеном JWT.
@@ -30,26 +76,50 @@ func (a *Auth) CreateCookie(
 	expiresAt time.Duration,
 	userID string,
 ) (*http.Cookie, error) {
-	token, err := jwttoken.NewJWTToken(expiresAt, a.secretKey, userID)
+	token, err := a.CreateToken(expiresAt, userID)
 	if err != nil {
 		return nil, err
 	}
 
 	return &http.Cookie{
-		Name:   key,
-		Value:  token,
-		Path:   "/",
-		MaxAge: int(expiresAt.Seconds()),
+		Name:     TokenKey,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(expiresAt.Seconds()),
+		Secure:   a.secure,
+		HttpOnly: a.secure,
 	}, nil
 }
 
-// Validate проверяет токен в cookie.
+// IssueToken выдает пользователю новый токен в cookie и заголовке ответа.
+func (a *Auth) IssueToken(w http.ResponseWriter, userID string) error {
+	cookie, err := a.CreateCookie(TokenTTL, userID)
+	if err != nil {
+		return err
+	}
+	SetToken(w, cookie)
+	return nil
+}
+
+// Validate проверяет токен любым ключом из набора по kid.
+// Токены без kid проверяются активным ключом.
 func (a *Auth) Validate(tokenString string) (*jwttoken.Claims, error) {
+	keys := a.keys.Load()
 	claims := &jwttoken.Claims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims,
 		func(t *jwt.Token) (interface{}, error) {
-			return []byte(a.secretKey), nil
-		})
+			kid, _ := t.Header["kid"].(string)
+			if len(kid) == 0 {
+				kid = keys.Active
+			}
+			secretKey, ok := keys.Keys[kid]
+			if !ok {
+				return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+			}
+			return []byte(secretKey), nil
+		},
+		jwt.WithValidMethods([]string{jwttoken.SigningMethod.Alg()}),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token %w", err)
 	}
@@ -66,7 +136,35 @@ func (a *Auth) Validate(tokenString string) (*jwttoken.Claims, error) {
 
 // CookieFromRequest cookie из *http.Request.
 func CookieFromRequest(r *http.Request) (*http.Cookie, error) {
-	return r.Cookie(key)
+	return r.Cookie(TokenKey)
+}
+
+// TokenFromRequest токен из заголовка Authorization или cookie.
+// Заголовок имеет приоритет.
+func TokenFromRequest(r *http.Request) (string, error) {
+	if header := r.Header.Get(HeaderKey); len(header) > 0 {
+		return BearerToken(header)
+	}
+
+	cookie, err := CookieFromRequest(r)
+	if err != nil {
+		return "", ErrNoToken
+	}
+	return cookie.Value, nil
+}
+
+// BearerToken токен из значения заголовка Authorization "Bearer <jwt>".
+func BearerToken(header string) (string, error) {
+	if len(header) <= len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return "", fmt.Errorf("unsupported authorization scheme")
+	}
+	return strings.TrimSpace(header[len(bearerPrefix):]), nil
+}
+
+// SetToken передает выданный токен в cookie и заголовке ответа.
+func SetToken(w http.ResponseWriter, cookie *http.Cookie) {
+	http.SetCookie(w, cookie)
+	w.Header().Set(HeaderKey, bearerPrefix+cookie.Value)
 }
 
 type contextKey struct {
@@ -74,7 +172,9 @@ type contextKey struct {
 }
 
 var (
-	userIDCtxKey = &contextKey{"userID"}
+	userIDCtxKey  = &contextKey{"userID"}
+	scopesCtxKey  = &contextKey{"scopes"}
+	newUserCtxKey = &contextKey{"newUser"}
 )
 
 // ContextWithUserID контекст с UserID.
@@ -83,6 +183,18 @@ func ContextWithUserID(parent context.Context, userID string) context.Context {
 	return ctx
 }
 
+// ContextWithNewUserID контекст с UserID, выданным в этом запросе.
+func ContextWithNewUserID(parent context.Context, userID string) context.Context {
+	ctx := ContextWithUserID(parent, userID)
+	return context.WithValue(ctx, newUserCtxKey, true)
+}
+
+// IsNewUser UserID выдан в этом запросе, клиент еще не предъявлял токен.
+func IsNewUser(ctx context.Context) bool {
+	isNew, _ := ctx.Value(newUserCtxKey).(bool)
+	return isNew
+}
+
 // UserIDFromContext UserID из контекста.
 func UserIDFromContext(ctx context.Context) string {
 	if userID, ok := ctx.Value(userIDCtxKey).(string); ok {
@@ -90,3 +202,34 @@ func UserIDFromContext(ctx context.Context) string {
 	}
 	return ""
 }
+
+// apiKeyScopes области доступа ключа API, которым авторизован запрос.
+type apiKeyScopes struct {
+	scopes []string
+}
+
+// ContextWithScopes контекст запроса, авторизованного ключом API.
+func ContextWithScopes(parent context.Context, scopes []string) context.Context {
+	return context.WithValue(parent, scopesCtxKey, apiKeyScopes{scopes: scopes})
+}
+
+// IsAPIKey запрос авторизован ключом API.
+func IsAPIKey(ctx context.Context) bool {
+	_, ok := ctx.Value(scopesCtxKey).(apiKeyScopes)
+	return ok
+}
+
+// HasScope проверяет доступ к области scope.
+// Запросы по токену и ключи без областей имеют полный доступ.
+func HasScope(ctx context.Context, scope string) bool {
+	s, ok := ctx.Value(scopesCtxKey).(apiKeyScopes)
+	if !ok || len(s.scopes) == 0 {
+		return true
+	}
+	for _, v := range s.scopes {
+		if v == scope {
+			return true
+		}
+	}
+	return false
+}
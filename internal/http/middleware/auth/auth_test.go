@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	jwttoken "github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/auth/jwt-token"
+)
+
+func TestValidate(t *testing.T) {
+	oldKeys := KeySet{Active: "k1", Keys: map[string]string{"k1": "secret-1"}}
+	newKeys := KeySet{Active: "k2", Keys: map[string]string{"k1": "secret-1", "k2": "secret-2"}}
+
+	oldToken, err := New(oldKeys, false).CreateToken(TokenTTL, "user")
+	require.NoError(t, err)
+
+	noKidToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwttoken.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenTTL)),
+		},
+		UserID: "user",
+	}).SignedString([]byte("secret-2"))
+	require.NoError(t, err)
+
+	unknownKidToken, err := jwttoken.NewJWTToken(TokenTTL, "k3", "secret-3", "user")
+	require.NoError(t, err)
+
+	hs512 := jwt.NewWithClaims(jwt.SigningMethodHS512, jwttoken.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenTTL)),
+		},
+		UserID: "user",
+	})
+	hs512.Header["kid"] = "k2"
+	hs512Token, err := hs512.SignedString([]byte("secret-2"))
+	require.NoError(t, err)
+
+	noneToken, err := jwt.NewWithClaims(jwt.SigningMethodNone, jwttoken.Claims{
+		UserID: "user",
+	}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name    string
+		token   string
+		isError bool
+	}{
+		{
+			name:  "token signed with previous key",
+			token: oldToken,
+		},
+		{
+			name:  "token without kid checked with active key",
+			token: noKidToken,
+		},
+		{
+			name:    "unknown kid",
+			token:   unknownKidToken,
+			isError: true,
+		},
+		{
+			name:    "unexpected signing method",
+			token:   hs512Token,
+			isError: true,
+		},
+		{
+			name:    "unsigned token",
+			token:   noneToken,
+			isError: true,
+		},
+	}
+
+	a := New(newKeys, false)
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			claims, err := a.Validate(tc.token)
+			if tc.isError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "user", claims.UserID)
+		})
+	}
+}
+
+func TestSetKeys(t *testing.T) {
+	a := New(SingleKey("secret-1"), false)
+
+	token, err := a.CreateToken(TokenTTL, "user")
+	require.NoError(t, err)
+
+	assert.Error(t, a.SetKeys(KeySet{Active: "k2", Keys: map[string]string{"k1": "secret"}}))
+
+	path := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"active":"k2","keys":{"k2":"secret-2"}}`), 0o600))
+
+	keys, err := LoadKeySet(path)
+	require.NoError(t, err)
+	require.NoError(t, a.SetKeys(keys))
+
+	// ключ default удален из набора
+	_, err = a.Validate(token)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	token, err = a.CreateToken(TokenTTL, "user")
+	require.NoError(t, err)
+	claims, err := a.Validate(token)
+	require.NoError(t, err)
+	assert.Equal(t, "user", claims.UserID)
+}
@@ -13,15 +13,20 @@ type Claims struct {
 	UserID string
 }
 
+// SigningMethod единственный допустимый алгоритм подписи токенов.
+var SigningMethod = jwt.SigningMethodHS256
+
 // NewJWTToken создает новый JWT-токен и подписывает его.
+// keyID передается в заголовке kid для выбора ключа при проверке.
 func NewJWTToken(
 	expiresAt time.Duration,
+	keyID string,
 	secretKey string,
 	userID string,
 ) (string, error) {
 
 	token := jwt.NewWithClaims(
-		jwt.SigningMethodHS256,
+		SigningMethod,
 		Claims{
 			RegisteredClaims: jwt.RegisteredClaims{
 				ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresAt)),
@@ -30,6 +35,8 @@ func NewJWTToken(
 		},
 	)
 
+	token.Header["kid"] = keyID
+
 	tokenString, err := token.SignedString([]byte(secretKey))
 	if err != nil {
 		return "", err
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// DefaultKeyID kid ключа, заданного одним секретом.
+const DefaultKeyID = "default"
+
+// ErrUnknownKey в наборе нет ключа с kid из токена.
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeySet набор ключей подписи JWT.
+// Новые токены подписываются ключом Active,
+// проверяются любым ключом набора - так старые ключи выводятся постепенно.
+type KeySet struct {
+	Active string            `json:"active"`
+	Keys   map[string]string `json:"keys"`
+}
+
+// SingleKey набор из одного секрета с kid DefaultKeyID.
+func SingleKey(secretKey string) KeySet {
+	return KeySet{
+		Active: DefaultKeyID,
+		Keys:   map[string]string{DefaultKeyID: secretKey},
+	}
+}
+
+// LoadKeySet читает набор ключей из JSON файла вида
+// {"active": "2024-02", "keys": {"2024-01": "...", "2024-02": "..."}}.
+func LoadKeySet(path string) (KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return KeySet{}, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	var ks KeySet
+	if err := json.Unmarshal(data, &ks); err != nil {
+		return KeySet{}, fmt.Errorf("failed to parse key file %s: %w", path, err)
+	}
+
+	if err := ks.Validate(); err != nil {
+		return KeySet{}, fmt.Errorf("invalid key file %s: %w", path, err)
+	}
+
+	return ks, nil
+}
+
+// Validate проверяет, что активный ключ есть в наборе и ключи не пустые.
+func (ks KeySet) Validate() error {
+	if len(ks.Keys) == 0 {
+		return errors.New("key set is empty")
+	}
+	for kid, key := range ks.Keys {
+		if len(kid) == 0 {
+			return errors.New("key id must not be empty")
+		}
+		if len(key) == 0 {
+			return fmt.Errorf("key %q is empty", kid)
+		}
+	}
+	if _, ok := ks.Keys[ks.Active]; !ok {
+		return fmt.Errorf("active key %q is not in the key set", ks.Active)
+	}
+	return nil
+}
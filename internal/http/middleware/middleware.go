@@ -1,7 +1,11 @@
 package middleware
 
 import (
+	"context"
+	"errors"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -9,24 +13,65 @@ import (
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/auth"
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/compress"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/ratelimit"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/lib/netutils"
+	urlhandler "github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler"
 )
 
+// APIKeyResolver поиск ключа API по значению из заголовка.
+//
+//go:generate mockery --name APIKeyResolver
+type APIKeyResolver interface {
+	ResolveAPIKey(ctx context.Context, key string) (models.APIKey, error)
+}
+
 // Middleware хранит общие объекты.
 type Middleware struct {
 	log  *zap.Logger
 	auth *auth.Auth
+	// apiKeys nil - авторизация по ключам API отключена.
+	apiKeys APIKeyResolver
+	// trustedSubnet подсеть, которой доступны внутренние ручки.
+	// nil - доступ запрещен всем.
+	trustedSubnet *net.IPNet
+	// trustedProxies подсети прокси, которым доверяется X-Real-IP.
+	trustedProxies []*net.IPNet
 }
 
 // New новая инстанция Middleware.
-func New(log *zap.Logger, auth *auth.Auth) *Middleware {
+func New(
+	log *zap.Logger,
+	auth *auth.Auth,
+	apiKeys APIKeyResolver,
+	trustedSubnet *net.IPNet,
+	trustedProxies []*net.IPNet,
+) *Middleware {
 	return &Middleware{
-		log:  log,
-		auth: auth,
+		log:            log,
+		auth:           auth,
+		apiKeys:        apiKeys,
+		trustedSubnet:  trustedSubnet,
+		trustedProxies: trustedProxies,
 	}
 }
 
+// RealIP подставляет в RemoteAddr адрес из X-Real-IP, если запрос
+// пришел от доверенного прокси. Остальные ручки и middleware
+// берут адрес клиента только из RemoteAddr.
+func (m *Middleware) RealIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if ip := netutils.RealIP(r, m.trustedProxies); ip != netutils.RemoteIP(r) {
+				r.RemoteAddr = ip
+			}
+			next.ServeHTTP(w, r)
+		},
+	)
+}
+
 // Logger логирование запросов.
 func (m *Middleware) Logger(next http.Handler) http.Handler {
 	return http.HandlerFunc(
@@ -98,46 +143,180 @@ func (m *Middleware) NewCompressHandler(contentTypes []string) func(next http.Ha
 	}
 }
 
-// Auth авторизация и валидация.
+// Auth авторизация по ключу API из заголовка X-API-Key,
+// затем по токену из заголовка Authorization или cookie.
+// Если токена нет или он невалиден, выдается новый пользователь.
+// Невалидный ключ API всегда отклоняется с 401.
 func (m *Middleware) Auth(next http.Handler) http.Handler {
+	return m.authenticate(next, false)
+}
+
+// StrictAuth авторизация для ручек, которым нужен существующий пользователь.
+// Без валидного токена возвращает 401, новый пользователь не выдается.
+func (m *Middleware) StrictAuth(next http.Handler) http.Handler {
+	return m.authenticate(next, true)
+}
+
+func (m *Middleware) authenticate(next http.Handler, strict bool) http.Handler {
 	return http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
 
-			cookie, err := auth.CookieFromRequest(r)
-			if err != nil {
-				userID := uuid.New().String()
-				cookie, err := m.auth.CreateCookie(time.Hour*3, userID)
-				if err != nil {
-					m.log.Error("failed to create new cookie", zap.Error(err))
-					w.WriteHeader(http.StatusInternalServerError)
+			if key := r.Header.Get(auth.APIKeyHeader); len(key) > 0 && m.apiKeys != nil {
+				m.authenticateAPIKey(w, r, next, key)
+				return
+			}
+
+			token, err := auth.TokenFromRequest(r)
+			if err == nil {
+				claims, errValidate := m.auth.Validate(token)
+				if errValidate == nil {
+					ctx := auth.ContextWithUserID(r.Context(), claims.UserID)
+					next.ServeHTTP(w, r.WithContext(ctx))
 					return
 				}
-				http.SetCookie(w, cookie)
-				ctx := auth.ContextWithUserID(r.Context(), userID)
-				next.ServeHTTP(w, r.WithContext(ctx))
-				return
+				err = errValidate
 			}
 
-			claims, err := m.auth.Validate(cookie.Value)
-			if err != nil {
-				userID := uuid.New().String()
+			if !errors.Is(err, auth.ErrNoToken) {
 				m.log.Error("token is invalid", zap.Error(err))
-				cookie, err := m.auth.CreateCookie(time.Hour*3, userID)
-				if err != nil {
-					m.log.Error("failed to create new cookie", zap.Error(err))
-					w.WriteHeader(http.StatusInternalServerError)
+			}
+
+			if strict {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			userID := uuid.New().String()
+			if err := m.auth.IssueToken(w, userID); err != nil {
+				m.log.Error("failed to create new cookie", zap.Error(err))
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			ctx := auth.ContextWithNewUserID(r.Context(), userID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		},
+	)
+}
+
+func (m *Middleware) authenticateAPIKey(w http.ResponseWriter, r *http.Request, next http.Handler, key string) {
+	apiKey, err := m.apiKeys.ResolveAPIKey(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, urlhandler.ErrInvalidAPIKey) {
+			m.log.Warn("api key is invalid", zap.String("ip", netutils.RemoteIP(r)))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		m.log.Error("failed to resolve api key", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ctx := auth.ContextWithUserID(r.Context(), apiKey.UserID)
+	ctx = auth.ContextWithScopes(ctx, apiKey.Scopes)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// RequireScope пропускает запросы по ключу API только с областью scope.
+func (m *Middleware) RequireScope(scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if !auth.HasScope(r.Context(), scope) {
+					w.WriteHeader(http.StatusForbidden)
 					return
 				}
-				http.SetCookie(w, cookie)
-				ctx := auth.ContextWithUserID(r.Context(), userID)
-				next.ServeHTTP(w, r.WithContext(ctx))
+				next.ServeHTTP(w, r)
+			},
+		)
+	}
+}
+
+// DenyAPIKey запрещает запросы по ключу API,
+// например управление самими ключами.
+func (m *Middleware) DenyAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if auth.IsAPIKey(r.Context()) {
+				w.WriteHeader(http.StatusForbidden)
 				return
 			}
+			next.ServeHTTP(w, r)
+		},
+	)
+}
+
+// RateLimit ограничивает частоту запросов пользователя.
+// Для только что выданных пользователей ключом служит IP клиента,
+// иначе запросы без cookie обходили бы ограничение.
+// nil limiter - без ограничений.
+func (m *Middleware) RateLimit(limiter *ratelimit.Limiter) func(next http.Handler) http.Handler {
+	return m.rateLimit(limiter, func(r *http.Request) string {
+		if userID := auth.UserIDFromContext(r.Context()); len(userID) > 0 && !auth.IsNewUser(r.Context()) {
+			return "user:" + userID
+		}
+		return "ip:" + netutils.RemoteIP(r)
+	})
+}
+
+// RateLimitIP ограничивает частоту запросов с IP клиента
+// независимо от пользователя: перебор паролей не обходится сменой токена.
+// nil limiter - без ограничений.
+func (m *Middleware) RateLimitIP(limiter *ratelimit.Limiter) func(next http.Handler) http.Handler {
+	return m.rateLimit(limiter, func(r *http.Request) string {
+		return "ip:" + netutils.RemoteIP(r)
+	})
+}
 
-			ctx := auth.ContextWithUserID(r.Context(), claims.UserID)
-			rr := r.WithContext(ctx)
+func (m *Middleware) rateLimit(limiter *ratelimit.Limiter, keyFn func(r *http.Request) string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				key := keyFn(r)
+
+				res := limiter.Allow(key)
+				w.Header().Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+				w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+				w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+
+				if !res.Allowed {
+					w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+					m.log.Warn("rate limit exceeded",
+						zap.String("key", key),
+						zap.String("url", r.URL.Path),
+					)
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+
+				next.ServeHTTP(w, r)
+			},
+		)
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// TrustedSubnet пропускает только запросы из доверенной подсети.
+func (m *Middleware) TrustedSubnet(next http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			ip := netutils.RemoteIP(r)
+			if !netutils.InSubnet(m.trustedSubnet, ip) {
+				m.log.Warn("request from untrusted address",
+					zap.String("ip", ip),
+					zap.String("url", r.URL.Path),
+				)
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
 
-			next.ServeHTTP(w, rr)
+			next.ServeHTTP(w, r)
 		},
 	)
 }
@@ -3,8 +3,11 @@ package middleware
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -12,10 +15,18 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"go.uber.org/zap/zaptest"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/auth"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/mocks"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/ratelimit"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/lib/netutils"
+	urlhandler "github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler"
 )
 
 func TestLogger(t *testing.T) {
@@ -28,7 +39,7 @@ func TestLogger(t *testing.T) {
 	)
 
 	r := chi.NewRouter()
-	r.Use(New(log, nil).Logger)
+	r.Use(New(log, nil, nil, nil, nil).Logger)
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
@@ -132,7 +143,7 @@ func TestCompress(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 			r := chi.NewRouter()
-			r.Use(New(log, nil).NewCompressHandler(tc.ContentTypesSupported))
+			r.Use(New(log, nil, nil, nil, nil).NewCompressHandler(tc.ContentTypesSupported))
 			r.Post("/", func(w http.ResponseWriter, r *http.Request) {
 				defer r.Body.Close()
 				b, err := io.ReadAll(r.Body)
@@ -186,3 +197,363 @@ func TestCompress(t *testing.T) {
 	}
 
 }
+
+func TestTrustedSubnet(t *testing.T) {
+	_, subnet, err := net.ParseCIDR("192.168.1.0/24")
+	require.NoError(t, err)
+	proxies, err := netutils.ParseSubnets([]string{"10.10.0.0/16"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		subnet         *net.IPNet
+		remoteAddr     string
+		realIP         string
+		expectedStatus int
+	}{
+		{
+			name:           "ip in trusted subnet",
+			subnet:         subnet,
+			remoteAddr:     "192.168.1.15:40000",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "ip out of trusted subnet",
+			subnet:         subnet,
+			remoteAddr:     "10.0.0.1:40000",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "X-Real-IP from trusted proxy",
+			subnet:         subnet,
+			remoteAddr:     "10.10.0.2:40000",
+			realIP:         "192.168.1.15",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "X-Real-IP from untrusted client is ignored",
+			subnet:         subnet,
+			remoteAddr:     "10.0.0.1:40000",
+			realIP:         "192.168.1.15",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "empty subnet denies everything",
+			remoteAddr:     "192.168.1.15:40000",
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := New(zaptest.NewLogger(t), nil, nil, tt.subnet, proxies)
+			r := chi.NewRouter()
+			r.Use(m.RealIP, m.TrustedSubnet)
+			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if len(tt.realIP) > 0 {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			rr := httptest.NewRecorder()
+
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func TestAuth(t *testing.T) {
+	a := auth.New(auth.SingleKey("secret-key"), false)
+
+	token, err := a.CreateToken(auth.TokenTTL, "user")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		strict         bool
+		header         string
+		cookie         string
+		expectedStatus int
+		expectedUser   string
+		expectedNew    bool
+	}{
+		{
+			name:           "bearer token",
+			header:         "Bearer " + token,
+			expectedStatus: http.StatusOK,
+			expectedUser:   "user",
+		},
+		{
+			name:           "cookie token",
+			cookie:         token,
+			expectedStatus: http.StatusOK,
+			expectedUser:   "user",
+		},
+		{
+			name:           "header has priority over cookie",
+			header:         "Bearer invalid",
+			cookie:         token,
+			expectedStatus: http.StatusOK,
+			expectedNew:    true,
+		},
+		{
+			name:           "no token issues new user",
+			expectedStatus: http.StatusOK,
+			expectedNew:    true,
+		},
+		{
+			name:           "strict with bearer token",
+			strict:         true,
+			header:         "Bearer " + token,
+			expectedStatus: http.StatusOK,
+			expectedUser:   "user",
+		},
+		{
+			name:           "strict with invalid token",
+			strict:         true,
+			header:         "Bearer invalid",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "strict with unsupported scheme",
+			strict:         true,
+			header:         "Basic dXNlcjpwYXNz",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "strict without token",
+			strict:         true,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := New(zaptest.NewLogger(t), a, nil, nil, nil)
+			mw := m.Auth
+			if tt.strict {
+				mw = m.StrictAuth
+			}
+
+			var userID string
+			r := chi.NewRouter()
+			r.Use(mw)
+			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+				userID = auth.UserIDFromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if len(tt.header) > 0 {
+				req.Header.Set(auth.HeaderKey, tt.header)
+			}
+			if len(tt.cookie) > 0 {
+				req.AddCookie(&http.Cookie{Name: auth.TokenKey, Value: tt.cookie})
+			}
+			rr := httptest.NewRecorder()
+
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if !tt.expectedNew {
+				assert.Equal(t, tt.expectedUser, userID)
+				assert.Empty(t, rr.Header().Get(auth.HeaderKey))
+				return
+			}
+
+			issued := strings.TrimPrefix(rr.Header().Get(auth.HeaderKey), "Bearer ")
+			claims, err := a.Validate(issued)
+			require.NoError(t, err)
+			assert.Equal(t, userID, claims.UserID)
+			assert.NotEqual(t, "user", userID)
+		})
+	}
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	const validKey = "sk_valid"
+
+	tests := []struct {
+		name           string
+		key            string
+		scopes         []string
+		scope          string
+		resolveErr     error
+		expectedStatus int
+	}{
+		{
+			name:           "key without scopes",
+			key:            validKey,
+			scope:          models.ScopeDelete,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "key with required scope",
+			key:            validKey,
+			scopes:         []string{models.ScopeShorten},
+			scope:          models.ScopeShorten,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "key without required scope",
+			key:            validKey,
+			scopes:         []string{models.ScopeShorten},
+			scope:          models.ScopeRead,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "invalid key",
+			key:            "sk_invalid",
+			resolveErr:     urlhandler.ErrInvalidAPIKey,
+			scope:          models.ScopeRead,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "storage error",
+			key:            validKey,
+			resolveErr:     errors.New("storage is unavailable"),
+			scope:          models.ScopeRead,
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			resolver := mocks.NewAPIKeyResolver(t)
+			resolver.On("ResolveAPIKey", mock.Anything, tt.key).
+				Return(models.APIKey{UserID: "user", Scopes: tt.scopes}, tt.resolveErr)
+
+			m := New(zaptest.NewLogger(t), auth.New(auth.SingleKey("secret-key"), false), resolver, nil, nil)
+
+			var userID string
+			r := chi.NewRouter()
+			r.Use(m.Auth)
+			r.With(m.RequireScope(tt.scope)).Get("/", func(w http.ResponseWriter, r *http.Request) {
+				userID = auth.UserIDFromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			})
+			r.With(m.DenyAPIKey).Get("/keys", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(auth.APIKeyHeader, tt.key)
+			rr := httptest.NewRecorder()
+
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			// новый пользователь по ключу API не выдается
+			assert.Empty(t, rr.Header().Get(auth.HeaderKey))
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			assert.Equal(t, "user", userID)
+
+			req = httptest.NewRequest(http.MethodGet, "/keys", nil)
+			req.Header.Set(auth.APIKeyHeader, tt.key)
+			rr = httptest.NewRecorder()
+
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusForbidden, rr.Code)
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	a := auth.New(auth.SingleKey("secret-key"), false)
+	token, err := a.CreateToken(auth.TokenTTL, "user")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := New(zaptest.NewLogger(t), a, nil, nil, nil)
+
+	r := chi.NewRouter()
+	r.Use(m.RealIP, m.Auth)
+	r.With(m.RateLimit(ratelimit.NewLimiter(ctx, ratelimit.Limit{Rate: 0.001, Burst: 1}))).
+		Post("/", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		})
+
+	send := func(header string, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.RemoteAddr = ip + ":40000"
+		if len(header) > 0 {
+			req.Header.Set(auth.HeaderKey, header)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := send("Bearer "+token, "10.0.0.1")
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", rr.Header().Get("X-RateLimit-Remaining"))
+
+	// лимит привязан к пользователю, а не к адресу
+	rr = send("Bearer "+token, "10.0.0.2")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "1000", rr.Header().Get("Retry-After"))
+
+	// без токена каждый запрос получает нового пользователя, лимит по IP
+	assert.Equal(t, http.StatusCreated, send("", "10.0.0.3").Code)
+	assert.Equal(t, http.StatusTooManyRequests, send("", "10.0.0.3").Code)
+	assert.Equal(t, http.StatusCreated, send("", "10.0.0.4").Code)
+
+	// подмена X-Real-IP без доверенного прокси не дает новую корзину
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.RemoteAddr = "10.0.0.3:40000"
+	req.Header.Set("X-Real-IP", "203.0.113.9")
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+}
+
+func TestRateLimitIP(t *testing.T) {
+	a := auth.New(auth.SingleKey("secret-key"), false)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := New(zaptest.NewLogger(t), a, nil, nil, nil)
+
+	r := chi.NewRouter()
+	r.Use(m.RealIP, m.Auth)
+	r.With(m.RateLimitIP(ratelimit.NewLimiter(ctx, ratelimit.Limit{Rate: 0.001, Burst: 1}))).
+		Post("/api/user/login", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+
+	send := func(userID string, ip string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/user/login", nil)
+		req.RemoteAddr = ip + ":40000"
+		token, err := a.CreateToken(auth.TokenTTL, userID)
+		require.NoError(t, err)
+		req.Header.Set(auth.HeaderKey, "Bearer "+token)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusOK, send("user-1", "10.0.0.1"))
+	// смена токена не дает новую корзину
+	assert.Equal(t, http.StatusTooManyRequests, send("user-2", "10.0.0.1"))
+	assert.Equal(t, http.StatusOK, send("user-2", "10.0.0.2"))
+}
@@ -0,0 +1,147 @@
+// ratelimit ограничение частоты запросов по алгоритму token bucket
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// cleanupInterval период удаления заполненных корзин.
+const cleanupInterval = time.Minute
+
+// Limit параметры корзины.
+// Rate токенов в секунду, Burst емкость корзины.
+// Rate <= 0 отключает ограничение.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Limiters ограничения для групп ручек, nil - без ограничений.
+type Limiters struct {
+	Create   *Limiter
+	Batch    *Limiter
+	Redirect *Limiter
+	// Auth регистрация и вход, ограничивается по IP.
+	Auth *Limiter
+}
+
+// Result результат проверки запроса.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter через сколько появится следующий токен.
+	RetryAfter time.Duration
+	// Reset через сколько корзина заполнится полностью.
+	Reset time.Duration
+}
+
+// Limiter набор корзин по ключу (пользователь или IP).
+type Limiter struct {
+	mutex   sync.Mutex
+	limit   Limit
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter конструктор Limiter.
+// Пока ctx не отменен, корзины, заполненные до конца, периодически удаляются:
+// они ничем не отличаются от новых, поэтому память не растет от разовых клиентов.
+// Для отключенного лимита возвращается nil.
+func NewLimiter(ctx context.Context, limit Limit) *Limiter {
+	if limit.Rate <= 0 {
+		return nil
+	}
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+
+	l := &Limiter{
+		limit:   limit,
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+
+	go func() {
+		ticker := time.NewTicker(cleanupInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				l.cleanup()
+			}
+		}
+	}()
+
+	return l
+}
+
+// Allow забирает токен из корзины key.
+func (l *Limiter) Allow(key string) Result {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.limit.Burst), last: now}
+		l.buckets[key] = b
+	}
+	b.refill(now, l.limit)
+
+	res := Result{Limit: l.limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = l.duration(1 - b.tokens)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = l.duration(float64(l.limit.Burst) - b.tokens)
+
+	return res
+}
+
+// Len количество корзин.
+func (l *Limiter) Len() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return len(l.buckets)
+}
+
+func (l *Limiter) cleanup() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	for key, b := range l.buckets {
+		b.refill(now, l.limit)
+		if b.tokens >= float64(l.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// duration время накопления tokens токенов.
+func (l *Limiter) duration(tokens float64) time.Duration {
+	return time.Duration(math.Ceil(tokens / l.limit.Rate * float64(time.Second)))
+}
+
+func (b *bucket) refill(now time.Time, limit Limit) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+	b.last = now
+}
@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	now := time.Now()
+	l := NewLimiter(ctx, Limit{Rate: 1, Burst: 2})
+	l.now = func() time.Time { return now }
+
+	assert.True(t, l.Allow("user").Allowed)
+	res := l.Allow("user")
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, 2, res.Limit)
+
+	res = l.Allow("user")
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 2*time.Second, res.Reset)
+
+	// корзины разных ключей независимы
+	assert.True(t, l.Allow("other").Allowed)
+
+	now = now.Add(500 * time.Millisecond)
+	res = l.Allow("user")
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+
+	now = now.Add(500 * time.Millisecond)
+	assert.True(t, l.Allow("user").Allowed)
+
+	// заполненные корзины удаляются
+	now = now.Add(10 * time.Second)
+	l.cleanup()
+	assert.Equal(t, 0, l.Len())
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	assert.Nil(t, NewLimiter(context.Background(), Limit{}))
+}
@@ -8,51 +8,89 @@ import (
 	"github.com/go-chi/chi/v5"
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
 
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/handlers"
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/http/middleware/ratelimit"
 )
 
 // NewRouter создает новый роутер.
 func NewRouter(
 	h *handlers.Handlers,
 	m *middleware.Middleware,
+	limiters ratelimit.Limiters,
 ) *chi.Mux {
 
 	router := chi.NewRouter()
 
 	router.Use(
 		chiMiddleware.Recoverer,
+		m.RealIP,
 		chiMiddleware.URLFormat,
 		m.NewCompressHandler([]string{
 			"application/json",
 			"text/html",
 		}),
-		m.Auth,
-		m.Logger,
 	)
 
 	router.NotFound(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 	})
-	router.Get("/ping", h.PingHandler)
-	router.Get("/{id}", h.RedirectHandler)
-	router.Post("/", h.SaveHandler)
-	router.Post("/api/shorten", h.SaveJSONHandler)
-	router.Post("/api/shorten/batch", h.BatchHandler)
-	router.Get("/api/user/urls", h.UserUrlsHandler)
-	router.Delete("/api/user/urls", h.DeleteURLS)
-
-	// Регистрация pprof-обработчиков
-	router.HandleFunc("/debug/pprof/", pprof.Index)
-	router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
-	router.HandleFunc("/debug/pprof/profile", pprof.Profile)
-	router.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
-	router.HandleFunc("/debug/pprof/trace", pprof.Trace)
-
-	router.Handle("/debug/pprof/block", pprof.Handler("block"))
-	router.Handle("/debug/pprof/goroutine", pprof.Handler("goroutine"))
-	router.Handle("/debug/pprof/heap", pprof.Handler("heap"))
-	router.Handle("/debug/pprof/threadcreate", pprof.Handler("threadcreate"))
+
+	// Ручки, которым нужен существующий пользователь
+	router.Group(func(r chi.Router) {
+		r.Use(m.StrictAuth, m.Logger)
+
+		r.With(m.RequireScope(models.ScopeRead)).Get("/api/user/urls", h.UserUrlsHandler)
+		r.With(m.RequireScope(models.ScopeDelete)).Delete("/api/user/urls", h.DeleteURLS)
+		r.With(m.RequireScope(models.ScopeDelete)).Post("/api/user/urls/restore", h.RestoreURLSHandler)
+		r.With(m.RequireScope(models.ScopeShorten)).Patch("/api/user/urls/{id}", h.UpdateURLHandler)
+		r.With(m.RequireScope(models.ScopeRead)).Get("/api/user/urls/{id}/history", h.URLHistoryHandler)
+		r.With(m.RequireScope(models.ScopeRead)).Get("/api/user/tags", h.ListTagsHandler)
+
+		// Ключами API нельзя управлять с помощью ключа API
+		r.With(m.DenyAPIKey).Route("/api/user/keys", func(r chi.Router) {
+			r.Post("/", h.CreateAPIKeyHandler)
+			r.Get("/", h.ListAPIKeysHandler)
+			r.Delete("/{id}", h.RevokeAPIKeyHandler)
+		})
+	})
+
+	router.Group(func(r chi.Router) {
+		r.Use(m.Auth, m.Logger)
+
+		r.Get("/ping", h.PingHandler)
+		r.With(m.RateLimit(limiters.Redirect)).Get("/{id}", h.RedirectHandler)
+
+		r.Group(func(r chi.Router) {
+			r.Use(m.RequireScope(models.ScopeShorten))
+
+			r.With(m.RateLimit(limiters.Create)).Post("/", h.SaveHandler)
+			r.With(m.RateLimit(limiters.Create)).Post("/api/shorten", h.SaveJSONHandler)
+			r.With(m.RateLimit(limiters.Batch)).Post("/api/shorten/batch", h.BatchHandler)
+		})
+
+		r.With(m.RateLimitIP(limiters.Auth)).Post("/api/user/register", h.RegisterHandler)
+		r.With(m.RateLimitIP(limiters.Auth)).Post("/api/user/login", h.LoginHandler)
+		r.With(m.RequireScope(models.ScopeRead)).Get("/api/user/urls/{id}/stats", h.URLStatsHandler)
+		r.With(m.TrustedSubnet).Route("/api/internal", func(r chi.Router) {
+			r.Get("/stats", h.InternalStatsHandler)
+			r.Post("/urls/block", h.BlockURLSHandler)
+			r.Delete("/urls/block", h.UnblockURLSHandler)
+		})
+
+		// Регистрация pprof-обработчиков
+		r.HandleFunc("/debug/pprof/", pprof.Index)
+		r.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		r.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		r.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		r.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+		r.Handle("/debug/pprof/block", pprof.Handler("block"))
+		r.Handle("/debug/pprof/goroutine", pprof.Handler("goroutine"))
+		r.Handle("/debug/pprof/heap", pprof.Handler("heap"))
+		r.Handle("/debug/pprof/threadcreate", pprof.Handler("threadcreate"))
+	})
 
 	return router
 }
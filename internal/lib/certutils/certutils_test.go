@@ -0,0 +1,30 @@
+package certutils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSelfSigned(t *testing.T) {
+	certPEM, keyPEM, err := GenerateSelfSigned([]string{"localhost", "127.0.0.1"}, time.Hour)
+	require.NoError(t, err)
+
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	require.NoError(t, err)
+
+	assert.Equal(t, x509.ECDSA, cert.PublicKeyAlgorithm)
+	assert.Equal(t, []string{"localhost"}, cert.DNSNames)
+	require.Len(t, cert.IPAddresses, 1)
+	assert.True(t, cert.IPAddresses[0].Equal(net.ParseIP("127.0.0.1")))
+	assert.NoError(t, cert.VerifyHostname("localhost"))
+	assert.True(t, cert.NotAfter.After(time.Now()))
+}
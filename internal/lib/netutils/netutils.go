@@ -0,0 +1,80 @@
+// netutils отвечает за работу с сетевыми адресами
+package netutils
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RemoteIP адрес клиента из RemoteAddr.
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// RealIP адрес клиента из заголовка X-Real-IP, если запрос
+// пришел от доверенного прокси из proxies, иначе из RemoteAddr.
+func RealIP(r *http.Request, proxies []*net.IPNet) string {
+	remote := RemoteIP(r)
+	if !InSubnets(proxies, remote) {
+		return remote
+	}
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(ip) != nil {
+		return ip
+	}
+	return remote
+}
+
+// ParseSubnet разбирает подсеть в формате CIDR.
+// Пустая строка - подсеть не задана, возвращается nil.
+func ParseSubnet(cidr string) (*net.IPNet, error) {
+	if len(cidr) == 0 {
+		return nil, nil
+	}
+	_, subnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted subnet %q: %w", cidr, err)
+	}
+	return subnet, nil
+}
+
+// InSubnet проверяет, входит ли ip в подсеть.
+// Если подсеть не задана, адрес не входит никуда.
+func InSubnet(subnet *net.IPNet, ip string) bool {
+	if subnet == nil {
+		return false
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	return subnet.Contains(parsed)
+}
+
+// ParseSubnets разбирает список подсетей в формате CIDR.
+func ParseSubnets(cidrs []string) ([]*net.IPNet, error) {
+	subnets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, subnet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid subnet %q: %w", cidr, err)
+		}
+		subnets = append(subnets, subnet)
+	}
+	return subnets, nil
+}
+
+// InSubnets проверяет, входит ли ip хотя бы в одну из подсетей.
+func InSubnets(subnets []*net.IPNet, ip string) bool {
+	for _, subnet := range subnets {
+		if InSubnet(subnet, ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package netutils
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRealIP(t *testing.T) {
+	proxies, err := ParseSubnets([]string{"10.0.0.0/24"})
+	require.NoError(t, err)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.0.0.7:53211"
+	assert.Equal(t, "10.0.0.7", RemoteIP(r))
+	assert.Equal(t, "10.0.0.7", RealIP(r, proxies))
+
+	r.Header.Set("X-Real-IP", "192.168.1.15")
+	assert.Equal(t, "192.168.1.15", RealIP(r, proxies))
+	assert.Equal(t, "10.0.0.7", RemoteIP(r))
+
+	// заголовок недоверенного клиента игнорируется
+	assert.Equal(t, "10.0.0.7", RealIP(r, nil))
+	r.RemoteAddr = "203.0.113.5:53211"
+	assert.Equal(t, "203.0.113.5", RealIP(r, proxies))
+
+	r.RemoteAddr = "10.0.0.7:53211"
+	r.Header.Set("X-Real-IP", "not-an-ip")
+	assert.Equal(t, "10.0.0.7", RealIP(r, proxies))
+}
+
+func TestInSubnet(t *testing.T) {
+	tests := []struct {
+		name     string
+		cidr     string
+		ip       string
+		expected bool
+	}{
+		{
+			name:     "ip in subnet",
+			cidr:     "192.168.1.0/24",
+			ip:       "192.168.1.15",
+			expected: true,
+		},
+		{
+			name: "ip out of subnet",
+			cidr: "192.168.1.0/24",
+			ip:   "192.168.2.15",
+		},
+		{
+			name: "empty subnet denies everything",
+			ip:   "192.168.1.15",
+		},
+		{
+			name: "invalid ip",
+			cidr: "192.168.1.0/24",
+			ip:   "not-an-ip",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			subnet, err := ParseSubnet(tt.cidr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, InSubnet(subnet, tt.ip))
+		})
+	}
+}
+
+func TestParseSubnetInvalid(t *testing.T) {
+	_, err := ParseSubnet("192.168.1.0")
+	assert.Error(t, err)
+}
+
+func TestParseSubnets(t *testing.T) {
+	subnets, err := ParseSubnets([]string{"10.0.0.0/8", " 192.168.1.0/24"})
+	require.NoError(t, err)
+	assert.True(t, InSubnets(subnets, "192.168.1.15"))
+	assert.False(t, InSubnets(subnets, "172.16.0.1"))
+	assert.False(t, InSubnets(nil, "10.0.0.1"))
+
+	_, err = ParseSubnets([]string{"10.0.0.1"})
+	assert.Error(t, err)
+}
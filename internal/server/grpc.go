@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// GRPCServer хранит информацию о gRPC сервере.
+type GRPCServer struct {
+	log    *zap.Logger
+	server *grpc.Server
+	addr   string
+}
+
+// NewGRPCServer новый gRPC сервер, слушающий addr.
+//
+//	NewGRPCServer(zap.L(), grpc.NewServer(), ":3200")
+func NewGRPCServer(
+	log *zap.Logger,
+	srv *grpc.Server,
+	addr string,
+) *GRPCServer {
+	return &GRPCServer{
+		log:    log,
+		server: srv,
+		addr:   addr,
+	}
+}
+
+// Run запускает сервер.
+func (gs *GRPCServer) Run() error {
+	gs.log.Info("running")
+
+	lis, err := net.Listen("tcp", gs.addr)
+	if err != nil {
+		gs.log.Error("failed to listen", zap.Error(err))
+		return err
+	}
+
+	if err := gs.server.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		gs.log.Error(
+			"failed to stopped",
+			zap.Error(err),
+		)
+		return err
+	}
+
+	gs.log.Warn("stopped")
+	return nil
+}
+
+// Stop graceful shutdown сервера.
+// Если ctx истекает раньше, незавершенные вызовы прерываются.
+func (gs *GRPCServer) Stop(ctx context.Context) error {
+	gs.log.Info("stopping...")
+
+	done := make(chan struct{})
+	go func() {
+		gs.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		gs.server.Stop()
+		return ctx.Err()
+	}
+}
@@ -3,16 +3,28 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"time"
 
 	"go.uber.org/zap"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/lib/certutils"
 )
 
+// selfSignedValidFor срок действия самоподписанного сертификата.
+const selfSignedValidFor = 365 * 24 * time.Hour
+
 // HTTPServer хранит информацию о http сервере.
 type HTTPServer struct {
 	log    *zap.Logger
 	server *http.Server
+	// https сервер запускается через ListenAndServeTLS,
+	// сертификат загружен в server.TLSConfig.
+	https bool
 }
 
 // NewHTTPServer новый http-сервер.
@@ -28,10 +40,75 @@ func NewHTTPServer(
 	}
 }
 
+// NewHTTPSServer новый https-сервер.
+// Сертификат загружается сразу, ошибка в файлах видна до запуска.
+// Если файлы сертификата и ключа не заданы,
+// в памяти выпускается самоподписанный сертификат.
+//
+//	NewHTTPSServer(zap.L(),&http.Server{Addr:":8443"}, "", "")
+func NewHTTPSServer(
+	log *zap.Logger,
+	srv *http.Server,
+	certFile string,
+	keyFile string,
+) (*HTTPServer, error) {
+	if (len(certFile) == 0) != (len(keyFile) == 0) {
+		return nil, fmt.Errorf("both certificate and key files must be set")
+	}
+
+	var cert tls.Certificate
+	if len(certFile) == 0 {
+		certPEM, keyPEM, err := certutils.GenerateSelfSigned(hostsFromAddr(srv.Addr), selfSignedValidFor)
+		if err != nil {
+			return nil, err
+		}
+
+		cert, err = tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load self-signed certificate: %w", err)
+		}
+
+		log.Warn("using self-signed certificate")
+	} else {
+		var err error
+		cert, err = tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load certificate: %w", err)
+		}
+	}
+
+	if srv.TLSConfig == nil {
+		srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	srv.TLSConfig.Certificates = []tls.Certificate{cert}
+
+	return &HTTPServer{
+		log:    log,
+		server: srv,
+		https:  true,
+	}, nil
+}
+
+// TLSConfig копия TLS конфигурации https сервера, nil для http.
+// Позволяет gRPC серверу использовать тот же сертификат.
+func (hs *HTTPServer) TLSConfig() *tls.Config {
+	if !hs.https {
+		return nil
+	}
+	return hs.server.TLSConfig.Clone()
+}
+
 // Run запускает сервер.
 func (hs *HTTPServer) Run() error {
-	hs.log.Info("running")
-	err := hs.server.ListenAndServe()
+	hs.log.Info("running", zap.Bool("https", hs.https))
+
+	var err error
+	if hs.https {
+		err = hs.server.ListenAndServeTLS("", "")
+	} else {
+		err = hs.server.ListenAndServe()
+	}
+
 	if errors.Is(err, http.ErrServerClosed) {
 		hs.log.Warn("stopped")
 		return nil
@@ -49,3 +126,20 @@ func (hs *HTTPServer) Stop(ctx context.Context) error {
 	hs.log.Info("stopping...")
 	return hs.server.Shutdown(ctx)
 }
+
+// hostsFromAddr имена, на которые выпускается самоподписанный сертификат.
+func hostsFromAddr(addr string) []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || len(host) == 0 {
+		return hosts
+	}
+
+	for _, h := range hosts {
+		if h == host {
+			return hosts
+		}
+	}
+	return append(hosts, host)
+}
@@ -2,14 +2,22 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap/zaptest"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
 )
 
 func TestHTTPServer(t *testing.T) {
@@ -43,3 +51,70 @@ func TestHTTPServer(t *testing.T) {
 	assert.NoError(t, errgroup.Wait())
 
 }
+
+func TestHTTPSServerSelfSigned(t *testing.T) {
+	srv, err := NewHTTPSServer(
+		zaptest.NewLogger(t),
+		&http.Server{Addr: "127.0.0.1:0"},
+		"",
+		"",
+	)
+	assert.NoError(t, err)
+	assert.Len(t, srv.server.TLSConfig.Certificates, 1)
+	assert.Len(t, srv.TLSConfig().Certificates, 1)
+	assert.Nil(t, NewHTTPServer(zaptest.NewLogger(t), &http.Server{}).TLSConfig())
+
+	_, err = NewHTTPSServer(
+		zaptest.NewLogger(t),
+		&http.Server{Addr: "127.0.0.1:0"},
+		"cert.pem",
+		"",
+	)
+	assert.Error(t, err)
+
+	// файлы загружаются при создании, а не при запуске
+	_, err = NewHTTPSServer(
+		zaptest.NewLogger(t),
+		&http.Server{Addr: "127.0.0.1:0"},
+		"missing-cert.pem",
+		"missing-key.pem",
+	)
+	assert.Error(t, err)
+}
+
+func TestGRPCServerTLS(t *testing.T) {
+	https, err := NewHTTPSServer(zaptest.NewLogger(t), &http.Server{Addr: "127.0.0.1:0"}, "", "")
+	require.NoError(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	srv := NewGRPCServer(
+		zaptest.NewLogger(t),
+		grpc.NewServer(grpc.Creds(credentials.NewTLS(https.TLSConfig()))),
+		addr,
+	)
+
+	errgroup := errgroup.Group{}
+	errgroup.Go(srv.Run)
+	defer func() {
+		assert.NoError(t, srv.Stop(context.Background()))
+		assert.NoError(t, errgroup.Wait())
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, addr,
+		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{InsecureSkipVerify: true})),
+		grpc.WithBlock(),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// рукопожатие TLS прошло, сервер ответил на вызов
+	err = conn.Invoke(ctx, "/shortener.ShortenerService/Unknown", &emptypb.Empty{}, &emptypb.Empty{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
@@ -0,0 +1,64 @@
+package urlhandler
+
+import (
+	"fmt"
+	"strings"
+
+	dbkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/db-keeper"
+)
+
+// Ограничения на пользовательские алиасы по умолчанию
+const (
+	DefaultAliasCharset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-_"
+	DefaultAliasMinLen  = 3
+	DefaultAliasMaxLen  = dbkeeper.MaxShortURLLen
+)
+
+// DefaultAliasReserved алиасы, совпадающие с путями сервиса.
+var DefaultAliasReserved = []string{"api", "ping", "debug"}
+
+// AliasRules ограничения на пользовательские алиасы.
+// Нулевые значения заменяются значениями по умолчанию.
+type AliasRules struct {
+	Charset  string
+	MinLen   int
+	MaxLen   int
+	Reserved []string
+}
+
+func (r AliasRules) withDefaults() AliasRules {
+	if len(r.Charset) == 0 {
+		r.Charset = DefaultAliasCharset
+	}
+	if r.MinLen < 1 {
+		r.MinLen = DefaultAliasMinLen
+	}
+	if r.MaxLen < 1 {
+		r.MaxLen = DefaultAliasMaxLen
+	}
+	if r.Reserved == nil {
+		r.Reserved = DefaultAliasReserved
+	}
+	return r
+}
+
+// Validate проверяет алиас на соответствие ограничениям.
+func (r AliasRules) Validate(alias string) error {
+	if len(alias) < r.MinLen || len(alias) > r.MaxLen {
+		return fmt.Errorf("%w: length must be between %d and %d", ErrInvalidAlias, r.MinLen, r.MaxLen)
+	}
+
+	for _, c := range alias {
+		if !strings.ContainsRune(r.Charset, c) {
+			return fmt.Errorf("%w: character %q is not allowed", ErrInvalidAlias, c)
+		}
+	}
+
+	for _, word := range r.Reserved {
+		if strings.EqualFold(alias, word) {
+			return fmt.Errorf("%w: %q is reserved", ErrInvalidAlias, alias)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package urlhandler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/lib/cryptoutils"
+	dbkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/db-keeper"
+)
+
+// Параметры ключей API
+const (
+	// APIKeyPrefix префикс ключа, по нему ключ легко найти в логах и конфигах.
+	APIKeyPrefix = "sk_"
+	apiKeyLen    = 40
+	apiKeyIDLen  = 12
+	maxKeyName   = 255
+	// apiKeyTouchInterval время последнего использования обновляется не чаще.
+	apiKeyTouchInterval = time.Minute
+)
+
+// Ошибки ключей API
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrInvalidAPIKey  = errors.New("invalid api key")
+	ErrInvalidScope   = errors.New("invalid api key scope")
+)
+
+var knownScopes = map[string]bool{
+	models.ScopeShorten: true,
+	models.ScopeRead:    true,
+	models.ScopeDelete:  true,
+}
+
+// CreateAPIKey создает ключ API пользователя.
+// Ключ возвращается один раз, в хранилище попадает только его хеш.
+func (uh *URLHandler) CreateAPIKey(
+	ctx context.Context,
+	userID string,
+	req models.APIKeyRequest,
+) (models.CreatedAPIKey, error) {
+	name := strings.TrimSpace(req.Name)
+	if len(name) == 0 || len(name) > maxKeyName {
+		return models.CreatedAPIKey{}, fmt.Errorf("%w: name length must be between 1 and %d", ErrInvalidAPIKey, maxKeyName)
+	}
+
+	scopes := make([]string, 0, len(req.Scopes))
+	seen := make(map[string]bool, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if !knownScopes[scope] {
+			return models.CreatedAPIKey{}, fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	id, err := cryptoutils.GenerateRandomString(apiKeyIDLen)
+	if err != nil {
+		return models.CreatedAPIKey{}, err
+	}
+	secret, err := cryptoutils.GenerateRandomString(apiKeyLen)
+	if err != nil {
+		return models.CreatedAPIKey{}, err
+	}
+	key := APIKeyPrefix + secret
+
+	apiKey := models.APIKey{
+		ID:        id,
+		UserID:    userID,
+		Name:      name,
+		KeyHash:   hashAPIKey(key),
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := uh.storage.CreateAPIKey(ctx, apiKey); err != nil {
+		return models.CreatedAPIKey{}, fmt.Errorf("failed to save api key: %w", err)
+	}
+
+	return models.CreatedAPIKey{
+		APIKeyInfo: apiKeyInfo(apiKey),
+		Key:        key,
+	}, nil
+}
+
+// ListAPIKeys ключи API пользователя без секретов.
+func (uh *URLHandler) ListAPIKeys(ctx context.Context, userID string) ([]models.APIKeyInfo, error) {
+	keys, err := uh.storage.GetAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read api keys: %w", err)
+	}
+
+	infos := make([]models.APIKeyInfo, 0, len(keys))
+	for _, key := range keys {
+		infos = append(infos, apiKeyInfo(key))
+	}
+	return infos, nil
+}
+
+// RevokeAPIKey отзывает ключ API пользователя.
+func (uh *URLHandler) RevokeAPIKey(ctx context.Context, userID string, id string) error {
+	if err := uh.storage.RevokeAPIKey(ctx, id, userID); err != nil {
+		if errors.Is(err, dbkeeper.ErrAPIKeyNotFound) {
+			return ErrAPIKeyNotFound
+		}
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	return nil
+}
+
+// ResolveAPIKey ключ API по значению из заголовка запроса.
+// Время последнего использования обновляется не чаще apiKeyTouchInterval.
+func (uh *URLHandler) ResolveAPIKey(ctx context.Context, key string) (models.APIKey, error) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return models.APIKey{}, ErrInvalidAPIKey
+	}
+
+	apiKey, err := uh.storage.GetAPIKeyByHash(ctx, hashAPIKey(key))
+	if err != nil {
+		if errors.Is(err, dbkeeper.ErrAPIKeyNotFound) {
+			return models.APIKey{}, ErrInvalidAPIKey
+		}
+		return models.APIKey{}, fmt.Errorf("failed to read api key: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchInterval {
+		// ошибка обновления не мешает запросу
+		if err := uh.storage.TouchAPIKey(ctx, apiKey.ID, now); err == nil {
+			apiKey.LastUsedAt = &now
+		}
+	}
+
+	return apiKey, nil
+}
+
+// hashAPIKey ключи случайные и длинные, поэтому достаточно sha256 без соли.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func apiKeyInfo(key models.APIKey) models.APIKeyInfo {
+	scopes := key.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return models.APIKeyInfo{
+		ID:         key.ID,
+		Name:       key.Name,
+		Scopes:     scopes,
+		CreatedAt:  key.CreatedAt,
+		LastUsedAt: key.LastUsedAt,
+	}
+}
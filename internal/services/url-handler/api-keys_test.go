@@ -0,0 +1,152 @@
+package urlhandler
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler/mocks"
+	dbkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/db-keeper"
+)
+
+func TestCreateAPIKey(t *testing.T) {
+
+	cases := []struct {
+		name           string
+		req            models.APIKeyRequest
+		expectedScopes []string
+		expectedIs     error
+		isCallMock     bool
+	}{
+		{
+			name:           "key with scopes",
+			req:            models.APIKeyRequest{Name: "ci", Scopes: []string{"shorten", "read", "shorten"}},
+			expectedScopes: []string{"shorten", "read"},
+			isCallMock:     true,
+		},
+		{
+			name:           "key without scopes",
+			req:            models.APIKeyRequest{Name: "ci"},
+			expectedScopes: []string{},
+			isCallMock:     true,
+		},
+		{
+			name:       "unknown scope",
+			req:        models.APIKeyRequest{Name: "ci", Scopes: []string{"admin"}},
+			expectedIs: ErrInvalidScope,
+		},
+		{
+			name:       "empty name",
+			req:        models.APIKeyRequest{Name: " "},
+			expectedIs: ErrInvalidAPIKey,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			storage := mocks.NewKeeperer(t)
+			var saved models.APIKey
+			if tc.isCallMock {
+				storage.On("CreateAPIKey", mock.Anything, mock.AnythingOfType("models.APIKey")).
+					Run(func(args mock.Arguments) {
+						saved = args.Get(1).(models.APIKey)
+					}).
+					Return(nil)
+			}
+
+			h := NewURLHandler(storage, mocks.NewDBPinger(t), nil, Option{})
+			key, err := h.CreateAPIKey(context.Background(), "user", tc.req)
+
+			if tc.expectedIs != nil {
+				assert.ErrorIs(t, err, tc.expectedIs)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(key.Key, APIKeyPrefix))
+			assert.Equal(t, tc.expectedScopes, key.Scopes)
+			assert.Equal(t, "user", saved.UserID)
+			assert.Equal(t, hashAPIKey(key.Key), saved.KeyHash)
+			assert.NotContains(t, saved.KeyHash, key.Key)
+		})
+	}
+}
+
+func TestResolveAPIKey(t *testing.T) {
+	const key = APIKeyPrefix + "secret"
+	recently := time.Now().Add(-time.Second)
+
+	cases := []struct {
+		name       string
+		key        string
+		stored     models.APIKey
+		getErr     error
+		isCallGet  bool
+		isTouch    bool
+		expectedIs error
+	}{
+		{
+			name:      "first use updates last used",
+			key:       key,
+			stored:    models.APIKey{ID: "id1", UserID: "user"},
+			isCallGet: true,
+			isTouch:   true,
+		},
+		{
+			name:      "recent use is not updated",
+			key:       key,
+			stored:    models.APIKey{ID: "id1", UserID: "user", LastUsedAt: &recently},
+			isCallGet: true,
+		},
+		{
+			name:       "unknown key",
+			key:        key,
+			getErr:     dbkeeper.ErrAPIKeyNotFound,
+			isCallGet:  true,
+			expectedIs: ErrInvalidAPIKey,
+		},
+		{
+			name:       "key without prefix",
+			key:        "secret",
+			expectedIs: ErrInvalidAPIKey,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			storage := mocks.NewKeeperer(t)
+			if tc.isCallGet {
+				storage.On("GetAPIKeyByHash", mock.Anything, hashAPIKey(tc.key)).Return(tc.stored, tc.getErr)
+			}
+			if tc.isTouch {
+				storage.On("TouchAPIKey", mock.Anything, tc.stored.ID, mock.AnythingOfType("time.Time")).Return(nil)
+			}
+
+			h := NewURLHandler(storage, mocks.NewDBPinger(t), nil, Option{})
+			apiKey, err := h.ResolveAPIKey(context.Background(), tc.key)
+
+			if tc.expectedIs != nil {
+				assert.ErrorIs(t, err, tc.expectedIs)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "user", apiKey.UserID)
+			assert.NotNil(t, apiKey.LastUsedAt)
+		})
+	}
+}
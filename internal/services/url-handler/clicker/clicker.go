@@ -0,0 +1,100 @@
+// clicker отвечает за асинхронную запись переходов по URL
+package clicker
+
+import (
+	"context"
+	"time"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+)
+
+const (
+	// batchSize размер пачки, при достижении которого переходы записываются сразу.
+	batchSize = 100
+	// flushInterval период записи неполной пачки.
+	flushInterval = 5 * time.Second
+)
+
+// Clicker копит переходы и записывает их пачками.
+type Clicker struct {
+	context  context.Context
+	clicks   chan models.Click
+	callback func(clicks []models.Click)
+}
+
+// NewClicker конструктор для Clicker.
+// bufLen размер очереди, при ее переполнении переходы отбрасываются,
+// чтобы не задерживать редирект.
+func NewClicker(ctx context.Context,
+	bufLen int,
+	callback func(clicks []models.Click),
+) *Clicker {
+	c := &Clicker{
+		context:  ctx,
+		clicks:   make(chan models.Click, bufLen),
+		callback: callback,
+	}
+
+	c.clicker()
+
+	return c
+}
+
+// AddClick добавляет переход в очередь на запись.
+// Возвращает false, если очередь переполнена или Clicker остановлен.
+func (c *Clicker) AddClick(click models.Click) bool {
+	select {
+	case <-c.context.Done():
+		return false
+	default:
+	}
+
+	select {
+	case c.clicks <- click:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Clicker) clicker() {
+	clicks := make([]models.Click, 0, batchSize)
+	go func() {
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case click := <-c.clicks:
+				clicks = append(clicks, click)
+
+				if len(clicks) >= batchSize {
+					c.callCallback(clicks)
+					clicks = clicks[:0]
+				}
+
+			case <-c.context.Done():
+				// Записываем то, что успело попасть в очередь
+			drain:
+				for {
+					select {
+					case click := <-c.clicks:
+						clicks = append(clicks, click)
+					default:
+						break drain
+					}
+				}
+				c.callCallback(clicks)
+				return
+			case <-ticker.C:
+				c.callCallback(clicks)
+				clicks = clicks[:0]
+			}
+		}
+	}()
+}
+
+func (c *Clicker) callCallback(clicks []models.Click) {
+	if len(clicks) > 0 {
+		c.callback(clicks)
+	}
+}
@@ -0,0 +1,129 @@
+package urlhandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	netURL "net/url"
+	"strings"
+	"time"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	dbkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/db-keeper"
+)
+
+// Окна статистики переходов
+const (
+	statsHourlyWindow = 24 * time.Hour
+	statsDailyWindow  = 30 * 24 * time.Hour
+)
+
+// Классы клиентов
+const (
+	UserAgentDesktop = "desktop"
+	UserAgentMobile  = "mobile"
+	UserAgentBot     = "bot"
+	UserAgentUnknown = "unknown"
+)
+
+// UnknownCountry страна, если ее не удалось определить.
+const UnknownCountry = "unknown"
+
+// CountryResolver определяет страну клиента по IP адресу.
+type CountryResolver interface {
+	Country(ip string) string
+}
+
+// NopCountryResolver заглушка, страна всегда не определена.
+type NopCountryResolver struct{}
+
+// Country всегда возвращает UnknownCountry.
+func (NopCountryResolver) Country(string) string {
+	return UnknownCountry
+}
+
+// RecordClick ставит переход в очередь на запись.
+// Не блокирует редирект: при переполнении очереди переход теряется.
+func (uh *URLHandler) RecordClick(ctx context.Context, click models.Click) {
+	if uh.clicker == nil {
+		return
+	}
+
+	select {
+	case <-ctx.Done():
+		return
+	default:
+	}
+
+	click.Referrer = referrerHost(click.Referrer)
+	click.UserAgent = classifyUserAgent(click.UserAgent)
+	click.Country = uh.countryResolver.Country(click.IP)
+	if len(click.Country) == 0 {
+		click.Country = UnknownCountry
+	}
+	click.IP = ""
+
+	uh.clicker.AddClick(click)
+}
+
+// GetStats статистика переходов по URL для его владельца.
+func (uh *URLHandler) GetStats(ctx context.Context, id string, userID string) (models.URLStats, error) {
+	now := time.Now().UTC()
+
+	stats, err := uh.storage.GetStats(
+		ctx,
+		id,
+		userID,
+		now.Add(-statsHourlyWindow).Truncate(time.Hour),
+		now.Add(-statsDailyWindow).Truncate(24*time.Hour),
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, dbkeeper.ErrNotFound):
+			return models.URLStats{}, ErrNotFound
+		case errors.Is(err, dbkeeper.ErrNotOwner):
+			return models.URLStats{}, ErrNotOwner
+		}
+		return models.URLStats{}, fmt.Errorf("failed to read stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+// referrerHost хост из заголовка Referer.
+func referrerHost(referrer string) string {
+	if len(referrer) == 0 {
+		return ""
+	}
+	u, err := netURL.Parse(referrer)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// classifyUserAgent грубая классификация клиента по User-Agent.
+func classifyUserAgent(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case len(ua) == 0:
+		return UserAgentUnknown
+	case containsAny(ua, "bot", "crawler", "spider", "curl", "wget", "python-requests", "go-http-client"):
+		return UserAgentBot
+	case containsAny(ua, "mobile", "android", "iphone", "ipad"):
+		return UserAgentMobile
+	case containsAny(ua, "mozilla", "opera"):
+		return UserAgentDesktop
+	default:
+		return UserAgentUnknown
+	}
+}
+
+func containsAny(s string, substrs ...string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package urlhandler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyUserAgent(t *testing.T) {
+	cases := []struct {
+		name      string
+		userAgent string
+		expected  string
+	}{
+		{
+			name:      "desktop browser",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36",
+			expected:  UserAgentDesktop,
+		},
+		{
+			name:      "mobile browser",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile/15E148",
+			expected:  UserAgentMobile,
+		},
+		{
+			name:      "search bot",
+			userAgent: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			expected:  UserAgentBot,
+		},
+		{
+			name:      "curl",
+			userAgent: "curl/8.4.0",
+			expected:  UserAgentBot,
+		},
+		{
+			name:     "empty",
+			expected: UserAgentUnknown,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, classifyUserAgent(tc.userAgent))
+		})
+	}
+}
+
+func TestReferrerHost(t *testing.T) {
+	assert.Equal(t, "news.ycombinator.com", referrerHost("https://News.YCombinator.com:443/item?id=1"))
+	assert.Empty(t, referrerHost(""))
+	assert.Empty(t, referrerHost("::not a url"))
+}
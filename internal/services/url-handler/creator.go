@@ -0,0 +1,29 @@
+package urlhandler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+)
+
+// MaxUserAgentLength размер колонки user_agent в БД, в символах.
+const MaxUserAgentLength = 512
+
+// creator заменяет IP создателя URL его HMAC-SHA256 хешем
+// и обрезает User-Agent до MaxUserAgentLength.
+func (uh *URLHandler) creator(c models.Creator) models.Creator {
+	if len(c.IP) > 0 {
+		mac := hmac.New(sha256.New, uh.ipHashKey)
+		mac.Write([]byte(c.IP))
+		c.IPHash = hex.EncodeToString(mac.Sum(nil))
+	}
+	c.IP = ""
+
+	if ua := []rune(c.UserAgent); len(ua) > MaxUserAgentLength {
+		c.UserAgent = string(ua[:MaxUserAgentLength])
+	}
+
+	return c
+}
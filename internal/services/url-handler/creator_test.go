@@ -0,0 +1,52 @@
+package urlhandler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler/mocks"
+)
+
+func TestCreator(t *testing.T) {
+	h := NewURLHandler(mocks.NewKeeperer(t), mocks.NewDBPinger(t), nil, Option{IPHashKey: []byte("secret")})
+	other := NewURLHandler(mocks.NewKeeperer(t), mocks.NewDBPinger(t), nil, Option{IPHashKey: []byte("other")})
+
+	c := h.creator(models.Creator{IP: "203.0.113.7", UserAgent: "curl/8.5.0"})
+	assert.Empty(t, c.IP)
+	assert.Len(t, c.IPHash, 64)
+	assert.Equal(t, "curl/8.5.0", c.UserAgent)
+
+	// хеш зависит от ключа и совпадает для одного IP
+	assert.Equal(t, c.IPHash, h.creator(models.Creator{IP: "203.0.113.7"}).IPHash)
+	assert.NotEqual(t, c.IPHash, h.creator(models.Creator{IP: "203.0.113.8"}).IPHash)
+	assert.NotEqual(t, c.IPHash, other.creator(models.Creator{IP: "203.0.113.7"}).IPHash)
+
+	assert.Empty(t, h.creator(models.Creator{}).IPHash)
+
+	long := h.creator(models.Creator{UserAgent: strings.Repeat("ю", MaxUserAgentLength+1)})
+	assert.Equal(t, strings.Repeat("ю", MaxUserAgentLength), long.UserAgent)
+}
+
+func TestSaveURLCreator(t *testing.T) {
+	storage := mocks.NewKeeperer(t)
+	h := NewURLHandler(storage, mocks.NewDBPinger(t), nil, Option{IPHashKey: []byte("secret")})
+
+	expected := h.creator(models.Creator{IP: "203.0.113.7", UserAgent: "curl/8.5.0"})
+	storage.On("PostURL", mock.Anything, models.URLRequest{
+		URL:     "https://ya.ru/",
+		Creator: expected,
+	}, "", 0).Return("promo", nil)
+
+	id, err := h.SaveURL(context.Background(), models.URLRequest{
+		URL:     "https://ya.ru/",
+		Creator: models.Creator{IP: "203.0.113.7", UserAgent: "curl/8.5.0"},
+	}, "")
+	require.NoError(t, err)
+	assert.Equal(t, "promo", id)
+}
@@ -0,0 +1,46 @@
+package urlhandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrDestinationBlocked адрес назначения запрещен политикой или администратором.
+var ErrDestinationBlocked = errors.New("destination is blocked")
+
+// DestinationPolicy политика допустимых адресов назначения.
+type DestinationPolicy interface {
+	Allowed(url string) bool
+}
+
+// AllowAllPolicy разрешает любые адреса назначения.
+type AllowAllPolicy struct{}
+
+// Allowed всегда true.
+func (AllowAllPolicy) Allowed(string) bool {
+	return true
+}
+
+// checkDestination проверяет URL в каноническом виде по политике.
+func (uh *URLHandler) checkDestination(url string) error {
+	if !uh.policy.Allowed(url) {
+		return fmt.Errorf("%w: %s", ErrDestinationBlocked, url)
+	}
+	return nil
+}
+
+// BlockURLS блокирует (blocked = true) или разблокирует существующие URL,
+// переходы по заблокированным URL запрещены.
+// Возвращает количество измененных URL.
+func (uh *URLHandler) BlockURLS(ctx context.Context, shortURLS []string, blocked bool) (int64, error) {
+	if len(shortURLS) == 0 {
+		return 0, nil
+	}
+
+	updated, err := uh.storage.BlockURLS(ctx, shortURLS, blocked)
+	if err != nil {
+		return updated, fmt.Errorf("failed to block urls: %w", err)
+	}
+	return updated, nil
+}
@@ -0,0 +1,53 @@
+package urlhandler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler/mocks"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler/policy"
+	dbkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/db-keeper"
+)
+
+func TestDestinationPolicy(t *testing.T) {
+	p, err := policy.New(policy.Rules{Suffixes: []string{"phish.io"}})
+	require.NoError(t, err)
+
+	h := NewURLHandler(mocks.NewKeeperer(t), mocks.NewDBPinger(t), nil, Option{Policy: p})
+
+	_, err = h.SaveURL(context.Background(), models.URLRequest{URL: "https://Bank.Phish.io/login"}, "")
+	assert.ErrorIs(t, err, ErrDestinationBlocked)
+
+	resp, err := h.SaveURLS(context.Background(), []models.BatchRequest{
+		{CorrelationID: "1", OriginalURL: "https://phish.io/"},
+	}, "")
+	require.NoError(t, err)
+	require.Len(t, resp, 1)
+	assert.Equal(t, models.BatchInvalid, resp[0].Status)
+	assert.Contains(t, resp[0].Reason, ErrDestinationBlocked.Error())
+}
+
+func TestBlockURLS(t *testing.T) {
+	storage := mocks.NewKeeperer(t)
+	storage.On("BlockURLS", mock.Anything, []string{"promo"}, true).Return(int64(1), nil)
+	storage.On("GetURL", mock.Anything, "promo").Return("", dbkeeper.ErrURLBlocked)
+
+	h := NewURLHandler(storage, mocks.NewDBPinger(t), nil, Option{})
+
+	updated, err := h.BlockURLS(context.Background(), []string{"promo"}, true)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, updated)
+
+	_, err = h.ReadURL(context.Background(), "promo")
+	assert.ErrorIs(t, err, ErrDestinationBlocked)
+
+	// пустой список не обращается к хранилищу
+	updated, err = h.BlockURLS(context.Background(), nil, true)
+	require.NoError(t, err)
+	assert.Zero(t, updated)
+}
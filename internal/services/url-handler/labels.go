@@ -0,0 +1,108 @@
+package urlhandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+)
+
+// Ограничения на теги и папку URL
+const (
+	MaxTags         = 20
+	MaxTagLength    = 64
+	MaxFolderLength = 255
+)
+
+// ErrInvalidLabels некорректные теги или папка.
+var ErrInvalidLabels = errors.New("invalid tags or folder")
+
+// normalizeTag тег без пробелов по краям в нижнем регистре.
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// normalizeTags приводит теги к виду для хранения:
+// без повторов, по алфавиту, nil - тегов нет.
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if err := checkLabel("tag", tag, MaxTagLength); err != nil {
+			return nil, err
+		}
+		if len(tag) == 0 {
+			return nil, fmt.Errorf("%w: tag must not be empty", ErrInvalidLabels)
+		}
+		result = append(result, tag)
+	}
+
+	slices.Sort(result)
+	result = slices.Compact(result)
+	if len(result) > MaxTags {
+		return nil, fmt.Errorf("%w: at most %d tags are allowed", ErrInvalidLabels, MaxTags)
+	}
+
+	return result, nil
+}
+
+// normalizeFolder папка без пробелов по краям, пустая - URL вне папок.
+func normalizeFolder(folder string) (string, error) {
+	folder = strings.TrimSpace(folder)
+	if err := checkLabel("folder", folder, MaxFolderLength); err != nil {
+		return "", err
+	}
+	return folder, nil
+}
+
+func checkLabel(name, value string, maxLen int) error {
+	if utf8.RuneCountInString(value) > maxLen {
+		return fmt.Errorf("%w: %s must be at most %d characters", ErrInvalidLabels, name, maxLen)
+	}
+	if strings.IndexFunc(value, unicode.IsControl) >= 0 {
+		return fmt.Errorf("%w: %s must not contain control characters", ErrInvalidLabels, name)
+	}
+	return nil
+}
+
+// updateLabels проверяет изменение тегов и папки из запроса.
+// false - теги и папка не меняются.
+func updateLabels(req models.UpdateURLRequest) (models.URLLabels, bool, error) {
+	labels := models.URLLabels{}
+
+	if req.Tags != nil {
+		tags, err := normalizeTags(*req.Tags)
+		if err != nil {
+			return labels, false, err
+		}
+		labels.Tags = &tags
+	}
+
+	if req.Folder != nil {
+		folder, err := normalizeFolder(*req.Folder)
+		if err != nil {
+			return labels, false, err
+		}
+		labels.Folder = &folder
+	}
+
+	return labels, labels.Tags != nil || labels.Folder != nil, nil
+}
+
+// ListTags теги неудаленных URL пользователя с количеством URL.
+func (uh *URLHandler) ListTags(ctx context.Context, userID string) ([]models.TagCount, error) {
+	tags, err := uh.storage.GetTags(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tags: %w", err)
+	}
+	return tags, nil
+}
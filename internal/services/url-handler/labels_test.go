@@ -0,0 +1,177 @@
+package urlhandler
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler/mocks"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tooMany := make([]string, 0, MaxTags+1)
+	for i := 0; i <= MaxTags; i++ {
+		tooMany = append(tooMany, strings.Repeat("t", i+1))
+	}
+	allowed := slices.Clone(tooMany[:MaxTags])
+	slices.Sort(allowed)
+
+	cases := []struct {
+		name     string
+		tags     []string
+		expected []string
+		isError  bool
+	}{
+		{
+			name: "no tags",
+		},
+		{
+			name:     "trimmed lowercased and deduplicated",
+			tags:     []string{" News ", "go", "news", "GO"},
+			expected: []string{"go", "news"},
+		},
+		{
+			name:     "duplicates do not count against the limit",
+			tags:     append(tooMany[:MaxTags:MaxTags], tooMany[0]),
+			expected: allowed,
+		},
+		{
+			name:    "empty tag",
+			tags:    []string{"go", " "},
+			isError: true,
+		},
+		{
+			name:    "tag too long",
+			tags:    []string{strings.Repeat("ю", MaxTagLength+1)},
+			isError: true,
+		},
+		{
+			name:    "control characters",
+			tags:    []string{"go\nnews"},
+			isError: true,
+		},
+		{
+			name:    "too many tags",
+			tags:    tooMany,
+			isError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tags, err := normalizeTags(tc.tags)
+			if tc.isError {
+				assert.ErrorIs(t, err, ErrInvalidLabels)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, tags)
+		})
+	}
+}
+
+func TestSaveURLLabels(t *testing.T) {
+	storage := mocks.NewKeeperer(t)
+	storage.On("PostURL", mock.Anything, models.URLRequest{
+		URL:    "https://ya.ru/",
+		Tags:   []string{"news", "search"},
+		Folder: "work",
+	}, "", 0).Return("promo", nil)
+
+	h := NewURLHandler(storage, mocks.NewDBPinger(t), nil, Option{})
+
+	id, err := h.SaveURL(context.Background(), models.URLRequest{
+		URL:    "https://ya.ru/",
+		Tags:   []string{"Search", "news"},
+		Folder: " work ",
+	}, "")
+	require.NoError(t, err)
+	assert.Equal(t, "promo", id)
+
+	_, err = h.SaveURL(context.Background(), models.URLRequest{
+		URL:    "https://ya.ru/",
+		Folder: strings.Repeat("f", MaxFolderLength+1),
+	}, "")
+	assert.ErrorIs(t, err, ErrInvalidLabels)
+}
+
+func TestUpdateURLLabels(t *testing.T) {
+	const userID = "a6b8b5a4-7d2f-4c36-9cf1-5b9a0c0e3d11"
+
+	tags := func(tags ...string) *[]string { return &tags }
+	folder := func(folder string) *string { return &folder }
+
+	cases := []struct {
+		name         string
+		req          models.UpdateURLRequest
+		labels       models.URLLabels
+		isCallUpdate bool
+		expectedIs   error
+	}{
+		{
+			name:   "tags only",
+			req:    models.UpdateURLRequest{Tags: tags("Go", "go")},
+			labels: models.URLLabels{Tags: tags("go")},
+		},
+		{
+			name:   "clear tags and folder",
+			req:    models.UpdateURLRequest{Tags: tags(), Folder: folder(" ")},
+			labels: models.URLLabels{Tags: tags(), Folder: folder("")},
+		},
+		{
+			name:         "original url and folder",
+			req:          models.UpdateURLRequest{OriginalURL: "https://go.dev/", Folder: folder("work")},
+			labels:       models.URLLabels{Folder: folder("work")},
+			isCallUpdate: true,
+		},
+		{
+			name:       "invalid tags",
+			req:        models.UpdateURLRequest{OriginalURL: "https://go.dev/", Tags: tags("")},
+			expectedIs: ErrInvalidLabels,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			storage := mocks.NewKeeperer(t)
+			expected := models.MassURL{ShortURL: "promo", OriginalURL: "https://go.dev/", Folder: "work"}
+			labels := mock.MatchedBy(func(labels models.URLLabels) bool {
+				return assert.ObjectsAreEqual(tc.labels.Tags, labels.Tags) &&
+					assert.ObjectsAreEqual(tc.labels.Folder, labels.Folder)
+			})
+			switch {
+			case tc.expectedIs != nil:
+			case tc.isCallUpdate:
+				// URL и метки меняются одним вызовом хранилища
+				storage.On("UpdateURL", mock.Anything, "promo", userID, "https://go.dev/", labels).Return(expected, nil)
+			default:
+				storage.On("UpdateLabels", mock.Anything, "promo", userID, labels).Return(expected, nil)
+			}
+
+			h := NewURLHandler(storage, mocks.NewDBPinger(t), nil, Option{})
+			url, err := h.UpdateURL(context.Background(), userID, "promo", tc.req)
+
+			if tc.expectedIs != nil {
+				assert.ErrorIs(t, err, tc.expectedIs)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, expected, url)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package urlhandler
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+)
+
+// MaxPageLimit максимальный размер страницы списка URL.
+const MaxPageLimit = 1000
+
+// ErrInvalidQuery некорректные параметры списка URL.
+var ErrInvalidQuery = errors.New("invalid query")
+
+// cursorData содержимое курсора списка URL.
+type cursorData struct {
+	CreatedAt time.Time `json:"t"`
+	ShortURL  string    `json:"id"`
+}
+
+// encodeCursor непрозрачный для клиента курсор.
+func encodeCursor(c models.URLCursor) (string, error) {
+	data, err := json.Marshal(cursorData{CreatedAt: c.CreatedAt, ShortURL: c.ShortURL})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(cursor string) (*models.URLCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+
+	c := cursorData{}
+	if err := json.Unmarshal(data, &c); err != nil || len(c.ShortURL) == 0 {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+
+	return &models.URLCursor{CreatedAt: c.CreatedAt, ShortURL: c.ShortURL}, nil
+}
+
+// urlQuery проверяет параметры списка и переводит их в запрос к хранилищу.
+func urlQuery(userID string, req models.URLListRequest) (models.URLQuery, error) {
+	query := models.URLQuery{
+		UserID:   userID,
+		Limit:    req.Limit,
+		Sort:     req.Sort,
+		Contains: req.Contains,
+		Deleted:  req.Deleted,
+		Tag:      normalizeTag(req.Tag),
+		Folder:   strings.TrimSpace(req.Folder),
+	}
+
+	if req.Limit < 0 || req.Limit > MaxPageLimit {
+		return query, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidQuery, MaxPageLimit)
+	}
+
+	switch req.Sort {
+	case "":
+		query.Sort = models.SortCreatedDesc
+	case models.SortCreatedAsc, models.SortCreatedDesc:
+	default:
+		return query, fmt.Errorf("%w: unknown sort %q", ErrInvalidQuery, req.Sort)
+	}
+
+	switch req.Deleted {
+	case "":
+		query.Deleted = models.DeletedExclude
+	case models.DeletedExclude, models.DeletedInclude, models.DeletedOnly:
+	default:
+		return query, fmt.Errorf("%w: unknown deleted filter %q", ErrInvalidQuery, req.Deleted)
+	}
+
+	if len(req.Cursor) > 0 {
+		after, err := decodeCursor(req.Cursor)
+		if err != nil {
+			return query, err
+		}
+		query.After = after
+	}
+
+	return query, nil
+}
+
+// GetURLS страница списка сокращенных URL пользователя.
+// Без req.Limit возвращается весь список.
+func (uh *URLHandler) GetURLS(ctx context.Context, userID string, req models.URLListRequest) (models.URLListResponse, error) {
+	query, err := urlQuery(userID, req)
+	if err != nil {
+		return models.URLListResponse{}, err
+	}
+
+	page, err := uh.storage.GetURLS(ctx, query)
+	if err != nil {
+		return models.URLListResponse{}, fmt.Errorf("failed to read urls: %w", err)
+	}
+
+	resp := models.URLListResponse{URLs: page.URLs}
+	if page.Next != nil {
+		resp.NextCursor, err = encodeCursor(*page.Next)
+		if err != nil {
+			return models.URLListResponse{}, err
+		}
+	}
+
+	return resp, nil
+}
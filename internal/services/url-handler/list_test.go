@@ -0,0 +1,109 @@
+package urlhandler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler/mocks"
+)
+
+func TestGetURLS(t *testing.T) {
+	const userID = "a6b8b5a4-7d2f-4c36-9cf1-5b9a0c0e3d11"
+
+	cursor := models.URLCursor{
+		CreatedAt: time.Date(2024, 3, 1, 10, 0, 0, 123456000, time.UTC),
+		ShortURL:  "promo",
+	}
+	encoded, err := encodeCursor(cursor)
+	require.NoError(t, err)
+
+	urls := []models.MassURL{{ShortURL: "promo", OriginalURL: "https://ya.ru/"}}
+
+	cases := []struct {
+		name           string
+		req            models.URLListRequest
+		expectedQuery  models.URLQuery
+		page           models.URLPage
+		expectedCursor string
+		isCallStorage  bool
+	}{
+		{
+			name: "defaults",
+			expectedQuery: models.URLQuery{
+				UserID:  userID,
+				Sort:    models.SortCreatedDesc,
+				Deleted: models.DeletedExclude,
+			},
+			page:          models.URLPage{URLs: urls},
+			isCallStorage: true,
+		},
+		{
+			name: "next page",
+			req: models.URLListRequest{
+				Limit:    1,
+				Cursor:   encoded,
+				Sort:     models.SortCreatedAsc,
+				Contains: "ya",
+				Deleted:  models.DeletedOnly,
+			},
+			expectedQuery: models.URLQuery{
+				UserID:   userID,
+				Limit:    1,
+				After:    &cursor,
+				Sort:     models.SortCreatedAsc,
+				Contains: "ya",
+				Deleted:  models.DeletedOnly,
+			},
+			page:           models.URLPage{URLs: urls, Next: &cursor},
+			expectedCursor: encoded,
+			isCallStorage:  true,
+		},
+		{
+			name: "limit too large",
+			req:  models.URLListRequest{Limit: MaxPageLimit + 1},
+		},
+		{
+			name: "unknown sort",
+			req:  models.URLListRequest{Sort: "original_url"},
+		},
+		{
+			name: "unknown deleted filter",
+			req:  models.URLListRequest{Deleted: "all"},
+		},
+		{
+			name: "malformed cursor",
+			req:  models.URLListRequest{Cursor: "not a cursor"},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			storage := mocks.NewKeeperer(t)
+			if tc.isCallStorage {
+				storage.On("GetURLS", mock.Anything, tc.expectedQuery).Return(tc.page, nil)
+			}
+
+			h := NewURLHandler(storage, mocks.NewDBPinger(t), nil, Option{})
+			list, err := h.GetURLS(context.Background(), userID, tc.req)
+
+			if !tc.isCallStorage {
+				assert.ErrorIs(t, err, ErrInvalidQuery)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.page.URLs, list.URLs)
+			assert.Equal(t, tc.expectedCursor, list.NextCursor)
+		})
+	}
+}
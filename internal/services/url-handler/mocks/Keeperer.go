@@ -4,6 +4,7 @@ package mocks
 
 import (
 	context "context"
+	time "time"
 
 	mock "github.com/stretchr/testify/mock"
 	models "github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
@@ -14,11 +15,259 @@ type Keeperer struct {
 	mock.Mock
 }
 
+// BlockURLS provides a mock function with given fields: ctx, shortURLS, blocked
+func (_m *Keeperer) BlockURLS(ctx context.Context, shortURLS []string, blocked bool) (int64, error) {
+	ret := _m.Called(ctx, shortURLS, blocked)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string, bool) (int64, error)); ok {
+		return rf(ctx, shortURLS, blocked)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string, bool) int64); ok {
+		r0 = rf(ctx, shortURLS, blocked)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string, bool) error); ok {
+		r1 = rf(ctx, shortURLS, blocked)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ClaimURLS provides a mock function with given fields: ctx, fromUserID, toUserID, maxLinks
+func (_m *Keeperer) ClaimURLS(ctx context.Context, fromUserID string, toUserID string, maxLinks int) (int64, error) {
+	ret := _m.Called(ctx, fromUserID, toUserID, maxLinks)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int) (int64, error)); ok {
+		return rf(ctx, fromUserID, toUserID, maxLinks)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int) int64); ok {
+		r0 = rf(ctx, fromUserID, toUserID, maxLinks)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, int) error); ok {
+		r1 = rf(ctx, fromUserID, toUserID, maxLinks)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountURLS provides a mock function with given fields: ctx
+func (_m *Keeperer) CountURLS(ctx context.Context) (models.URLCounts, error) {
+	ret := _m.Called(ctx)
+
+	var r0 models.URLCounts
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (models.URLCounts, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) models.URLCounts); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(models.URLCounts)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountUsers provides a mock function with given fields: ctx
+func (_m *Keeperer) CountUsers(ctx context.Context) (int64, error) {
+	ret := _m.Called(ctx)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (int64, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) int64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateAPIKey provides a mock function with given fields: ctx, key
+func (_m *Keeperer) CreateAPIKey(ctx context.Context, key models.APIKey) error {
+	ret := _m.Called(ctx, key)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.APIKey) error); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateUser provides a mock function with given fields: ctx, user
+func (_m *Keeperer) CreateUser(ctx context.Context, user models.User) error {
+	ret := _m.Called(ctx, user)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.User) error); ok {
+		r0 = rf(ctx, user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteExpired provides a mock function with given fields: ctx, limit
+func (_m *Keeperer) DeleteExpired(ctx context.Context, limit int) (int64, error) {
+	ret := _m.Called(ctx, limit)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (int64, error)); ok {
+		return rf(ctx, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) int64); ok {
+		r0 = rf(ctx, limit)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DeleteURLS provides a mock function with given fields: ctx, shortURLS
 func (_m *Keeperer) DeleteURLS(ctx context.Context, shortURLS []models.DeleteURL) {
 	_m.Called(ctx, shortURLS)
 }
 
+// GetAPIKeyByHash provides a mock function with given fields: ctx, keyHash
+func (_m *Keeperer) GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error) {
+	ret := _m.Called(ctx, keyHash)
+
+	var r0 models.APIKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (models.APIKey, error)); ok {
+		return rf(ctx, keyHash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.APIKey); ok {
+		r0 = rf(ctx, keyHash)
+	} else {
+		r0 = ret.Get(0).(models.APIKey)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, keyHash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAPIKeys provides a mock function with given fields: ctx, userID
+func (_m *Keeperer) GetAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []models.APIKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]models.APIKey, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []models.APIKey); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.APIKey)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetStats provides a mock function with given fields: ctx, shortURL, userID, hourlyFrom, dailyFrom
+func (_m *Keeperer) GetStats(ctx context.Context, shortURL string, userID string, hourlyFrom time.Time, dailyFrom time.Time) (models.URLStats, error) {
+	ret := _m.Called(ctx, shortURL, userID, hourlyFrom, dailyFrom)
+
+	var r0 models.URLStats
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time, time.Time) (models.URLStats, error)); ok {
+		return rf(ctx, shortURL, userID, hourlyFrom, dailyFrom)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, time.Time, time.Time) models.URLStats); ok {
+		r0 = rf(ctx, shortURL, userID, hourlyFrom, dailyFrom)
+	} else {
+		r0 = ret.Get(0).(models.URLStats)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, time.Time, time.Time) error); ok {
+		r1 = rf(ctx, shortURL, userID, hourlyFrom, dailyFrom)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTags provides a mock function with given fields: ctx, userID
+func (_m *Keeperer) GetTags(ctx context.Context, userID string) ([]models.TagCount, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []models.TagCount
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]models.TagCount, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []models.TagCount); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.TagCount)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetURL provides a mock function with given fields: ctx, id
 func (_m *Keeperer) GetURL(ctx context.Context, id string) (string, error) {
 	ret := _m.Called(ctx, id)
@@ -43,25 +292,73 @@ func (_m *Keeperer) GetURL(ctx context.Context, id string) (string, error) {
 	return r0, r1
 }
 
-// GetURLS provides a mock function with given fields: ctx, userID
-func (_m *Keeperer) GetURLS(ctx context.Context, userID string) ([]models.MassURL, error) {
-	ret := _m.Called(ctx, userID)
+// GetURLHistory provides a mock function with given fields: ctx, shortURL, userID
+func (_m *Keeperer) GetURLHistory(ctx context.Context, shortURL string, userID string) ([]models.URLRevision, error) {
+	ret := _m.Called(ctx, shortURL, userID)
 
-	var r0 []models.MassURL
+	var r0 []models.URLRevision
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) ([]models.MassURL, error)); ok {
-		return rf(ctx, userID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) ([]models.URLRevision, error)); ok {
+		return rf(ctx, shortURL, userID)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) []models.MassURL); ok {
-		r0 = rf(ctx, userID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []models.URLRevision); ok {
+		r0 = rf(ctx, shortURL, userID)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]models.MassURL)
+			r0 = ret.Get(0).([]models.URLRevision)
 		}
 	}
 
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, shortURL, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetURLS provides a mock function with given fields: ctx, query
+func (_m *Keeperer) GetURLS(ctx context.Context, query models.URLQuery) (models.URLPage, error) {
+	ret := _m.Called(ctx, query)
+
+	var r0 models.URLPage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.URLQuery) (models.URLPage, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.URLQuery) models.URLPage); ok {
+		r0 = rf(ctx, query)
+	} else {
+		r0 = ret.Get(0).(models.URLPage)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.URLQuery) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserByLogin provides a mock function with given fields: ctx, login
+func (_m *Keeperer) GetUserByLogin(ctx context.Context, login string) (models.User, error) {
+	ret := _m.Called(ctx, login)
+
+	var r0 models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (models.User, error)); ok {
+		return rf(ctx, login)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.User); ok {
+		r0 = rf(ctx, login)
+	} else {
+		r0 = ret.Get(0).(models.User)
+	}
+
 	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, userID)
+		r1 = rf(ctx, login)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -69,23 +366,47 @@ func (_m *Keeperer) GetURLS(ctx context.Context, userID string) ([]models.MassUR
 	return r0, r1
 }
 
-// PostURL provides a mock function with given fields: ctx, url, userID
-func (_m *Keeperer) PostURL(ctx context.Context, url string, userID string) (string, error) {
-	ret := _m.Called(ctx, url, userID)
+// PostURL provides a mock function with given fields: ctx, req, userID, maxLinks
+func (_m *Keeperer) PostURL(ctx context.Context, req models.URLRequest, userID string, maxLinks int) (string, error) {
+	ret := _m.Called(ctx, req, userID, maxLinks)
 
 	var r0 string
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) (string, error)); ok {
-		return rf(ctx, url, userID)
+	if rf, ok := ret.Get(0).(func(context.Context, models.URLRequest, string, int) (string, error)); ok {
+		return rf(ctx, req, userID, maxLinks)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) string); ok {
-		r0 = rf(ctx, url, userID)
+	if rf, ok := ret.Get(0).(func(context.Context, models.URLRequest, string, int) string); ok {
+		r0 = rf(ctx, req, userID, maxLinks)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
-		r1 = rf(ctx, url, userID)
+	if rf, ok := ret.Get(1).(func(context.Context, models.URLRequest, string, int) error); ok {
+		r1 = rf(ctx, req, userID, maxLinks)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PurgeDeleted provides a mock function with given fields: ctx, deletedBefore, limit
+func (_m *Keeperer) PurgeDeleted(ctx context.Context, deletedBefore time.Time, limit int) (int64, error) {
+	ret := _m.Called(ctx, deletedBefore, limit)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) (int64, error)); ok {
+		return rf(ctx, deletedBefore, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) int64); ok {
+		r0 = rf(ctx, deletedBefore, limit)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, int) error); ok {
+		r1 = rf(ctx, deletedBefore, limit)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -93,25 +414,132 @@ func (_m *Keeperer) PostURL(ctx context.Context, url string, userID string) (str
 	return r0, r1
 }
 
-// SaveURLS provides a mock function with given fields: ctx, urls, userID
-func (_m *Keeperer) SaveURLS(ctx context.Context, urls []models.BatchRequest, userID string) ([]models.BatchResponse, error) {
-	ret := _m.Called(ctx, urls, userID)
+// RestoreURLS provides a mock function with given fields: ctx, shortURLS, userID, deletedAfter, maxLinks
+func (_m *Keeperer) RestoreURLS(ctx context.Context, shortURLS []string, userID string, deletedAfter time.Time, maxLinks int) ([]string, error) {
+	ret := _m.Called(ctx, shortURLS, userID, deletedAfter, maxLinks)
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string, string, time.Time, int) ([]string, error)); ok {
+		return rf(ctx, shortURLS, userID, deletedAfter, maxLinks)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string, string, time.Time, int) []string); ok {
+		r0 = rf(ctx, shortURLS, userID, deletedAfter, maxLinks)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string, string, time.Time, int) error); ok {
+		r1 = rf(ctx, shortURLS, userID, deletedAfter, maxLinks)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RevokeAPIKey provides a mock function with given fields: ctx, id, userID
+func (_m *Keeperer) RevokeAPIKey(ctx context.Context, id string, userID string) error {
+	ret := _m.Called(ctx, id, userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, id, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveClicks provides a mock function with given fields: ctx, clicks
+func (_m *Keeperer) SaveClicks(ctx context.Context, clicks []models.Click) {
+	_m.Called(ctx, clicks)
+}
+
+// SaveURLS provides a mock function with given fields: ctx, urls, userID, maxLinks
+func (_m *Keeperer) SaveURLS(ctx context.Context, urls []models.BatchRequest, userID string, maxLinks int) ([]models.BatchResponse, error) {
+	ret := _m.Called(ctx, urls, userID, maxLinks)
 
 	var r0 []models.BatchResponse
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, []models.BatchRequest, string) ([]models.BatchResponse, error)); ok {
-		return rf(ctx, urls, userID)
+	if rf, ok := ret.Get(0).(func(context.Context, []models.BatchRequest, string, int) ([]models.BatchResponse, error)); ok {
+		return rf(ctx, urls, userID, maxLinks)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, []models.BatchRequest, string) []models.BatchResponse); ok {
-		r0 = rf(ctx, urls, userID)
+	if rf, ok := ret.Get(0).(func(context.Context, []models.BatchRequest, string, int) []models.BatchResponse); ok {
+		r0 = rf(ctx, urls, userID, maxLinks)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]models.BatchResponse)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, []models.BatchRequest, string) error); ok {
-		r1 = rf(ctx, urls, userID)
+	if rf, ok := ret.Get(1).(func(context.Context, []models.BatchRequest, string, int) error); ok {
+		r1 = rf(ctx, urls, userID, maxLinks)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// TouchAPIKey provides a mock function with given fields: ctx, id, usedAt
+func (_m *Keeperer) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	ret := _m.Called(ctx, id, usedAt)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) error); ok {
+		r0 = rf(ctx, id, usedAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateLabels provides a mock function with given fields: ctx, shortURL, userID, labels
+func (_m *Keeperer) UpdateLabels(ctx context.Context, shortURL string, userID string, labels models.URLLabels) (models.MassURL, error) {
+	ret := _m.Called(ctx, shortURL, userID, labels)
+
+	var r0 models.MassURL
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, models.URLLabels) (models.MassURL, error)); ok {
+		return rf(ctx, shortURL, userID, labels)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, models.URLLabels) models.MassURL); ok {
+		r0 = rf(ctx, shortURL, userID, labels)
+	} else {
+		r0 = ret.Get(0).(models.MassURL)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, models.URLLabels) error); ok {
+		r1 = rf(ctx, shortURL, userID, labels)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateURL provides a mock function with given fields: ctx, shortURL, userID, originalURL, labels
+func (_m *Keeperer) UpdateURL(ctx context.Context, shortURL string, userID string, originalURL string, labels models.URLLabels) (models.MassURL, error) {
+	ret := _m.Called(ctx, shortURL, userID, originalURL, labels)
+
+	var r0 models.MassURL
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, models.URLLabels) (models.MassURL, error)); ok {
+		return rf(ctx, shortURL, userID, originalURL, labels)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, models.URLLabels) models.MassURL); ok {
+		r0 = rf(ctx, shortURL, userID, originalURL, labels)
+	} else {
+		r0 = ret.Get(0).(models.MassURL)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, models.URLLabels) error); ok {
+		r1 = rf(ctx, shortURL, userID, originalURL, labels)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -0,0 +1,131 @@
+package urlhandler
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	netURL "net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// ErrInvalidURL URL не прошел проверку.
+var ErrInvalidURL = errors.New("invalid url")
+
+// DefaultURLSchemes разрешенные схемы по умолчанию.
+var DefaultURLSchemes = []string{"http", "https"}
+
+// defaultPorts порты, которые удаляются из адреса для схемы.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// trackingPrefix префикс параметров запроса для отслеживания.
+const trackingPrefix = "utm_"
+
+// URLRules правила проверки и приведения URL к каноническому виду.
+type URLRules struct {
+	// Schemes разрешенные схемы, nil - DefaultURLSchemes.
+	Schemes []string
+	// StripTracking удалять параметры utm_*.
+	StripTracking bool
+}
+
+func (r URLRules) withDefaults() URLRules {
+	if r.Schemes == nil {
+		r.Schemes = DefaultURLSchemes
+	}
+	return r
+}
+
+// Normalize проверяет URL и приводит его к каноническому виду:
+// схема и хост в нижнем регистре, IDN в punycode, без порта по умолчанию,
+// пустой путь заменяется на "/".
+func (r URLRules) Normalize(raw string) (string, error) {
+	u, err := netURL.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if !r.allowedScheme(u.Scheme) {
+		return "", fmt.Errorf("%w: scheme %q is not allowed", ErrInvalidURL, u.Scheme)
+	}
+	if len(u.Opaque) > 0 || len(u.Hostname()) == 0 {
+		return "", fmt.Errorf("%w: host is empty", ErrInvalidURL)
+	}
+
+	host, err := normalizeHost(u.Hostname())
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
+
+	port := u.Port()
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+	switch {
+	case len(port) > 0:
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
+	}
+
+	if len(u.Path) == 0 {
+		u.Path, u.RawPath = "/", ""
+	}
+
+	if r.StripTracking {
+		u.RawQuery = stripTracking(u.RawQuery)
+		u.ForceQuery = false
+	}
+
+	return u.String(), nil
+}
+
+func (r URLRules) allowedScheme(scheme string) bool {
+	for _, s := range r.Schemes {
+		if strings.EqualFold(s, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeHost хост в нижнем регистре, интернациональные имена в punycode.
+func normalizeHost(host string) (string, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+
+	ascii, err := idna.Lookup.ToASCII(strings.TrimSuffix(host, "."))
+	if err != nil {
+		return "", fmt.Errorf("host %q: %w", host, err)
+	}
+	return strings.ToLower(ascii), nil
+}
+
+// stripTracking удаляет параметры utm_* с сохранением порядка остальных.
+func stripTracking(rawQuery string) string {
+	if len(rawQuery) == 0 {
+		return rawQuery
+	}
+
+	params := strings.Split(rawQuery, "&")
+	kept := params[:0]
+	for _, p := range params {
+		key, _, _ := strings.Cut(p, "=")
+		if name, err := netURL.QueryUnescape(key); err == nil {
+			key = name
+		}
+		if strings.HasPrefix(strings.ToLower(key), trackingPrefix) {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	return strings.Join(kept, "&")
+}
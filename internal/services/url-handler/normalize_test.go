@@ -0,0 +1,115 @@
+package urlhandler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+
+	cases := []struct {
+		name          string
+		rules         URLRules
+		url           string
+		expectedURL   string
+		expectedIsErr bool
+	}{
+		{
+			name:        "canonical url",
+			url:         "https://practicum.yandex.ru/",
+			expectedURL: "https://practicum.yandex.ru/",
+		},
+		{
+			name:        "scheme and host in lower case",
+			url:         "HTTPS://Practicum.Yandex.RU/Learn",
+			expectedURL: "https://practicum.yandex.ru/Learn",
+		},
+		{
+			name:        "empty path",
+			url:         "https://ya.ru",
+			expectedURL: "https://ya.ru/",
+		},
+		{
+			name:        "default port",
+			url:         "http://ya.ru:80/search?text=go",
+			expectedURL: "http://ya.ru/search?text=go",
+		},
+		{
+			name:        "non-default port",
+			url:         "https://ya.ru:8443/",
+			expectedURL: "https://ya.ru:8443/",
+		},
+		{
+			name:        "idn host",
+			url:         "https://Пример.РФ/путь",
+			expectedURL: "https://xn--e1afmkfd.xn--p1ai/%D0%BF%D1%83%D1%82%D1%8C",
+		},
+		{
+			name:        "ipv6 host",
+			url:         "https://[::1]:443/",
+			expectedURL: "https://[::1]/",
+		},
+		{
+			name:        "tracking parameters are kept by default",
+			url:         "https://ya.ru/?utm_source=mail&q=go",
+			expectedURL: "https://ya.ru/?utm_source=mail&q=go",
+		},
+		{
+			name:        "tracking parameters are stripped",
+			rules:       URLRules{StripTracking: true},
+			url:         "https://ya.ru/?utm_source=mail&q=go&UTM_Campaign=spring#top",
+			expectedURL: "https://ya.ru/?q=go#top",
+		},
+		{
+			name:        "only tracking parameters",
+			rules:       URLRules{StripTracking: true},
+			url:         "https://ya.ru/?utm_source=mail",
+			expectedURL: "https://ya.ru/",
+		},
+		{
+			name:          "javascript scheme",
+			url:           "javascript:alert(1)",
+			expectedIsErr: true,
+		},
+		{
+			name:          "ftp scheme",
+			url:           "ftp://ftp.example.com/file",
+			expectedIsErr: true,
+		},
+		{
+			name:          "bare host",
+			url:           "practicum.yandex.ru/",
+			expectedIsErr: true,
+		},
+		{
+			name:          "empty host",
+			url:           "https:///path",
+			expectedIsErr: true,
+		},
+		{
+			name:        "custom schemes",
+			rules:       URLRules{Schemes: []string{"ftp"}},
+			url:         "FTP://ftp.example.com/file",
+			expectedURL: "ftp://ftp.example.com/file",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			url, err := tc.rules.withDefaults().Normalize(tc.url)
+
+			if tc.expectedIsErr {
+				assert.ErrorIs(t, err, ErrInvalidURL)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedURL, url)
+		})
+	}
+}
@@ -0,0 +1,179 @@
+// policy отвечает за правила допустимых адресов назначения
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	netURL "net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/net/idna"
+)
+
+// Режимы применения правил
+const (
+	// ModeDeny запрещены адреса, подходящие под правила.
+	ModeDeny = "deny"
+	// ModeAllow разрешены только адреса, подходящие под правила.
+	ModeAllow = "allow"
+)
+
+// ErrInvalidRules правила не удалось разобрать.
+var ErrInvalidRules = errors.New("invalid policy rules")
+
+// Rules правила в формате JSON файла:
+// {"mode": "deny", "domains": ["evil.com"], "suffixes": ["phish.io"], "patterns": ["^https?://[^/]*paypal"]}.
+//
+// Domains совпадают с хостом точно, Suffixes - с хостом и его поддоменами,
+// Patterns проверяются по всему URL.
+type Rules struct {
+	Mode     string   `json:"mode"`
+	Domains  []string `json:"domains"`
+	Suffixes []string `json:"suffixes"`
+	Patterns []string `json:"patterns"`
+}
+
+// compiled правила, подготовленные к проверке.
+type compiled struct {
+	allow    bool
+	domains  map[string]struct{}
+	suffixes []string
+	patterns []*regexp.Regexp
+}
+
+// Policy набор правил, который можно заменить без перезапуска.
+// Безопасен для конкурентного использования.
+type Policy struct {
+	rules atomic.Pointer[compiled]
+}
+
+// New конструктор Policy.
+func New(rules Rules) (*Policy, error) {
+	p := &Policy{}
+	if err := p.SetRules(rules); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Load читает правила из JSON файла.
+func Load(path string) (Rules, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Rules{}, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	defer f.Close()
+
+	var rules Rules
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rules); err != nil {
+		return Rules{}, fmt.Errorf("%w: %s: %w", ErrInvalidRules, path, err)
+	}
+
+	return rules, nil
+}
+
+// SetRules заменяет набор правил.
+// При ошибке продолжает действовать прежний набор.
+func (p *Policy) SetRules(rules Rules) error {
+	c, err := compile(rules)
+	if err != nil {
+		return err
+	}
+	p.rules.Store(c)
+	return nil
+}
+
+// Allowed проверяет, можно ли сокращать url.
+// url должен быть в каноническом виде: хост в нижнем регистре и punycode.
+func (p *Policy) Allowed(url string) bool {
+	c := p.rules.Load()
+	if c == nil {
+		return true
+	}
+	return c.match(url) == c.allow
+}
+
+func (c *compiled) match(url string) bool {
+	u, err := netURL.Parse(url)
+	if err != nil {
+		return false
+	}
+	host := strings.TrimSuffix(u.Hostname(), ".")
+
+	if _, ok := c.domains[host]; ok {
+		return true
+	}
+	for _, suffix := range c.suffixes {
+		if host == suffix || strings.HasSuffix(host, "."+suffix) {
+			return true
+		}
+	}
+	for _, re := range c.patterns {
+		if re.MatchString(url) {
+			return true
+		}
+	}
+	return false
+}
+
+func compile(rules Rules) (*compiled, error) {
+	c := &compiled{
+		domains:  make(map[string]struct{}, len(rules.Domains)),
+		suffixes: make([]string, 0, len(rules.Suffixes)),
+		patterns: make([]*regexp.Regexp, 0, len(rules.Patterns)),
+	}
+
+	switch rules.Mode {
+	case "", ModeDeny:
+	case ModeAllow:
+		c.allow = true
+	default:
+		return nil, fmt.Errorf("%w: unknown mode %q", ErrInvalidRules, rules.Mode)
+	}
+
+	for _, domain := range rules.Domains {
+		host, err := normalizeDomain(domain)
+		if err != nil {
+			return nil, err
+		}
+		c.domains[host] = struct{}{}
+	}
+
+	for _, suffix := range rules.Suffixes {
+		host, err := normalizeDomain(strings.TrimPrefix(suffix, "."))
+		if err != nil {
+			return nil, err
+		}
+		c.suffixes = append(c.suffixes, host)
+	}
+
+	for _, pattern := range rules.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: pattern %q: %w", ErrInvalidRules, pattern, err)
+		}
+		c.patterns = append(c.patterns, re)
+	}
+
+	return c, nil
+}
+
+// normalizeDomain домен в нижнем регистре и punycode, как хост в каноническом URL.
+func normalizeDomain(domain string) (string, error) {
+	domain = strings.TrimSuffix(strings.TrimSpace(domain), ".")
+	if len(domain) == 0 {
+		return "", fmt.Errorf("%w: empty domain", ErrInvalidRules)
+	}
+
+	host, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return "", fmt.Errorf("%w: domain %q: %w", ErrInvalidRules, domain, err)
+	}
+	return strings.ToLower(host), nil
+}
@@ -0,0 +1,124 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowed(t *testing.T) {
+
+	cases := []struct {
+		name     string
+		rules    Rules
+		url      string
+		expected bool
+	}{
+		{
+			name:     "no rules",
+			url:      "https://ya.ru/",
+			expected: true,
+		},
+		{
+			name:     "exact domain is denied",
+			rules:    Rules{Domains: []string{"Evil.com"}},
+			url:      "https://evil.com/login",
+			expected: false,
+		},
+		{
+			name:     "exact domain does not match subdomain",
+			rules:    Rules{Domains: []string{"evil.com"}},
+			url:      "https://www.evil.com/login",
+			expected: true,
+		},
+		{
+			name:     "suffix matches subdomain",
+			rules:    Rules{Suffixes: []string{".phish.io"}},
+			url:      "https://secure.bank.phish.io/",
+			expected: false,
+		},
+		{
+			name:     "suffix matches domain itself",
+			rules:    Rules{Suffixes: []string{"phish.io"}},
+			url:      "https://phish.io/",
+			expected: false,
+		},
+		{
+			name:     "suffix does not match partial label",
+			rules:    Rules{Suffixes: []string{"phish.io"}},
+			url:      "https://notphish.io/",
+			expected: true,
+		},
+		{
+			name:     "idn domain",
+			rules:    Rules{Domains: []string{"пример.рф"}},
+			url:      "https://xn--e1afmkfd.xn--p1ai/",
+			expected: false,
+		},
+		{
+			name:     "pattern matches full url",
+			rules:    Rules{Patterns: []string{`/wp-admin/`}},
+			url:      "https://blog.example.com/wp-admin/login.php",
+			expected: false,
+		},
+		{
+			name:     "allow mode permits listed domain",
+			rules:    Rules{Mode: ModeAllow, Suffixes: []string{"yandex.ru"}},
+			url:      "https://practicum.yandex.ru/",
+			expected: true,
+		},
+		{
+			name:     "allow mode denies other domains",
+			rules:    Rules{Mode: ModeAllow, Suffixes: []string{"yandex.ru"}},
+			url:      "https://go.dev/",
+			expected: false,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p, err := New(tc.rules)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, p.Allowed(tc.url))
+		})
+	}
+}
+
+func TestSetRules(t *testing.T) {
+	p, err := New(Rules{Domains: []string{"evil.com"}})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, p.SetRules(Rules{Mode: "block"}), ErrInvalidRules)
+	assert.ErrorIs(t, p.SetRules(Rules{Patterns: []string{"("}}), ErrInvalidRules)
+	assert.ErrorIs(t, p.SetRules(Rules{Domains: []string{" "}}), ErrInvalidRules)
+
+	// неверные правила не заменяют прежние
+	assert.False(t, p.Allowed("https://evil.com/"))
+
+	require.NoError(t, p.SetRules(Rules{Domains: []string{"phish.io"}}))
+	assert.True(t, p.Allowed("https://evil.com/"))
+	assert.False(t, p.Allowed("https://phish.io/"))
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"mode": "allow",
+		"suffixes": ["yandex.ru"]
+	}`), 0o600))
+
+	rules, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, Rules{Mode: ModeAllow, Suffixes: []string{"yandex.ru"}}, rules)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"domain": ["evil.com"]}`), 0o600))
+	_, err = Load(path)
+	assert.ErrorIs(t, err, ErrInvalidRules)
+}
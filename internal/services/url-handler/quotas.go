@@ -0,0 +1,75 @@
+package urlhandler
+
+import (
+	"errors"
+	"fmt"
+
+	dbkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/db-keeper"
+)
+
+// Значения ограничений по умолчанию
+const (
+	// DefaultMaxURLLength размер колонки original_url в БД.
+	DefaultMaxURLLength = 4000
+)
+
+// Ошибки превышения ограничений
+var (
+	ErrURLTooLong        = errors.New("url is too long")
+	ErrBatchTooLarge     = errors.New("batch is too large")
+	ErrLinkQuotaExceeded = errors.New("link quota exceeded")
+)
+
+// Quotas ограничения на сохраняемые URL.
+// Нулевое значение - без ограничения, кроме MaxURLLength,
+// для которого используется DefaultMaxURLLength.
+type Quotas struct {
+	MaxLinksPerUser int
+	MaxBatchSize    int
+	MaxURLLength    int
+}
+
+// LimitError превышение ограничения Limit значением Value.
+// Err одна из ошибок ErrURLTooLong, ErrBatchTooLarge, ErrLinkQuotaExceeded.
+type LimitError struct {
+	Err   error
+	Limit int
+	Value int
+}
+
+// Error описание ошибки.
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s: %d exceeds limit %d", e.Err, e.Value, e.Limit)
+}
+
+// Unwrap позволяет проверить ошибку через errors.Is.
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+func (q Quotas) withDefaults() Quotas {
+	if q.MaxURLLength < 1 {
+		q.MaxURLLength = DefaultMaxURLLength
+	}
+	return q
+}
+
+func (q Quotas) checkURL(url string) error {
+	if len(url) > q.MaxURLLength {
+		return &LimitError{Err: ErrURLTooLong, Limit: q.MaxURLLength, Value: len(url)}
+	}
+	return nil
+}
+
+func (q Quotas) checkBatch(size int) error {
+	if q.MaxBatchSize > 0 && size > q.MaxBatchSize {
+		return &LimitError{Err: ErrBatchTooLarge, Limit: q.MaxBatchSize, Value: size}
+	}
+	return nil
+}
+
+// linkQuotaError ошибка хранилища о превышении квоты на URL пользователя.
+// Квота проверяется хранилищем атомарно с сохранением.
+func linkQuotaError(err *dbkeeper.QuotaError) error {
+	return &LimitError{Err: ErrLinkQuotaExceeded, Limit: err.Limit, Value: err.Value}
+}
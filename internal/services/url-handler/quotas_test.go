@@ -0,0 +1,102 @@
+package urlhandler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler/mocks"
+	dbkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/db-keeper"
+)
+
+func TestQuotas(t *testing.T) {
+	const userID = "a6b8b5a4-7d2f-4c36-9cf1-5b9a0c0e3d11"
+
+	quotas := Quotas{MaxLinksPerUser: 3, MaxBatchSize: 2, MaxURLLength: 32}
+
+	cases := []struct {
+		name          string
+		urls          []string
+		storageErr    error
+		isCallSave    bool
+		expectedIs    error
+		expectedLimit int
+	}{
+		{
+			name:          "batch is too large",
+			urls:          []string{"https://ya.ru/", "https://go.dev/", "https://pkg.go.dev/"},
+			expectedIs:    ErrBatchTooLarge,
+			expectedLimit: 2,
+		},
+		{
+			name:          "link quota exceeded",
+			urls:          []string{"https://ya.ru/", "https://go.dev/"},
+			storageErr:    &dbkeeper.QuotaError{Limit: 3, Value: 4},
+			isCallSave:    true,
+			expectedIs:    ErrLinkQuotaExceeded,
+			expectedLimit: 3,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			storage := mocks.NewKeeperer(t)
+			h := NewURLHandler(storage, mocks.NewDBPinger(t), nil, Option{Quotas: quotas})
+
+			urls := make([]models.BatchRequest, 0, len(tc.urls))
+			for _, url := range tc.urls {
+				urls = append(urls, models.BatchRequest{CorrelationID: url, OriginalURL: url})
+			}
+			if tc.isCallSave {
+				// квота проверяется хранилищем в транзакции сохранения
+				storage.On("SaveURLS", mock.Anything, urls, userID, quotas.MaxLinksPerUser).
+					Return(nil, tc.storageErr)
+			}
+
+			resp, err := h.SaveURLS(context.Background(), urls, userID)
+			assert.Nil(t, resp)
+			require.ErrorIs(t, err, tc.expectedIs)
+
+			var limitErr *LimitError
+			require.ErrorAs(t, err, &limitErr)
+			assert.Equal(t, tc.expectedLimit, limitErr.Limit)
+		})
+	}
+}
+
+func TestQuotasSaveURL(t *testing.T) {
+	const userID = "a6b8b5a4-7d2f-4c36-9cf1-5b9a0c0e3d11"
+
+	storage := mocks.NewKeeperer(t)
+	storage.On("PostURL", mock.Anything, models.URLRequest{URL: "https://ya.ru/"}, userID, 1).
+		Return("", &dbkeeper.QuotaError{Limit: 1, Value: 2}).Once()
+
+	h := NewURLHandler(storage, mocks.NewDBPinger(t), nil, Option{Quotas: Quotas{MaxLinksPerUser: 1}})
+
+	_, err := h.SaveURL(context.Background(), models.URLRequest{URL: "https://ya.ru/"}, userID)
+	assert.ErrorIs(t, err, ErrLinkQuotaExceeded)
+
+	// в пакете слишком длинный URL становится ошибкой элемента
+	resp, err := h.SaveURLS(context.Background(), []models.BatchRequest{
+		{CorrelationID: "1", OriginalURL: "https://ya.ru/" + strings.Repeat("a", DefaultMaxURLLength)},
+	}, userID)
+	require.NoError(t, err)
+	require.Len(t, resp, 1)
+	assert.Equal(t, models.BatchInvalid, resp[0].Status)
+	assert.Contains(t, resp[0].Reason, ErrURLTooLong.Error())
+
+	// длина по умолчанию ограничена размером колонки в БД
+	_, err = h.SaveURL(context.Background(), models.URLRequest{
+		URL: "https://ya.ru/" + strings.Repeat("a", DefaultMaxURLLength),
+	}, userID)
+	assert.ErrorIs(t, err, ErrURLTooLong)
+}
@@ -0,0 +1,85 @@
+// reaper отвечает за фоновое удаление URL: истекших и с истекшим сроком восстановления
+package reaper
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Reaper хранит данные для периодического удаления URL.
+type Reaper struct {
+	context   context.Context
+	log       *zap.Logger
+	interval  time.Duration
+	batchSize int
+	callback  func(ctx context.Context, limit int) (int64, error)
+}
+
+// NewReaper конструктор для Reaper.
+// Каждые interval вызывает callback пачками по batchSize,
+// пока удаляется полная пачка.
+func NewReaper(ctx context.Context,
+	log *zap.Logger,
+	interval time.Duration,
+	batchSize int,
+	callback func(ctx context.Context, limit int) (int64, error),
+) *Reaper {
+	r := &Reaper{
+		context:   ctx,
+		log:       log,
+		interval:  interval,
+		batchSize: batchSize,
+		callback:  callback,
+	}
+
+	if interval > 0 && batchSize > 0 {
+		r.reaper()
+	}
+
+	return r
+}
+
+func (r *Reaper) reaper() {
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.context.Done():
+				return
+			case <-ticker.C:
+				r.reap()
+			}
+		}
+	}()
+}
+
+func (r *Reaper) reap() {
+	var total int64
+	for {
+		select {
+		case <-r.context.Done():
+			return
+		default:
+		}
+
+		ctx, cancel := context.WithTimeout(r.context, time.Second*10)
+		n, err := r.callback(ctx, r.batchSize)
+		cancel()
+		if err != nil {
+			r.log.Error("failed to delete urls", zap.Error(err))
+			return
+		}
+
+		total += n
+		if n < int64(r.batchSize) {
+			break
+		}
+	}
+
+	if total > 0 {
+		r.log.Info("urls deleted", zap.Int64("count", total))
+	}
+}
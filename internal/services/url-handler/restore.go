@@ -0,0 +1,51 @@
+package urlhandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	dbkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/db-keeper"
+)
+
+// RestoreURLS восстанавливает удаленные URL пользователя,
+// если срок восстановления не истек.
+// Возвращает восстановленные URL, остальные пропускаются.
+// Квота учитывает только восстановленные URL.
+func (uh *URLHandler) RestoreURLS(ctx context.Context, shortURLS []string, userID string) ([]string, error) {
+	if len(shortURLS) == 0 {
+		return []string{}, nil
+	}
+	if err := uh.quotas.checkBatch(len(shortURLS)); err != nil {
+		return nil, err
+	}
+	restored, err := uh.storage.RestoreURLS(ctx, shortURLS, userID, uh.restoreDeadline(), uh.quotas.MaxLinksPerUser)
+	if err != nil {
+		var quotaErr *dbkeeper.QuotaError
+		if errors.As(err, &quotaErr) {
+			return nil, linkQuotaError(quotaErr)
+		}
+		return nil, fmt.Errorf("failed to restore urls: %w", err)
+	}
+
+	return restored, nil
+}
+
+// PurgeDeleted окончательно удаляет до limit URL
+// с истекшим сроком восстановления.
+// Без срока восстановления удаленные URL хранятся бессрочно.
+func (uh *URLHandler) PurgeDeleted(ctx context.Context, limit int) (int64, error) {
+	if uh.restoreGrace <= 0 {
+		return 0, nil
+	}
+	return uh.storage.PurgeDeleted(ctx, uh.restoreDeadline(), limit)
+}
+
+// restoreDeadline URL, удаленные не позже этого времени, не восстанавливаются.
+func (uh *URLHandler) restoreDeadline() time.Time {
+	if uh.restoreGrace <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(-uh.restoreGrace)
+}
@@ -0,0 +1,103 @@
+package urlhandler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler/mocks"
+)
+
+func TestRestoreURLS(t *testing.T) {
+	const userID = "a6b8b5a4-7d2f-4c36-9cf1-5b9a0c0e3d11"
+
+	cases := []struct {
+		name          string
+		grace         time.Duration
+		shortURLS     []string
+		restored      []string
+		storageErr    error
+		isCallStorage bool
+		isError       bool
+	}{
+		{
+			name:          "within grace period",
+			grace:         time.Hour,
+			shortURLS:     []string{"promo", "sale"},
+			restored:      []string{"promo"},
+			isCallStorage: true,
+		},
+		{
+			name:          "without grace period",
+			shortURLS:     []string{"promo"},
+			restored:      []string{"promo"},
+			isCallStorage: true,
+		},
+		{
+			name:     "empty list",
+			grace:    time.Hour,
+			restored: []string{},
+		},
+		{
+			name:          "storage error",
+			grace:         time.Hour,
+			shortURLS:     []string{"promo"},
+			storageErr:    errors.New("connection refused"),
+			isCallStorage: true,
+			isError:       true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			storage := mocks.NewKeeperer(t)
+			if tc.isCallStorage {
+				storage.On("RestoreURLS", mock.Anything, tc.shortURLS, userID,
+					mock.MatchedBy(func(deletedAfter time.Time) bool {
+						if tc.grace == 0 {
+							return deletedAfter.IsZero()
+						}
+						return time.Since(deletedAfter) >= tc.grace && time.Since(deletedAfter) < tc.grace+time.Minute
+					}),
+					0,
+				).Return(tc.restored, tc.storageErr)
+			}
+
+			h := NewURLHandler(storage, mocks.NewDBPinger(t), nil, Option{RestoreGrace: tc.grace})
+			restored, err := h.RestoreURLS(context.Background(), tc.shortURLS, userID)
+
+			if tc.isError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.restored, restored)
+		})
+	}
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	storage := mocks.NewKeeperer(t)
+	storage.On("PurgeDeleted", mock.Anything, mock.AnythingOfType("time.Time"), 100).Return(int64(3), nil).Once()
+
+	h := NewURLHandler(storage, mocks.NewDBPinger(t), nil, Option{RestoreGrace: time.Hour})
+	purged, err := h.PurgeDeleted(context.Background(), 100)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), purged)
+
+	// без срока восстановления URL не удаляются
+	h = NewURLHandler(mocks.NewKeeperer(t), mocks.NewDBPinger(t), nil, Option{})
+	purged, err = h.PurgeDeleted(context.Background(), 100)
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+}
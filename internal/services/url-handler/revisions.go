@@ -0,0 +1,110 @@
+package urlhandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	dbkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/db-keeper"
+)
+
+// ErrInvalidRevision ревизия не задана корректно или не найдена.
+var ErrInvalidRevision = errors.New("invalid revision")
+
+// UpdateURL заменяет оригинальный URL пользователя на req.OriginalURL
+// или возвращает его к ревизии req.Revision.
+// Новый URL проходит те же проверки, что и при сохранении.
+// Теги и папка, если заданы в req, меняются атомарно с URL.
+func (uh *URLHandler) UpdateURL(
+	ctx context.Context,
+	userID string,
+	id string,
+	req models.UpdateURLRequest,
+) (models.MassURL, error) {
+	labels, hasLabels, err := updateLabels(req)
+	if err != nil {
+		return models.MassURL{}, err
+	}
+	if hasLabels && len(req.OriginalURL) == 0 && req.Revision == 0 {
+		return uh.updateLabels(ctx, userID, id, labels)
+	}
+
+	raw := req.OriginalURL
+	switch {
+	case len(req.OriginalURL) > 0 && req.Revision != 0:
+		return models.MassURL{}, fmt.Errorf("%w: original_url and revision are mutually exclusive", ErrInvalidRevision)
+	case req.Revision < 0:
+		return models.MassURL{}, fmt.Errorf("%w: revision must be positive", ErrInvalidRevision)
+	case req.Revision > 0:
+		url, err := uh.revisionURL(ctx, userID, id, req.Revision)
+		if err != nil {
+			return models.MassURL{}, err
+		}
+		raw = url
+	}
+
+	url, err := uh.urlRules.Normalize(raw)
+	if err != nil {
+		return models.MassURL{}, err
+	}
+	if err := uh.quotas.checkURL(url); err != nil {
+		return models.MassURL{}, err
+	}
+	if err := uh.checkDestination(url); err != nil {
+		return models.MassURL{}, err
+	}
+
+	updated, err := uh.storage.UpdateURL(ctx, id, userID, url, labels)
+	if err != nil {
+		return models.MassURL{}, ownerError("failed to update url", err)
+	}
+
+	return updated, nil
+}
+
+func (uh *URLHandler) updateLabels(ctx context.Context, userID string, id string, labels models.URLLabels) (models.MassURL, error) {
+	url, err := uh.storage.UpdateLabels(ctx, id, userID, labels)
+	if err != nil {
+		return models.MassURL{}, ownerError("failed to update url labels", err)
+	}
+	return url, nil
+}
+
+// GetURLHistory прежние оригинальные URL пользователя, начиная с последнего.
+func (uh *URLHandler) GetURLHistory(ctx context.Context, userID string, id string) ([]models.URLRevision, error) {
+	history, err := uh.storage.GetURLHistory(ctx, id, userID)
+	if err != nil {
+		return nil, ownerError("failed to read url history", err)
+	}
+	return history, nil
+}
+
+// revisionURL оригинальный URL ревизии revision.
+func (uh *URLHandler) revisionURL(ctx context.Context, userID string, id string, revision int64) (string, error) {
+	history, err := uh.GetURLHistory(ctx, userID, id)
+	if err != nil {
+		return "", err
+	}
+	for _, rev := range history {
+		if rev.Revision == revision {
+			return rev.OriginalURL, nil
+		}
+	}
+	return "", fmt.Errorf("%w: revision %d is not found", ErrInvalidRevision, revision)
+}
+
+// ownerError ошибки хранилища при доступе к URL пользователя.
+func ownerError(msg string, err error) error {
+	switch {
+	case errors.Is(err, dbkeeper.ErrNotFound):
+		return ErrNotFound
+	case errors.Is(err, dbkeeper.ErrNotOwner):
+		return ErrNotOwner
+	case errors.Is(err, dbkeeper.ErrURLRemoved):
+		return ErrURLRemoved
+	case errors.Is(err, dbkeeper.ErrAlreadyExists):
+		return ErrAlreadyExists
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
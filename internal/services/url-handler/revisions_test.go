@@ -0,0 +1,108 @@
+package urlhandler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler/mocks"
+	dbkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/db-keeper"
+)
+
+func TestUpdateURL(t *testing.T) {
+	const userID = "a6b8b5a4-7d2f-4c36-9cf1-5b9a0c0e3d11"
+
+	history := []models.URLRevision{
+		{Revision: 2, OriginalURL: "https://go.dev/", ReplacedAt: time.Now()},
+		{Revision: 1, OriginalURL: "https://ya.ru/", ReplacedAt: time.Now()},
+	}
+
+	cases := []struct {
+		name         string
+		req          models.UpdateURLRequest
+		storedURL    string
+		storageErr   error
+		isCallUpdate bool
+		isCallList   bool
+		expectedIs   error
+	}{
+		{
+			name:         "new original url",
+			req:          models.UpdateURLRequest{OriginalURL: "HTTPS://Practicum.Yandex.ru"},
+			storedURL:    "https://practicum.yandex.ru/",
+			isCallUpdate: true,
+		},
+		{
+			name:         "rollback to revision",
+			req:          models.UpdateURLRequest{Revision: 1},
+			storedURL:    "https://ya.ru/",
+			isCallUpdate: true,
+			isCallList:   true,
+		},
+		{
+			name:       "unknown revision",
+			req:        models.UpdateURLRequest{Revision: 3},
+			isCallList: true,
+			expectedIs: ErrInvalidRevision,
+		},
+		{
+			name:       "original url and revision together",
+			req:        models.UpdateURLRequest{OriginalURL: "https://ya.ru/", Revision: 1},
+			expectedIs: ErrInvalidRevision,
+		},
+		{
+			name:       "invalid url",
+			req:        models.UpdateURLRequest{OriginalURL: "javascript:alert(1)"},
+			expectedIs: ErrInvalidURL,
+		},
+		{
+			name:         "url belongs to another user",
+			req:          models.UpdateURLRequest{OriginalURL: "https://ya.ru/"},
+			storedURL:    "https://ya.ru/",
+			storageErr:   dbkeeper.ErrNotOwner,
+			isCallUpdate: true,
+			expectedIs:   ErrNotOwner,
+		},
+		{
+			name:         "original url is already shortened",
+			req:          models.UpdateURLRequest{OriginalURL: "https://ya.ru/"},
+			storedURL:    "https://ya.ru/",
+			storageErr:   dbkeeper.ErrAlreadyExists,
+			isCallUpdate: true,
+			expectedIs:   ErrAlreadyExists,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			storage := mocks.NewKeeperer(t)
+			if tc.isCallList {
+				storage.On("GetURLHistory", mock.Anything, "promo", userID).Return(history, nil)
+			}
+			if tc.isCallUpdate {
+				storage.On("UpdateURL", mock.Anything, "promo", userID, tc.storedURL, models.URLLabels{}).
+					Return(models.MassURL{ShortURL: "promo", OriginalURL: tc.storedURL}, tc.storageErr)
+			}
+
+			h := NewURLHandler(storage, mocks.NewDBPinger(t), nil, Option{})
+			url, err := h.UpdateURL(context.Background(), userID, "promo", tc.req)
+
+			if tc.expectedIs != nil {
+				assert.ErrorIs(t, err, tc.expectedIs)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, models.MassURL{ShortURL: "promo", OriginalURL: tc.storedURL}, url)
+		})
+	}
+}
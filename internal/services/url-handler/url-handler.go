@@ -5,9 +5,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	netURL "net/url"
+	"math"
+	"time"
 
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler/clicker"
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler/deleter"
 	dbkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/db-keeper"
 )
@@ -16,17 +18,43 @@ import (
 var (
 	ErrAlreadyExists = errors.New("the value already exists")
 	ErrURLRemoved    = errors.New("url has already been deleted")
+	ErrAliasTaken    = errors.New("alias is already taken")
+	ErrInvalidAlias  = errors.New("invalid alias")
+	ErrURLExpired    = errors.New("url has expired")
+	ErrInvalidExpiry = errors.New("invalid expiration")
+	ErrNotFound      = errors.New("url not found")
+	ErrNotOwner      = errors.New("url belongs to another user")
 )
 
 // Keeperer интерфейс хранилища данных.
 //
 //go:generate mockery --name Keeperer
 type Keeperer interface {
-	PostURL(ctx context.Context, url string, userID string) (string, error)
+	PostURL(ctx context.Context, req models.URLRequest, userID string, maxLinks int) (string, error)
 	GetURL(ctx context.Context, id string) (string, error)
-	SaveURLS(ctx context.Context, urls []models.BatchRequest, userID string) ([]models.BatchResponse, error)
-	GetURLS(ctx context.Context, userID string) ([]models.MassURL, error)
+	SaveURLS(ctx context.Context, urls []models.BatchRequest, userID string, maxLinks int) ([]models.BatchResponse, error)
+	GetURLS(ctx context.Context, query models.URLQuery) (models.URLPage, error)
 	DeleteURLS(ctx context.Context, shortURLS []models.DeleteURL)
+	DeleteExpired(ctx context.Context, limit int) (int64, error)
+	SaveClicks(ctx context.Context, clicks []models.Click)
+	GetStats(ctx context.Context, shortURL string, userID string, hourlyFrom, dailyFrom time.Time) (models.URLStats, error)
+	CountURLS(ctx context.Context) (models.URLCounts, error)
+	CountUsers(ctx context.Context) (int64, error)
+	CreateUser(ctx context.Context, user models.User) error
+	GetUserByLogin(ctx context.Context, login string) (models.User, error)
+	ClaimURLS(ctx context.Context, fromUserID, toUserID string, maxLinks int) (int64, error)
+	CreateAPIKey(ctx context.Context, key models.APIKey) error
+	GetAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string, userID string) error
+	TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error
+	BlockURLS(ctx context.Context, shortURLS []string, blocked bool) (int64, error)
+	UpdateURL(ctx context.Context, shortURL string, userID string, originalURL string, labels models.URLLabels) (models.MassURL, error)
+	GetURLHistory(ctx context.Context, shortURL string, userID string) ([]models.URLRevision, error)
+	RestoreURLS(ctx context.Context, shortURLS []string, userID string, deletedAfter time.Time, maxLinks int) ([]string, error)
+	PurgeDeleted(ctx context.Context, deletedBefore time.Time, limit int) (int64, error)
+	UpdateLabels(ctx context.Context, shortURL string, userID string, labels models.URLLabels) (models.MassURL, error)
+	GetTags(ctx context.Context, userID string) ([]models.TagCount, error)
 }
 
 // DBPinger интерфейс проверки доступности хранилища.
@@ -36,19 +64,63 @@ type DBPinger interface {
 	PingContext(ctx context.Context) error
 }
 
+// Option настройки бизнес логики.
+type Option struct {
+	AliasRules AliasRules
+	URLRules   URLRules
+	Quotas     Quotas
+	// Clicker очередь записи переходов, nil - переходы не записываются.
+	Clicker *clicker.Clicker
+	// CountryResolver по умолчанию NopCountryResolver.
+	CountryResolver CountryResolver
+	// Policy по умолчанию AllowAllPolicy.
+	Policy DestinationPolicy
+	// RestoreGrace срок восстановления удаленных URL,
+	// 0 - восстановление без ограничения срока.
+	RestoreGrace time.Duration
+	// IPHashKey ключ хеширования IP создателей URL.
+	IPHashKey []byte
+}
+
 // URLHandler хранит объекты, необходимые для реализации бизнес логики
 type URLHandler struct {
-	storage Keeperer
-	pingDB  DBPinger
-	deleter *deleter.Deleter
+	storage         Keeperer
+	pingDB          DBPinger
+	deleter         *deleter.Deleter
+	clicker         *clicker.Clicker
+	countryResolver CountryResolver
+	aliasRules      AliasRules
+	urlRules        URLRules
+	quotas          Quotas
+	policy          DestinationPolicy
+	restoreGrace    time.Duration
+	ipHashKey       []byte
 }
 
 // NewURLHandler конструктор URLHandler.
-func NewURLHandler(storage Keeperer, pingDB DBPinger, deleter *deleter.Deleter) *URLHandler {
+func NewURLHandler(storage Keeperer, pingDB DBPinger, deleter *deleter.Deleter, opt Option) *URLHandler {
+	countryResolver := opt.CountryResolver
+	if countryResolver == nil {
+		countryResolver = NopCountryResolver{}
+	}
+
+	policy := opt.Policy
+	if policy == nil {
+		policy = AllowAllPolicy{}
+	}
+
 	return &URLHandler{
-		storage: storage,
-		pingDB:  pingDB,
-		deleter: deleter,
+		storage:         storage,
+		pingDB:          pingDB,
+		deleter:         deleter,
+		clicker:         opt.Clicker,
+		countryResolver: countryResolver,
+		aliasRules:      opt.AliasRules.withDefaults(),
+		urlRules:        opt.URLRules.withDefaults(),
+		quotas:          opt.Quotas.withDefaults(),
+		policy:          policy,
+		restoreGrace:    opt.RestoreGrace,
+		ipHashKey:       opt.IPHashKey,
 	}
 }
 
@@ -61,8 +133,13 @@ func (uh *URLHandler) ReadURL(ctx context.Context, alias string) (string, error)
 
 	url, err := uh.storage.GetURL(ctx, alias)
 	if err != nil {
-		if errors.Is(err, dbkeeper.ErrURLRemoved) {
+		switch {
+		case errors.Is(err, dbkeeper.ErrURLRemoved):
 			return "", ErrURLRemoved
+		case errors.Is(err, dbkeeper.ErrURLExpired):
+			return "", ErrURLExpired
+		case errors.Is(err, dbkeeper.ErrURLBlocked):
+			return "", ErrDestinationBlocked
 		}
 		return "", fmt.Errorf("failed to read url: %w", err)
 	}
@@ -72,24 +149,61 @@ func (uh *URLHandler) ReadURL(ctx context.Context, alias string) (string, error)
 }
 
 // SaveURL сохранение сокращенного URL.
-func (uh *URLHandler) SaveURL(ctx context.Context, url string, userID string) (string, error) {
+// URL приводится к каноническому виду, см. URLRules.Normalize,
+// и проверяется политикой адресов назначения.
+// Если алиас не задан, он генерируется случайно.
+// TTL переводится в ExpiresAt.
+func (uh *URLHandler) SaveURL(ctx context.Context, req models.URLRequest, userID string) (string, error) {
+
+	url, err := uh.urlRules.Normalize(req.URL)
+	if err != nil {
+		return "", err
+	}
+	if err := uh.quotas.checkURL(url); err != nil {
+		return "", err
+	}
+	if err := uh.checkDestination(url); err != nil {
+		return "", err
+	}
+	req.URL = url
+	req.Creator = uh.creator(req.Creator)
+
+	if req.Tags, err = normalizeTags(req.Tags); err != nil {
+		return "", err
+	}
+	if req.Folder, err = normalizeFolder(req.Folder); err != nil {
+		return "", err
+	}
 
-	alias := ""
-	if _, err := netURL.ParseRequestURI(url); err != nil {
-		return alias, fmt.Errorf("invalid url: %w", err)
+	if len(req.Alias) > 0 {
+		if err := uh.aliasRules.Validate(req.Alias); err != nil {
+			return "", err
+		}
+	}
+
+	expiresAt, err := resolveExpiry(req.ExpiresAt, req.TTL)
+	if err != nil {
+		return "", err
 	}
+	req.ExpiresAt, req.TTL = expiresAt, 0
 
-	alias, err := uh.storage.PostURL(ctx, url, userID)
+	id, err := uh.storage.PostURL(ctx, req, userID, uh.quotas.MaxLinksPerUser)
 	if err != nil {
+		var aliasErr *dbkeeper.AliasTakenError
+		var quotaErr *dbkeeper.QuotaError
 		switch {
 		case errors.Is(err, dbkeeper.ErrAlreadyExists):
-			return alias, ErrAlreadyExists
+			return id, ErrAlreadyExists
+		case errors.As(err, &aliasErr):
+			return "", fmt.Errorf("%w: %s", ErrAliasTaken, aliasErr.Alias)
+		case errors.As(err, &quotaErr):
+			return "", linkQuotaError(quotaErr)
 		default:
-			return alias, fmt.Errorf("failed to save url: %w", err)
+			return id, fmt.Errorf("failed to save url: %w", err)
 		}
 	}
 
-	return alias, nil
+	return id, nil
 }
 
 // Ping проверка доступности хранилища.
@@ -98,6 +212,9 @@ func (uh *URLHandler) Ping(ctx context.Context) error {
 }
 
 // SaveURLS массовое сохранение URL.
+// Ошибки отдельных элементов не прерывают пакет: результат для каждого
+// элемента возвращается в порядке urls со статусом BatchCreated,
+// BatchExisting или BatchInvalid.
 func (uh *URLHandler) SaveURLS(
 	ctx context.Context,
 	urls []models.BatchRequest,
@@ -106,12 +223,134 @@ func (uh *URLHandler) SaveURLS(
 	[]models.BatchResponse,
 	error,
 ) {
-	return uh.storage.SaveURLS(ctx, urls, userID)
+	if err := uh.quotas.checkBatch(len(urls)); err != nil {
+		return nil, err
+	}
+
+	results := make([]models.BatchResponse, len(urls))
+	// valid индексы элементов, прошедших проверку, в urls
+	valid := make([]int, 0, len(urls))
+	reqURLS := make([]models.BatchRequest, 0, len(urls))
+	aliases := make(map[string]struct{}, len(urls))
+	for i, url := range urls {
+		url, err := uh.prepareBatchItem(url, aliases)
+		if err != nil {
+			results[i] = models.BatchResponse{
+				CorrelationID: url.CorrelationID,
+				Status:        models.BatchInvalid,
+				Reason:        err.Error(),
+			}
+			continue
+		}
+		valid = append(valid, i)
+		reqURLS = append(reqURLS, url)
+	}
+
+	if len(reqURLS) == 0 {
+		return results, nil
+	}
+
+	resp, err := uh.storage.SaveURLS(ctx, reqURLS, userID, uh.quotas.MaxLinksPerUser)
+	if err != nil {
+		var quotaErr *dbkeeper.QuotaError
+		if errors.As(err, &quotaErr) {
+			return nil, linkQuotaError(quotaErr)
+		}
+		return nil, err
+	}
+	if len(resp) != len(reqURLS) {
+		return nil, fmt.Errorf("storage returned %d results for %d urls", len(resp), len(reqURLS))
+	}
+
+	for i, r := range resp {
+		results[valid[i]] = r
+	}
+	return results, nil
+}
+
+// prepareBatchItem проверяет элемент пакета и приводит его к виду для хранилища.
+// aliases алиасы предыдущих элементов пакета.
+func (uh *URLHandler) prepareBatchItem(url models.BatchRequest, aliases map[string]struct{}) (models.BatchRequest, error) {
+	original, err := uh.urlRules.Normalize(url.OriginalURL)
+	if err != nil {
+		return url, err
+	}
+	if err := uh.quotas.checkURL(original); err != nil {
+		return url, err
+	}
+	if err := uh.checkDestination(original); err != nil {
+		return url, err
+	}
+	url.OriginalURL = original
+	url.Creator = uh.creator(url.Creator)
+
+	if url.Tags, err = normalizeTags(url.Tags); err != nil {
+		return url, err
+	}
+	if url.Folder, err = normalizeFolder(url.Folder); err != nil {
+		return url, err
+	}
+
+	expiresAt, err := resolveExpiry(url.ExpiresAt, url.TTL)
+	if err != nil {
+		return url, err
+	}
+	url.ExpiresAt, url.TTL = expiresAt, 0
+
+	if len(url.Alias) == 0 {
+		return url, nil
+	}
+	if err := uh.aliasRules.Validate(url.Alias); err != nil {
+		return url, err
+	}
+	if _, ok := aliases[url.Alias]; ok {
+		return url, fmt.Errorf("%w: %q is duplicated in batch", ErrInvalidAlias, url.Alias)
+	}
+	aliases[url.Alias] = struct{}{}
+
+	return url, nil
 }
 
-// GetURLS список сокращенных URL пользователя.
-func (uh *URLHandler) GetURLS(ctx context.Context, userID string) ([]models.MassURL, error) {
-	return uh.storage.GetURLS(ctx, userID)
+// MaxTTL наибольший ttl в секундах, который помещается в time.Duration.
+const MaxTTL = int64(math.MaxInt64 / int64(time.Second))
+
+// resolveExpiry время истечения ссылки по expires_at или ttl.
+// nil - ссылка бессрочная.
+func resolveExpiry(expiresAt *time.Time, ttl int64) (*time.Time, error) {
+	switch {
+	case expiresAt != nil && ttl != 0:
+		return nil, fmt.Errorf("%w: expires_at and ttl are mutually exclusive", ErrInvalidExpiry)
+	case ttl < 0:
+		return nil, fmt.Errorf("%w: ttl must be positive", ErrInvalidExpiry)
+	case ttl > MaxTTL:
+		return nil, fmt.Errorf("%w: ttl must not exceed %d", ErrInvalidExpiry, MaxTTL)
+	case ttl > 0:
+		t := time.Now().Add(time.Duration(ttl) * time.Second).UTC()
+		return &t, nil
+	case expiresAt != nil && !expiresAt.After(time.Now()):
+		return nil, fmt.Errorf("%w: expires_at is in the past", ErrInvalidExpiry)
+	}
+	return expiresAt, nil
+}
+
+// ServiceStats статистика сервиса: количество URL и пользователей.
+func (uh *URLHandler) ServiceStats(ctx context.Context) (models.ServiceStats, error) {
+	urls, err := uh.storage.CountURLS(ctx)
+	if err != nil {
+		return models.ServiceStats{}, fmt.Errorf("failed to count urls: %w", err)
+	}
+
+	users, err := uh.storage.CountUsers(ctx)
+	if err != nil {
+		return models.ServiceStats{}, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	return models.ServiceStats{
+		URLs:    urls.Total,
+		Users:   users,
+		Deleted: urls.Deleted,
+		Expired: urls.Expired,
+	}, nil
 }
 
 // DeleteURLS удаление URL.
@@ -8,9 +8,11 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler/mocks"
+	dbkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/db-keeper"
 )
 
 func TestReadURL(t *testing.T) {
@@ -20,6 +22,7 @@ func TestReadURL(t *testing.T) {
 		id          string
 		expectedURL string
 		expectedErr error
+		expectedIs  error
 		isError     bool
 		isCallMock  bool
 	}{
@@ -36,6 +39,14 @@ func TestReadURL(t *testing.T) {
 			isError:     true,
 			isCallMock:  true,
 		},
+		{
+			name:        "url has expired",
+			id:          "idurltest",
+			expectedErr: dbkeeper.ErrURLExpired,
+			expectedIs:  ErrURLExpired,
+			isError:     true,
+			isCallMock:  true,
+		},
 		{
 			name:        "alias is empty",
 			expectedErr: errors.New("alias is empty"),
@@ -60,6 +71,7 @@ func TestReadURL(t *testing.T) {
 				storage,
 				mocks.NewDBPinger(t),
 				nil,
+				Option{},
 			)
 
 			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
@@ -69,6 +81,9 @@ func TestReadURL(t *testing.T) {
 			if tc.isError {
 				assert.Empty(t, url)
 				assert.Error(t, err)
+				if tc.expectedIs != nil {
+					assert.ErrorIs(t, err, tc.expectedIs)
+				}
 				return
 			}
 
@@ -82,12 +97,18 @@ func TestReadURL(t *testing.T) {
 }
 
 func TestSaveURL(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
 
 	cases := []struct {
 		name          string
 		longURL       string
+		alias         string
+		expiresAt     *time.Time
+		ttl           int64
 		expectedAlias string
 		expectedErr   error
+		expectedIs    error
 		isError       bool
 		isCallMock    bool
 	}{
@@ -108,7 +129,74 @@ func TestSaveURL(t *testing.T) {
 			name:        "invalid url",
 			longURL:     "practicum.yandex.ru/",
 			expectedErr: errors.New("invalid url"),
+			expectedIs:  ErrInvalidURL,
+			isError:     true,
+		},
+		{
+			name:          "custom alias",
+			longURL:       "https://practicum.yandex.ru/",
+			alias:         "spring-sale",
+			expectedAlias: "spring-sale",
+			isCallMock:    true,
+		},
+		{
+			name:        "custom alias is taken",
+			longURL:     "https://practicum.yandex.ru/",
+			alias:       "spring-sale",
+			expectedErr: &dbkeeper.AliasTakenError{Alias: "spring-sale"},
+			expectedIs:  ErrAliasTaken,
 			isError:     true,
+			isCallMock:  true,
+		},
+		{
+			name:       "reserved alias",
+			longURL:    "https://practicum.yandex.ru/",
+			alias:      "API",
+			expectedIs: ErrInvalidAlias,
+			isError:    true,
+		},
+		{
+			name:       "alias with forbidden characters",
+			longURL:    "https://practicum.yandex.ru/",
+			alias:      "spring/sale",
+			expectedIs: ErrInvalidAlias,
+			isError:    true,
+		},
+		{
+			name:       "alias is too short",
+			longURL:    "https://practicum.yandex.ru/",
+			alias:      "ab",
+			expectedIs: ErrInvalidAlias,
+			isError:    true,
+		},
+		{
+			name:       "negative ttl",
+			longURL:    "https://practicum.yandex.ru/",
+			ttl:        -1,
+			expectedIs: ErrInvalidExpiry,
+			isError:    true,
+		},
+		{
+			name:       "ttl overflows duration",
+			longURL:    "https://practicum.yandex.ru/",
+			ttl:        MaxTTL + 1,
+			expectedIs: ErrInvalidExpiry,
+			isError:    true,
+		},
+		{
+			name:       "expires_at in the past",
+			longURL:    "https://practicum.yandex.ru/",
+			expiresAt:  &past,
+			expectedIs: ErrInvalidExpiry,
+			isError:    true,
+		},
+		{
+			name:       "expires_at and ttl together",
+			longURL:    "https://practicum.yandex.ru/",
+			expiresAt:  &future,
+			ttl:        60,
+			expectedIs: ErrInvalidExpiry,
+			isError:    true,
 		},
 	}
 
@@ -121,18 +209,27 @@ func TestSaveURL(t *testing.T) {
 			storage := mocks.NewKeeperer(t)
 
 			if tc.isCallMock {
-				storage.On("PostURL", mock.AnythingOfType("*context.timerCtx"), tc.longURL, "").
+				storage.On("PostURL", mock.AnythingOfType("*context.timerCtx"),
+					models.URLRequest{URL: tc.longURL, Alias: tc.alias}, "", 0).
 					Return(tc.expectedAlias, tc.expectedErr)
 			}
 
-			h := NewURLHandler(storage, mocks.NewDBPinger(t), nil)
+			h := NewURLHandler(storage, mocks.NewDBPinger(t), nil, Option{})
 			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 			defer cancel()
-			alias, err := h.SaveURL(ctx, tc.longURL, "")
+			alias, err := h.SaveURL(ctx, models.URLRequest{
+				URL:       tc.longURL,
+				Alias:     tc.alias,
+				ExpiresAt: tc.expiresAt,
+				TTL:       tc.ttl,
+			}, "")
 
 			if tc.isError {
 				assert.Empty(t, alias)
 				assert.Error(t, err)
+				if tc.expectedIs != nil {
+					assert.ErrorIs(t, err, tc.expectedIs)
+				}
 				return
 			}
 
@@ -175,7 +272,7 @@ func TestPing(t *testing.T) {
 
 			pingDB := mocks.NewDBPinger(t)
 
-			h := NewURLHandler(storage, pingDB, nil)
+			h := NewURLHandler(storage, pingDB, nil, Option{})
 			pingDB.
 				On("PingContext", mock.AnythingOfType("*context.timerCtx")).
 				Return(err)
@@ -221,18 +318,25 @@ func TestSaveURLS(t *testing.T) {
 				{
 					CorrelationID: "1",
 					ShortURL:      "https://localhost:8080/dkh2ksukde",
+					Status:        models.BatchCreated,
 				},
 				{
 					CorrelationID: "2",
 					ShortURL:      "https://localhost:8080/fh43jfhfdq",
+					Status:        models.BatchExisting,
 				},
 			},
 		},
 		{
-			name:         "failed to receive url",
-			urls:         []models.BatchRequest{},
+			name: "failed to receive url",
+			urls: []models.BatchRequest{
+				{
+					CorrelationID: "1",
+					OriginalURL:   "https://practicum.yandex.ru/",
+				},
+			},
 			expectedURLS: nil,
-			err:          errors.New("no data to save"),
+			err:          errors.New("connection refused"),
 			isError:      true,
 		},
 	}
@@ -245,10 +349,10 @@ func TestSaveURLS(t *testing.T) {
 
 			storage := mocks.NewKeeperer(t)
 
-			storage.On("SaveURLS", mock.AnythingOfType("*context.timerCtx"), tc.urls, "").
+			storage.On("SaveURLS", mock.AnythingOfType("*context.timerCtx"), tc.urls, "", 0).
 				Return(tc.expectedURLS, tc.err)
 
-			h := NewURLHandler(storage, mocks.NewDBPinger(t), nil)
+			h := NewURLHandler(storage, mocks.NewDBPinger(t), nil, Option{})
 			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 			defer cancel()
 			respURLS, err := h.SaveURLS(ctx, tc.urls, "")
@@ -267,3 +371,102 @@ func TestSaveURLS(t *testing.T) {
 	}
 
 }
+
+func TestSaveURLSInvalidItems(t *testing.T) {
+
+	cases := []struct {
+		name     string
+		urls     []models.BatchRequest
+		saved    []models.BatchRequest
+		invalid  int
+		expected error
+	}{
+		{
+			name: "duplicated alias in batch",
+			urls: []models.BatchRequest{
+				{CorrelationID: "1", OriginalURL: "https://ya.ru/", Alias: "promo"},
+				{CorrelationID: "2", OriginalURL: "https://go.dev/", Alias: "promo"},
+			},
+			saved: []models.BatchRequest{
+				{CorrelationID: "1", OriginalURL: "https://ya.ru/", Alias: "promo"},
+			},
+			invalid:  1,
+			expected: ErrInvalidAlias,
+		},
+		{
+			name: "reserved alias in batch",
+			urls: []models.BatchRequest{
+				{CorrelationID: "1", OriginalURL: "https://ya.ru/", Alias: "ping"},
+			},
+			expected: ErrInvalidAlias,
+		},
+		{
+			name: "invalid url in batch",
+			urls: []models.BatchRequest{
+				{CorrelationID: "1", OriginalURL: "https://ya.ru/"},
+				{CorrelationID: "2", OriginalURL: "javascript:alert(1)"},
+			},
+			saved: []models.BatchRequest{
+				{CorrelationID: "1", OriginalURL: "https://ya.ru/"},
+			},
+			invalid:  1,
+			expected: ErrInvalidURL,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			storage := mocks.NewKeeperer(t)
+			if len(tc.saved) > 0 {
+				saved := make([]models.BatchResponse, 0, len(tc.saved))
+				for _, url := range tc.saved {
+					saved = append(saved, models.BatchResponse{
+						CorrelationID: url.CorrelationID,
+						ShortURL:      "short-" + url.CorrelationID,
+						Status:        models.BatchCreated,
+					})
+				}
+				storage.On("SaveURLS", mock.Anything, tc.saved, "", 0).Return(saved, nil)
+			}
+
+			h := NewURLHandler(storage, mocks.NewDBPinger(t), nil, Option{})
+			respURLS, err := h.SaveURLS(context.Background(), tc.urls, "")
+			require.NoError(t, err)
+			require.Len(t, respURLS, len(tc.urls))
+
+			// результаты возвращаются в порядке запроса
+			for i, resp := range respURLS {
+				assert.Equal(t, tc.urls[i].CorrelationID, resp.CorrelationID)
+			}
+
+			invalid := respURLS[tc.invalid]
+			assert.Equal(t, models.BatchInvalid, invalid.Status)
+			assert.Empty(t, invalid.ShortURL)
+			assert.Contains(t, invalid.Reason, tc.expected.Error())
+		})
+	}
+}
+
+func TestSaveURLSNormalize(t *testing.T) {
+	storage := mocks.NewKeeperer(t)
+	storage.On("SaveURLS", mock.Anything, []models.BatchRequest{
+		{CorrelationID: "1", OriginalURL: "https://ya.ru/"},
+		{CorrelationID: "2", OriginalURL: "https://go.dev/doc?q=1"},
+	}, "", 0).Return([]models.BatchResponse{
+		{CorrelationID: "1", ShortURL: "abc", Status: models.BatchExisting},
+		{CorrelationID: "2", ShortURL: "def", Status: models.BatchCreated},
+	}, nil)
+
+	h := NewURLHandler(storage, mocks.NewDBPinger(t), nil, Option{
+		URLRules: URLRules{StripTracking: true},
+	})
+	_, err := h.SaveURLS(context.Background(), []models.BatchRequest{
+		{CorrelationID: "1", OriginalURL: "HTTPS://YA.RU:443"},
+		{CorrelationID: "2", OriginalURL: "https://Go.Dev/doc?utm_source=x&q=1"},
+	}, "")
+	assert.NoError(t, err)
+}
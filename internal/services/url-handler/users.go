@@ -0,0 +1,131 @@
+package urlhandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	dbkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/db-keeper"
+)
+
+// Ограничения на учетные данные
+const (
+	MinLoginLen    = 3
+	MaxLoginLen    = 64
+	MinPasswordLen = 8
+	// MaxPasswordLen ограничение bcrypt.
+	MaxPasswordLen = 72
+)
+
+// Ошибки пользователей
+var (
+	ErrLoginTaken         = errors.New("login is already taken")
+	ErrInvalidCredentials = errors.New("invalid login or password")
+	ErrInvalidUser        = errors.New("invalid user data")
+)
+
+// dummyPasswordHash хеш для сравнения при неизвестном логине:
+// время ответа не выдает, существует ли логин.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+// Register регистрирует пользователя и переносит к нему
+// URL анонимного пользователя anonUserID.
+func (uh *URLHandler) Register(
+	ctx context.Context,
+	creds models.Credentials,
+	anonUserID string,
+) (models.AuthResponse, error) {
+	login := strings.TrimSpace(creds.Login)
+	if err := validateCredentials(login, creds.Password); err != nil {
+		return models.AuthResponse{}, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(creds.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return models.AuthResponse{}, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user := models.User{
+		ID:           uuid.New().String(),
+		Login:        login,
+		PasswordHash: string(hash),
+		CreatedAt:    time.Now().UTC(),
+	}
+
+	if err := uh.storage.CreateUser(ctx, user); err != nil {
+		if errors.Is(err, dbkeeper.ErrLoginTaken) {
+			return models.AuthResponse{}, ErrLoginTaken
+		}
+		return models.AuthResponse{}, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return uh.claim(ctx, anonUserID, user.ID)
+}
+
+// Login проверяет пароль и переносит к пользователю
+// URL анонимного пользователя anonUserID.
+func (uh *URLHandler) Login(
+	ctx context.Context,
+	creds models.Credentials,
+	anonUserID string,
+) (models.AuthResponse, error) {
+	user, err := uh.storage.GetUserByLogin(ctx, strings.TrimSpace(creds.Login))
+	if err != nil {
+		if errors.Is(err, dbkeeper.ErrUserNotFound) {
+			_ = bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(creds.Password))
+			return models.AuthResponse{}, ErrInvalidCredentials
+		}
+		return models.AuthResponse{}, fmt.Errorf("failed to read user: %w", err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(creds.Password)); err != nil {
+		return models.AuthResponse{}, ErrInvalidCredentials
+	}
+
+	return uh.claim(ctx, anonUserID, user.ID)
+}
+
+// claim переносит URL анонимного пользователя.
+// Ошибка переноса не мешает входу, ссылки можно забрать при следующем входе.
+// Если с ними пользователь превысил бы квоту, не переносится ни одна ссылка.
+func (uh *URLHandler) claim(ctx context.Context, anonUserID, userID string) (models.AuthResponse, error) {
+	resp := models.AuthResponse{UserID: userID}
+	if len(anonUserID) == 0 || anonUserID == userID {
+		return resp, nil
+	}
+
+	claimed, err := uh.storage.ClaimURLS(ctx, anonUserID, userID, uh.quotas.MaxLinksPerUser)
+	if err != nil {
+		var quotaErr *dbkeeper.QuotaError
+		if errors.As(err, &quotaErr) {
+			return resp, linkQuotaError(quotaErr)
+		}
+		return resp, fmt.Errorf("failed to claim urls: %w", err)
+	}
+	resp.Claimed = claimed
+
+	return resp, nil
+}
+
+func validateCredentials(login, password string) error {
+	if len(login) < MinLoginLen || len(login) > MaxLoginLen {
+		return fmt.Errorf("%w: login length must be between %d and %d", ErrInvalidUser, MinLoginLen, MaxLoginLen)
+	}
+	if len(password) < MinPasswordLen || len(password) > MaxPasswordLen {
+		return fmt.Errorf("%w: password length must be between %d and %d", ErrInvalidUser, MinPasswordLen, MaxPasswordLen)
+	}
+	return nil
+}
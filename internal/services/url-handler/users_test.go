@@ -0,0 +1,188 @@
+package urlhandler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/services/url-handler/mocks"
+	dbkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/db-keeper"
+)
+
+const anonUserID = "a3f1c2d4-0000-0000-0000-000000000001"
+
+func TestRegister(t *testing.T) {
+
+	cases := []struct {
+		name            string
+		creds           models.Credentials
+		createErr       error
+		claimErr        error
+		isCallCreate    bool
+		expectedIs      error
+		expectedClaimed int64
+	}{
+		{
+			name:            "successful registration",
+			creds:           models.Credentials{Login: "gopher", Password: "password123"},
+			isCallCreate:    true,
+			expectedClaimed: 2,
+		},
+		{
+			name:         "login is taken",
+			creds:        models.Credentials{Login: "gopher", Password: "password123"},
+			createErr:    dbkeeper.ErrLoginTaken,
+			isCallCreate: true,
+			expectedIs:   ErrLoginTaken,
+		},
+		{
+			name:       "short password",
+			creds:      models.Credentials{Login: "gopher", Password: "123"},
+			expectedIs: ErrInvalidUser,
+		},
+		{
+			name:       "short login",
+			creds:      models.Credentials{Login: " g ", Password: "password123"},
+			expectedIs: ErrInvalidUser,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			storage := mocks.NewKeeperer(t)
+			if tc.isCallCreate {
+				storage.On("CreateUser", mock.Anything, mock.MatchedBy(func(u models.User) bool {
+					return u.Login == tc.creds.Login &&
+						bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(tc.creds.Password)) == nil
+				})).Return(tc.createErr)
+			}
+			if tc.isCallCreate && tc.createErr == nil {
+				storage.On("ClaimURLS", mock.Anything, anonUserID, mock.AnythingOfType("string"), 0).
+					Return(tc.expectedClaimed, tc.claimErr)
+			}
+
+			h := NewURLHandler(storage, mocks.NewDBPinger(t), nil, Option{})
+			resp, err := h.Register(context.Background(), tc.creds, anonUserID)
+
+			if tc.expectedIs != nil {
+				assert.ErrorIs(t, err, tc.expectedIs)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NotEmpty(t, resp.UserID)
+			assert.NotEqual(t, anonUserID, resp.UserID)
+			assert.Equal(t, tc.expectedClaimed, resp.Claimed)
+		})
+	}
+}
+
+func TestDummyPasswordHash(t *testing.T) {
+	cost, err := bcrypt.Cost(dummyPasswordHash())
+	require.NoError(t, err)
+	assert.Equal(t, bcrypt.DefaultCost, cost)
+}
+
+func TestLogin(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	user := models.User{
+		ID:           "a3f1c2d4-0000-0000-0000-000000000002",
+		Login:        "gopher",
+		PasswordHash: string(hash),
+	}
+
+	cases := []struct {
+		name        string
+		creds       models.Credentials
+		user        models.User
+		getErr      error
+		claimErr    error
+		isCallClaim bool
+		expectedIs  error
+		isError     bool
+	}{
+		{
+			name:        "successful login",
+			creds:       models.Credentials{Login: "gopher", Password: "password123"},
+			user:        user,
+			isCallClaim: true,
+		},
+		{
+			name:       "wrong password",
+			creds:      models.Credentials{Login: "gopher", Password: "password124"},
+			user:       user,
+			expectedIs: ErrInvalidCredentials,
+			isError:    true,
+		},
+		{
+			name:       "unknown user",
+			creds:      models.Credentials{Login: "unknown", Password: "password123"},
+			getErr:     dbkeeper.ErrUserNotFound,
+			expectedIs: ErrInvalidCredentials,
+			isError:    true,
+		},
+		{
+			name:        "claim failed",
+			creds:       models.Credentials{Login: "gopher", Password: "password123"},
+			user:        user,
+			claimErr:    errors.New("storage is unavailable"),
+			isCallClaim: true,
+			isError:     true,
+		},
+		{
+			name:        "claim over quota",
+			creds:       models.Credentials{Login: "gopher", Password: "password123"},
+			user:        user,
+			claimErr:    &dbkeeper.QuotaError{Limit: 1, Value: 2},
+			isCallClaim: true,
+			expectedIs:  ErrLinkQuotaExceeded,
+			isError:     true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			storage := mocks.NewKeeperer(t)
+			storage.On("GetUserByLogin", mock.Anything, tc.creds.Login).Return(tc.user, tc.getErr)
+			if tc.isCallClaim {
+				storage.On("ClaimURLS", mock.Anything, anonUserID, user.ID, 1).Return(int64(1), tc.claimErr)
+			}
+
+			h := NewURLHandler(storage, mocks.NewDBPinger(t), nil, Option{Quotas: Quotas{MaxLinksPerUser: 1}})
+			resp, err := h.Login(context.Background(), tc.creds, anonUserID)
+
+			if tc.isError {
+				assert.Error(t, err)
+				if tc.expectedIs != nil {
+					assert.ErrorIs(t, err, tc.expectedIs)
+				}
+				// ошибка переноса ссылок не мешает входу
+				if tc.isCallClaim {
+					assert.Equal(t, user.ID, resp.UserID)
+				} else {
+					assert.Empty(t, resp.UserID)
+				}
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, user.ID, resp.UserID)
+		})
+	}
+}
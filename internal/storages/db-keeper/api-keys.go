@@ -0,0 +1,152 @@
+package dbkeeper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+)
+
+// ErrAPIKeyNotFound ключ API не найден или отозван.
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// CreateAPIKey сохраняет ключ API.
+func (k *DBKeeper) CreateAPIKey(ctx context.Context, key models.APIKey) error {
+	sqlStatement := `
+		INSERT INTO api_keys (id, user_id, name, key_hash, scopes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := k.db.ExecContext(
+		ctx,
+		sqlStatement,
+		key.ID, key.UserID, key.Name, key.KeyHash, strings.Join(key.Scopes, ","), key.CreatedAt,
+	)
+	return err
+}
+
+// GetAPIKeys ключи API пользователя.
+func (k *DBKeeper) GetAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	sqlStatement := `
+		SELECT
+			id,
+			user_id,
+			name,
+			key_hash,
+			scopes,
+			created_at,
+			last_used_at
+		FROM
+			api_keys
+		WHERE
+			user_id = $1
+		ORDER BY
+			created_at;`
+
+	rows, err := k.db.QueryContext(ctx, sqlStatement, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// GetAPIKeyByHash ключ API по хешу.
+func (k *DBKeeper) GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error) {
+	sqlStatement := `
+		SELECT
+			id,
+			user_id,
+			name,
+			key_hash,
+			scopes,
+			created_at,
+			last_used_at
+		FROM
+			api_keys
+		WHERE
+			key_hash = $1;`
+
+	key, err := scanAPIKey(k.db.QueryRowContext(ctx, sqlStatement, keyHash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.APIKey{}, ErrAPIKeyNotFound
+		}
+		return models.APIKey{}, err
+	}
+
+	return key, nil
+}
+
+// RevokeAPIKey удаляет ключ API пользователя.
+func (k *DBKeeper) RevokeAPIKey(ctx context.Context, id string, userID string) error {
+	sqlStatement := `DELETE FROM api_keys WHERE id = $1 AND user_id = $2;`
+
+	res, err := k.db.ExecContext(ctx, sqlStatement, id, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// TouchAPIKey обновляет время последнего использования ключа API.
+func (k *DBKeeper) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	sqlStatement := `UPDATE api_keys SET last_used_at = $2 WHERE id = $1;`
+
+	_, err := k.db.ExecContext(ctx, sqlStatement, id, usedAt)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAPIKey(row rowScanner) (models.APIKey, error) {
+	key := models.APIKey{}
+	var (
+		scopes     string
+		lastUsedAt sql.NullTime
+	)
+
+	if err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.KeyHash,
+		&scopes,
+		&key.CreatedAt,
+		&lastUsedAt,
+	); err != nil {
+		return models.APIKey{}, err
+	}
+
+	if len(scopes) > 0 {
+		key.Scopes = strings.Split(scopes, ",")
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return key, nil
+}
@@ -0,0 +1,25 @@
+package dbkeeper
+
+import (
+	"context"
+)
+
+// BlockURLS блокирует (blocked = true) или разблокирует URL.
+// Возвращает количество измененных URL.
+func (k *DBKeeper) BlockURLS(ctx context.Context, shortURLS []string, blocked bool) (int64, error) {
+	sqlStatement := `
+		UPDATE shortened_url
+		SET
+			is_blocked = $2,
+			updated_at = now()
+		WHERE
+			short_url = ANY($1)
+			AND is_blocked <> $2;`
+
+	tag, err := k.dbPool.Exec(ctx, sqlStatement, shortURLS, blocked)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
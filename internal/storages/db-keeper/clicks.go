@@ -0,0 +1,144 @@
+package dbkeeper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+)
+
+// SaveClicks запись переходов по URL.
+// Переходы по уже удаленным URL пропускаются.
+func (k *DBKeeper) SaveClicks(ctx context.Context, clicks []models.Click) {
+
+	query := `
+		INSERT INTO url_clicks (short_url, clicked_at, referrer, user_agent, country)
+		SELECT
+			@shortURL, @clickedAt, @referrer, @userAgent, @country
+		WHERE
+			EXISTS (
+				SELECT 1 FROM shortened_url WHERE short_url = @shortURL
+			)`
+
+	batch := &pgx.Batch{}
+	for _, click := range clicks {
+		args := pgx.NamedArgs{
+			"shortURL":  click.ShortURL,
+			"clickedAt": click.Timestamp,
+			"referrer":  click.Referrer,
+			"userAgent": click.UserAgent,
+			"country":   click.Country,
+		}
+		batch.Queue(query, args)
+	}
+
+	results := k.dbPool.SendBatch(ctx, batch)
+	defer results.Close()
+
+	for _, click := range clicks {
+		if _, err := results.Exec(); err != nil {
+			k.log.Error("failed to save click",
+				zap.String("url", click.ShortURL),
+				zap.Error(err),
+			)
+		}
+	}
+
+	if err := results.Close(); err != nil {
+		k.log.Error("failed to close response batch",
+			zap.Error(err),
+		)
+	}
+}
+
+// GetStats статистика переходов по URL:
+// общее количество, по часам начиная с hourlyFrom и по дням начиная с dailyFrom.
+func (k *DBKeeper) GetStats(
+	ctx context.Context,
+	shortURL string,
+	userID string,
+	hourlyFrom, dailyFrom time.Time,
+) (models.URLStats, error) {
+	var owner sql.NullString
+	row := k.db.QueryRowContext(ctx,
+		`SELECT user_id FROM shortened_url WHERE short_url = $1;`,
+		shortURL,
+	)
+	if err := row.Scan(&owner); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.URLStats{}, ErrNotFound
+		}
+		return models.URLStats{}, err
+	}
+	if !owner.Valid || owner.String != userID {
+		return models.URLStats{}, ErrNotOwner
+	}
+
+	stats := models.URLStats{ShortURL: shortURL}
+
+	row = k.db.QueryRowContext(ctx,
+		`SELECT count(*) FROM url_clicks WHERE short_url = $1;`,
+		shortURL,
+	)
+	if err := row.Scan(&stats.Total); err != nil {
+		return models.URLStats{}, err
+	}
+
+	var err error
+	stats.Hourly, err = k.statsBuckets(ctx, "hour", shortURL, hourlyFrom)
+	if err != nil {
+		return models.URLStats{}, err
+	}
+
+	stats.Daily, err = k.statsBuckets(ctx, "day", shortURL, dailyFrom)
+	if err != nil {
+		return models.URLStats{}, err
+	}
+
+	return stats, nil
+}
+
+// statsBuckets количество переходов, сгруппированное по интервалам unit (hour, day) в UTC.
+func (k *DBKeeper) statsBuckets(
+	ctx context.Context,
+	unit string,
+	shortURL string,
+	from time.Time,
+) ([]models.StatsBucket, error) {
+	sqlStatement := `
+		SELECT
+			date_trunc($1, clicked_at AT TIME ZONE 'UTC') AS bucket,
+			count(*)
+		FROM
+			url_clicks
+		WHERE
+			short_url = $2
+			AND clicked_at >= $3
+		GROUP BY
+			bucket
+		ORDER BY
+			bucket;`
+
+	rows, err := k.db.QueryContext(ctx, sqlStatement, unit, shortURL, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := []models.StatsBucket{}
+	for rows.Next() {
+		bucket := models.StatsBucket{}
+		if err := rows.Scan(&bucket.Start, &bucket.Clicks); err != nil {
+			return nil, err
+		}
+		bucket.Start = bucket.Start.UTC()
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets, rows.Err()
+}
@@ -6,10 +6,13 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 
@@ -21,8 +24,35 @@ import (
 var (
 	ErrAlreadyExists = errors.New("the value already exists")
 	ErrURLRemoved    = errors.New("url has already been deleted")
+	ErrAliasTaken    = errors.New("alias is already taken")
+	ErrURLExpired    = errors.New("url has expired")
+	ErrNotFound      = errors.New("url not found")
+	ErrNotOwner      = errors.New("url belongs to another user")
+	ErrURLBlocked    = errors.New("url is blocked")
 )
 
+// AliasTakenError пользовательский алиас уже занят.
+type AliasTakenError struct {
+	Alias string
+}
+
+// Error описание ошибки.
+func (e *AliasTakenError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrAliasTaken, e.Alias)
+}
+
+// Unwrap позволяет проверить ошибку через errors.Is(err, ErrAliasTaken).
+func (e *AliasTakenError) Unwrap() error {
+	return ErrAliasTaken
+}
+
+// pkeyConstraint ограничение первичного ключа shortened_url.
+const pkeyConstraint = "shortened_url_pkey"
+
+// MaxShortURLLen ширина колонки short_url (VARCHAR(64)).
+// Более длинный алиас не поместится в БД.
+const MaxShortURLLen = 64
+
 // DBKeeper хранит подключения к БД.
 type DBKeeper struct {
 	db     *sql.DB
@@ -40,30 +70,65 @@ func NewDBKeeper(log *zap.Logger, db *sql.DB, dbPool *pgxpool.Pool) *DBKeeper {
 }
 
 // PostURL сохранение сокращенного URL.
-func (k *DBKeeper) PostURL(ctx context.Context, url string, userID string) (string, error) {
+// Если алиас не задан, он генерируется случайно.
+// Квота maxLinks на URL пользователя проверяется в той же транзакции,
+// 0 - без ограничения.
+func (k *DBKeeper) PostURL(ctx context.Context, req models.URLRequest, userID string, maxLinks int) (string, error) {
 
-	id, err := cryptoutils.GenerateRandomString(10)
+	id, err := aliasOrRandom(req.Alias)
 	if err != nil {
 		return "", err
 	}
 
+	tx, err := k.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			k.log.Error("fail rollback",
+				zap.Error(err),
+			)
+		}
+	}()
+
+	if err := lockQuota(ctx, tx, userID, maxLinks); err != nil {
+		return "", err
+	}
+
+	if _, err := tx.ExecContext(ctx, deleteExpiredConflict, req.URL, id); err != nil {
+		return "", err
+	}
+
+	// теги сохраняются тем же запросом
 	sqlStatement := `
-		INSERT INTO shortened_url (short_url, original_url, user_id)
-		VALUES ($1, $2, $3)`
+		WITH url AS (
+			INSERT INTO shortened_url (short_url, original_url, user_id, expires_at, creator_ip_hash, user_agent, folder)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING short_url
+		)
+		INSERT INTO url_tags (short_url, tag)
+		SELECT url.short_url, tag FROM url, unnest($8::varchar[]) AS tag`
 
-	_, err = k.db.ExecContext(
+	_, err = tx.ExecContext(
 		ctx,
 		sqlStatement,
-		id, url, NullUserID(userID),
+		id, req.URL, NullUserID(userID), req.ExpiresAt, req.Creator.IPHash, req.Creator.UserAgent,
+		req.Folder, req.Tags,
 	)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			if pgErr.ConstraintName == pkeyConstraint && len(req.Alias) > 0 {
+				return "", &AliasTakenError{Alias: req.Alias}
+			}
+
+			// транзакция прервана ошибкой, URL читается вне ее
 			sqlStatement := `SELECT short_url FROM shortened_url WHERE original_url=$1;`
 			row := k.db.QueryRowContext(
 				ctx,
 				sqlStatement,
-				url,
+				req.URL,
 			)
 			id := ""
 			if err := row.Scan(&id); err != nil {
@@ -75,59 +140,102 @@ func (k *DBKeeper) PostURL(ctx context.Context, url string, userID string) (stri
 
 		return "", err
 	}
+
+	if err := checkQuota(ctx, tx, userID, maxLinks); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
 	return id, nil
 }
 
+// deleteExpiredConflict удаляет истекший URL, занимающий оригинальный URL $1
+// или короткий URL $2: иначе повторное сокращение вернуло бы мертвую ссылку
+// до удаления истекших URL по расписанию.
+const deleteExpiredConflict = `
+	DELETE FROM shortened_url
+	WHERE
+		expires_at <= now()
+		AND (original_url = $1 OR short_url = $2);`
+
 // SaveURLS массовое сохранение URL.
-func (k *DBKeeper) SaveURLS(ctx context.Context, urls []models.BatchRequest, userID string) ([]models.BatchResponse, error) {
+// Результат для каждого элемента возвращается в порядке urls:
+// уже сокращенный URL - BatchExisting, занятый алиас - BatchInvalid.
+// Квота maxLinks учитывает только созданные URL: при превышении
+// пакет не сохраняется целиком.
+func (k *DBKeeper) SaveURLS(ctx context.Context, urls []models.BatchRequest, userID string, maxLinks int) ([]models.BatchResponse, error) {
 	tx, err := k.db.BeginTx(ctx, nil)
 
 	if err != nil {
 		return nil, err
 	}
 	defer func() {
-		if err := tx.Rollback(); err != nil {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
 			k.log.Error("fail rollback",
 				zap.Error(err),
 			)
 		}
 	}()
 
-	sqlStatement := `
-		INSERT INTO shortened_url(short_url, original_url, user_id)
-		VALUES ($1, $2, $3)`
+	if err := lockQuota(ctx, tx, userID, maxLinks); err != nil {
+		return nil, err
+	}
 
-	stmt, err := tx.PrepareContext(ctx, sqlStatement)
+	// ON CONFLICT не прерывает транзакцию на уже сохраненном URL
+	insertStmt, err := tx.PrepareContext(ctx, `
+		WITH url AS (
+			INSERT INTO shortened_url(short_url, original_url, user_id, expires_at, creator_ip_hash, user_agent, folder)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT DO NOTHING
+			RETURNING short_url
+		), tags AS (
+			INSERT INTO url_tags (short_url, tag)
+			SELECT url.short_url, tag FROM url, unnest($8::varchar[]) AS tag
+		)
+		SELECT short_url FROM url`)
 	if err != nil {
 		return nil, err
 	}
+	defer k.closeStmt(insertStmt)
 
-	defer func() {
-		if err := stmt.Close(); err != nil {
-			k.log.Error("failed close of prepared statement",
-				zap.Error(err),
-			)
-		}
-	}()
+	selectStmt, err := tx.PrepareContext(ctx, `SELECT short_url FROM shortened_url WHERE original_url=$1;`)
+	if err != nil {
+		return nil, err
+	}
+	defer k.closeStmt(selectStmt)
 
-	batchResp := make([]models.BatchResponse, 0, len(urls))
+	aliasStmt, err := tx.PrepareContext(ctx, `SELECT original_url FROM shortened_url WHERE short_url=$1;`)
+	if err != nil {
+		return nil, err
+	}
+	defer k.closeStmt(aliasStmt)
 
-	for _, url := range urls {
-		id, err := cryptoutils.GenerateRandomString(10)
-		if err != nil {
-			return nil, err
-		}
+	deleteStmt, err := tx.PrepareContext(ctx, deleteExpiredConflict)
+	if err != nil {
+		return nil, err
+	}
+	defer k.closeStmt(deleteStmt)
 
-		_, err = stmt.ExecContext(ctx, id, url.OriginalURL, NullUserID(userID))
+	stmts := batchStmts{
+		insert:     insertStmt,
+		byOriginal: selectStmt,
+		byAlias:    aliasStmt,
+		delete:     deleteStmt,
+	}
 
+	batchResp := make([]models.BatchResponse, 0, len(urls))
+	for _, url := range urls {
+		resp, err := saveBatchItem(ctx, stmts, url, userID)
 		if err != nil {
 			return nil, err
 		}
+		batchResp = append(batchResp, resp)
+	}
 
-		batchResp = append(batchResp, models.BatchResponse{
-			CorrelationID: url.CorrelationID,
-			ShortURL:      id,
-		})
+	if err := checkQuota(ctx, tx, userID, maxLinks); err != nil {
+		return nil, err
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -136,61 +244,311 @@ func (k *DBKeeper) SaveURLS(ctx context.Context, urls []models.BatchRequest, use
 	return batchResp, nil
 }
 
+// maxIDAttempts количество попыток сохранить URL со случайным
+// коротким URL, если сгенерированный URL уже занят.
+const maxIDAttempts = 3
+
+// batchStmts подготовленные в транзакции запросы SaveURLS.
+type batchStmts struct {
+	insert     *sql.Stmt
+	byOriginal *sql.Stmt
+	byAlias    *sql.Stmt
+	delete     *sql.Stmt
+}
+
+// saveBatchItem сохраняет элемент пакета.
+// Алиас, занятый другим оригинальным URL, - BatchInvalid, даже если
+// оригинальный URL уже сокращен. Занятый случайный короткий URL
+// генерируется заново.
+func saveBatchItem(ctx context.Context, stmts batchStmts, url models.BatchRequest, userID string) (models.BatchResponse, error) {
+	resp := models.BatchResponse{CorrelationID: url.CorrelationID}
+
+	for attempt := 1; ; attempt++ {
+		id, err := aliasOrRandom(url.Alias)
+		if err != nil {
+			return resp, err
+		}
+
+		if _, err := stmts.delete.ExecContext(ctx, url.OriginalURL, id); err != nil {
+			return resp, err
+		}
+
+		err = stmts.insert.QueryRowContext(
+			ctx,
+			id, url.OriginalURL, NullUserID(userID), url.ExpiresAt, url.Creator.IPHash, url.Creator.UserAgent,
+			url.Folder, url.Tags,
+		).Scan(&resp.ShortURL)
+		switch {
+		case err == nil:
+			resp.Status = models.BatchCreated
+			return resp, nil
+		case !errors.Is(err, sql.ErrNoRows):
+			return resp, err
+		}
+
+		// конфликт по короткому или оригинальному URL
+		if len(url.Alias) > 0 {
+			var original string
+			err := stmts.byAlias.QueryRowContext(ctx, url.Alias).Scan(&original)
+			switch {
+			case err == nil && original != url.OriginalURL:
+				resp.Status = models.BatchInvalid
+				resp.Reason = (&AliasTakenError{Alias: url.Alias}).Error()
+				return resp, nil
+			case err != nil && !errors.Is(err, sql.ErrNoRows):
+				return resp, err
+			}
+		}
+
+		err = stmts.byOriginal.QueryRowContext(ctx, url.OriginalURL).Scan(&resp.ShortURL)
+		switch {
+		case err == nil:
+			resp.Status = models.BatchExisting
+			return resp, nil
+		case !errors.Is(err, sql.ErrNoRows):
+			return resp, err
+		case len(url.Alias) > 0:
+			return resp, fmt.Errorf("alias %s conflicted with a concurrently removed url", url.Alias)
+		case attempt == maxIDAttempts:
+			return resp, fmt.Errorf("generated short url %s collided %d times", id, attempt)
+		}
+	}
+}
+
+// closeStmt закрывает подготовленный запрос с записью ошибки в лог.
+func (k *DBKeeper) closeStmt(stmt *sql.Stmt) {
+	if err := stmt.Close(); err != nil {
+		k.log.Error("failed close of prepared statement",
+			zap.Error(err),
+		)
+	}
+}
+
 // GetURL чтение оригинального URL.
 func (k *DBKeeper) GetURL(ctx context.Context, id string) (string, error) {
-	sqlStatement := `SELECT original_url, is_deleted FROM shortened_url WHERE short_url=$1;`
+	sqlStatement := `SELECT original_url, is_deleted, is_blocked, expires_at FROM shortened_url WHERE short_url=$1;`
 
 	row := k.db.QueryRowContext(ctx, sqlStatement, id)
 
 	var fullURL string
-	var deleted bool
+	var deleted, blocked bool
+	var expiresAt sql.NullTime
 
-	err := row.Scan(&fullURL, &deleted)
+	err := row.Scan(&fullURL, &deleted, &blocked, &expiresAt)
 	if err != nil {
 		return "", fmt.Errorf("records for the key %s do not exist", id)
 	}
 	if deleted {
 		return "", ErrURLRemoved
 	}
+	if blocked {
+		return "", ErrURLBlocked
+	}
+	if expiresAt.Valid && !expiresAt.Time.After(time.Now()) {
+		return "", ErrURLExpired
+	}
 
 	return fullURL, nil
 }
 
-// GetURLS список сокращенных URL пользователя.
-func (k *DBKeeper) GetURLS(ctx context.Context, userID string) ([]models.MassURL, error) {
-	sqlStatement := `
+// likeEscaper экранирует спецсимволы шаблона LIKE.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// GetURLS страница списка сокращенных URL пользователя.
+// URL упорядочены по времени создания и короткому URL,
+// следующая страница начинается после query.After.
+func (k *DBKeeper) GetURLS(ctx context.Context, query models.URLQuery) (models.URLPage, error) {
+	var sb strings.Builder
+	args := []any{query.UserID}
+
+	sb.WriteString(`
 		SELECT
 			short_url,
-			original_url
+			original_url,
+			ARRAY(
+				SELECT tag FROM url_tags WHERE url_tags.short_url = shortened_url.short_url ORDER BY tag
+			),
+			folder,
+			deleted_at,
+			created_at,
+			updated_at,
+			creator_ip_hash,
+			user_agent
 		FROM
 			shortened_url
 		WHERE
-			user_id = $1;`
+			user_id = $1`)
+
+	switch query.Deleted {
+	case models.DeletedInclude:
+	case models.DeletedOnly:
+		sb.WriteString(`
+			AND is_deleted`)
+	default:
+		sb.WriteString(`
+			AND NOT is_deleted`)
+	}
 
-	rows, err := k.db.QueryContext(ctx, sqlStatement, userID)
-	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			return []models.MassURL{}, nil
+	if len(query.Contains) > 0 {
+		args = append(args, likeEscaper.Replace(query.Contains))
+		fmt.Fprintf(&sb, `
+			AND original_url ILIKE '%%' || $%d || '%%'`, len(args))
+	}
 
-		default:
-			return nil, err
-		}
+	if len(query.Tag) > 0 {
+		args = append(args, query.Tag)
+		fmt.Fprintf(&sb, `
+			AND EXISTS (
+				SELECT 1 FROM url_tags WHERE url_tags.short_url = shortened_url.short_url AND tag = $%d
+			)`, len(args))
 	}
 
-	if rows.Err() != nil {
-		return nil, err
+	if len(query.Folder) > 0 {
+		args = append(args, query.Folder)
+		fmt.Fprintf(&sb, `
+			AND folder = $%d`, len(args))
+	}
+
+	order, cmp := "DESC", "<"
+	if query.Sort == models.SortCreatedAsc {
+		order, cmp = "ASC", ">"
+	}
+
+	if query.After != nil {
+		args = append(args, query.After.CreatedAt, query.After.ShortURL)
+		fmt.Fprintf(&sb, `
+			AND (created_at, short_url) %s ($%d, $%d)`, cmp, len(args)-1, len(args))
+	}
+
+	fmt.Fprintf(&sb, `
+		ORDER BY
+			created_at %s,
+			short_url %s`, order, order)
+
+	// лишняя строка показывает, что есть следующая страница
+	if query.Limit > 0 {
+		args = append(args, query.Limit+1)
+		fmt.Fprintf(&sb, `
+		LIMIT
+			$%d`, len(args))
+	}
+
+	rows, err := k.db.QueryContext(ctx, sb.String(), args...)
+	if err != nil {
+		return models.URLPage{}, err
 	}
+	defer rows.Close()
 
-	urls := []models.MassURL{}
+	page := models.URLPage{URLs: []models.MassURL{}}
+	typeMap := pgtype.NewMap()
+	var last models.URLCursor
 	for rows.Next() {
+		if query.Limit > 0 && len(page.URLs) == query.Limit {
+			page.Next = &last
+			break
+		}
+
 		url := models.MassURL{}
-		err = rows.Scan(&url.ShortURL, &url.OriginalURL)
+		var deletedAt sql.NullTime
+		var createdAt, updatedAt time.Time
+		err = rows.Scan(
+			&url.ShortURL, &url.OriginalURL, typeMap.SQLScanner(&url.Tags), &url.Folder, &deletedAt,
+			&createdAt, &updatedAt, &url.CreatorIPHash, &url.UserAgent,
+		)
 		if err != nil {
-			return nil, err
+			return models.URLPage{}, err
 		}
-		urls = append(urls, url)
+		if deletedAt.Valid {
+			url.DeletedAt = &deletedAt.Time
+		}
+		url.CreatedAt, url.UpdatedAt = &createdAt, &updatedAt
+		page.URLs = append(page.URLs, url)
+		last = models.URLCursor{CreatedAt: createdAt, ShortURL: url.ShortURL}
+	}
+
+	if err := rows.Err(); err != nil {
+		return models.URLPage{}, err
+	}
+	return page, nil
+}
+
+// aliasOrRandom пользовательский алиас или случайный, если он не задан.
+func aliasOrRandom(alias string) (string, error) {
+	if len(alias) > 0 {
+		return alias, nil
+	}
+	return cryptoutils.GenerateRandomString(10)
+}
+
+// DeleteExpired окончательно удаляет до limit истекших URL.
+func (k *DBKeeper) DeleteExpired(ctx context.Context, limit int) (int64, error) {
+	sqlStatement := `
+		DELETE FROM shortened_url
+		WHERE
+			short_url IN (
+				SELECT
+					short_url
+				FROM
+					shortened_url
+				WHERE
+					expires_at <= now()
+				LIMIT
+					$1
+			);`
+
+	res, err := k.db.ExecContext(ctx, sqlStatement, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// CountURLS количество URL: всего, удаленных и истекших.
+func (k *DBKeeper) CountURLS(ctx context.Context) (models.URLCounts, error) {
+	sqlStatement := `
+		SELECT
+			count(*),
+			count(*) FILTER (WHERE is_deleted),
+			count(*) FILTER (WHERE expires_at <= now())
+		FROM
+			shortened_url;`
+
+	counts := models.URLCounts{}
+	row := k.db.QueryRowContext(ctx, sqlStatement)
+	if err := row.Scan(&counts.Total, &counts.Deleted, &counts.Expired); err != nil {
+		return models.URLCounts{}, err
+	}
+
+	return counts, nil
+}
+
+// CountUsers количество пользователей, сохранивших хотя бы один URL.
+func (k *DBKeeper) CountUsers(ctx context.Context) (int64, error) {
+	sqlStatement := `SELECT count(DISTINCT user_id) FROM shortened_url;`
+
+	var users int64
+	row := k.db.QueryRowContext(ctx, sqlStatement)
+	if err := row.Scan(&users); err != nil {
+		return 0, err
 	}
+
+	return users, nil
+}
+
+// CountUserURLS количество неудаленных URL пользователя.
+func (k *DBKeeper) CountUserURLS(ctx context.Context, userID string) (int64, error) {
+	sqlStatement := `
+		SELECT count(*)
+		FROM shortened_url
+		WHERE user_id = $1 AND NOT is_deleted;`
+
+	var urls int64
+	row := k.db.QueryRowContext(ctx, sqlStatement, userID)
+	if err := row.Scan(&urls); err != nil {
+		return 0, err
+	}
+
 	return urls, nil
 }
 
@@ -208,16 +566,20 @@ func NullUserID(userID string) sql.NullString {
 
 }
 
-// DeleteURLS удаление URL
+// DeleteURLS удаление URL.
+// Время удаления повторно удаленного URL не меняется.
 func (k *DBKeeper) DeleteURLS(ctx context.Context, shortURLS []models.DeleteURL) {
 
 	query := `
 		UPDATE shortened_url
 		SET
-			is_deleted = true
+			is_deleted = true,
+			deleted_at = now(),
+			updated_at = now()
 		WHERE
 			short_url = @shortURL
-			AND user_id = @userID`
+			AND user_id = @userID
+			AND NOT is_deleted`
 
 	batch := &pgx.Batch{}
 	for _, url := range shortURLS {
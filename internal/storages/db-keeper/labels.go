@@ -0,0 +1,136 @@
+package dbkeeper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+)
+
+// UpdateLabels заменяет теги и папку URL пользователя.
+// Возвращает URL после изменения.
+func (k *DBKeeper) UpdateLabels(ctx context.Context, shortURL string, userID string, labels models.URLLabels) (models.MassURL, error) {
+	tx, err := k.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.MassURL{}, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			k.log.Error("fail rollback",
+				zap.Error(err),
+			)
+		}
+	}()
+
+	url := models.MassURL{ShortURL: shortURL}
+	var owner sql.NullString
+	var deleted bool
+	row := tx.QueryRowContext(ctx,
+		`SELECT original_url, folder, user_id, is_deleted FROM shortened_url WHERE short_url = $1 FOR UPDATE;`,
+		shortURL,
+	)
+	if err := row.Scan(&url.OriginalURL, &url.Folder, &owner, &deleted); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.MassURL{}, ErrNotFound
+		}
+		return models.MassURL{}, err
+	}
+	if !owner.Valid || owner.String != userID {
+		return models.MassURL{}, ErrNotOwner
+	}
+	if deleted {
+		return models.MassURL{}, ErrURLRemoved
+	}
+
+	if err := applyLabels(ctx, tx, &url, labels); err != nil {
+		return models.MassURL{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.MassURL{}, err
+	}
+	return url, nil
+}
+
+// applyLabels заменяет теги и папку URL, заблокированного в транзакции tx,
+// и читает теги url после изменения.
+func applyLabels(ctx context.Context, tx *sql.Tx, url *models.MassURL, labels models.URLLabels) error {
+	if labels.Folder != nil {
+		url.Folder = *labels.Folder
+	}
+
+	if labels.Folder != nil || labels.Tags != nil {
+		_, err := tx.ExecContext(ctx,
+			`UPDATE shortened_url SET folder = $2, updated_at = now() WHERE short_url = $1;`,
+			url.ShortURL, url.Folder,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if labels.Tags != nil {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM url_tags WHERE short_url = $1;`, url.ShortURL); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO url_tags (short_url, tag) SELECT $1, tag FROM unnest($2::varchar[]) AS tag;`,
+			url.ShortURL, *labels.Tags,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	row := tx.QueryRowContext(ctx,
+		`SELECT ARRAY(SELECT tag FROM url_tags WHERE short_url = $1 ORDER BY tag);`,
+		url.ShortURL,
+	)
+	return row.Scan(pgtype.NewMap().SQLScanner(&url.Tags))
+}
+
+// GetTags теги неудаленных URL пользователя с количеством URL,
+// начиная с самых частых.
+func (k *DBKeeper) GetTags(ctx context.Context, userID string) ([]models.TagCount, error) {
+	sqlStatement := `
+		SELECT
+			url_tags.tag,
+			count(*)
+		FROM
+			url_tags
+			JOIN shortened_url ON shortened_url.short_url = url_tags.short_url
+		WHERE
+			shortened_url.user_id = $1
+			AND NOT shortened_url.is_deleted
+		GROUP BY
+			url_tags.tag
+		ORDER BY
+			count(*) DESC,
+			url_tags.tag;`
+
+	rows, err := k.db.QueryContext(ctx, sqlStatement, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.TagCount{}
+	for rows.Next() {
+		tag := models.TagCount{}
+		if err := rows.Scan(&tag.Tag, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,291 @@
+// migrations версионные миграции схемы postgres
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+//go:embed sql/*.sql
+var embedded embed.FS
+
+// lockID ключ advisory lock, под которым выполняются миграции.
+const lockID int64 = 7_310_925_101
+
+// Направления миграций
+const (
+	DirectionUp   = "up"   // применить все новые миграции
+	DirectionDown = "down" // откатить последнюю миграцию
+)
+
+type migration struct {
+	version int64
+	name    string
+	up      string
+	down    string
+}
+
+// Migrator применяет и откатывает миграции.
+type Migrator struct {
+	log        *zap.Logger
+	db         *sql.DB
+	migrations []migration
+}
+
+// NewMigrator конструктор Migrator со встроенным набором миграций.
+func NewMigrator(log *zap.Logger, db *sql.DB) (*Migrator, error) {
+	sqlFS, err := fs.Sub(embedded, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := load(sqlFS)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		log:        log,
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Run выполняет миграции в заданном направлении.
+func (m *Migrator) Run(ctx context.Context, direction string) error {
+	switch direction {
+	case DirectionUp:
+		return m.Up(ctx)
+	case DirectionDown:
+		return m.Down(ctx)
+	default:
+		return fmt.Errorf("unknown migration direction %q", direction)
+	}
+}
+
+// Up применяет все еще не примененные миграции.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mg := range m.migrations {
+			if applied[mg.version] {
+				continue
+			}
+
+			if err := m.apply(ctx, conn, mg.up,
+				`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`,
+				mg.version, mg.name,
+			); err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", mg.version, mg.name, err)
+			}
+
+			m.log.Info("migration applied",
+				zap.Int64("version", mg.version),
+				zap.String("name", mg.name),
+			)
+		}
+
+		return nil
+	})
+}
+
+// Down откатывает последнюю примененную миграцию.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mg := m.migrations[i]
+			if !applied[mg.version] {
+				continue
+			}
+
+			if len(mg.down) == 0 {
+				return fmt.Errorf("migration %d_%s has no down script", mg.version, mg.name)
+			}
+
+			if err := m.apply(ctx, conn, mg.down,
+				`DELETE FROM schema_migrations WHERE version = $1`,
+				mg.version,
+			); err != nil {
+				return fmt.Errorf("failed to roll back migration %d_%s: %w", mg.version, mg.name, err)
+			}
+
+			m.log.Info("migration rolled back",
+				zap.Int64("version", mg.version),
+				zap.String("name", mg.name),
+			)
+			return nil
+		}
+
+		m.log.Info("no migrations to roll back")
+		return nil
+	})
+}
+
+// withLock выполняет fn на одном соединении под advisory lock,
+// чтобы несколько инстансов не применяли миграции одновременно.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID); err != nil {
+			m.log.Error("failed to release migration lock", zap.Error(err))
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE
+			IF NOT EXISTS schema_migrations (
+				version BIGINT PRIMARY KEY,
+				name VARCHAR(255) NOT NULL,
+				applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+			);`); err != nil {
+		return fmt.Errorf("failed to create table schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// apply выполняет скрипт миграции и обновляет историю в одной транзакции.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, script string, history string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, history, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]bool, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]bool{}
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+
+	return applied, rows.Err()
+}
+
+// load читает миграции вида 0001_name.up.sql / 0001_name.down.sql.
+func load(fsys fs.FS) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*migration{}
+
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		version, name, direction, err := parseName(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mg, ok := byVersion[version]
+		if !ok {
+			mg = &migration{version: version, name: name}
+			byVersion[version] = mg
+		}
+
+		if mg.name != name {
+			return nil, fmt.Errorf("migration %d has different names: %s, %s", version, mg.name, name)
+		}
+
+		switch direction {
+		case DirectionUp:
+			mg.up = string(data)
+		case DirectionDown:
+			mg.down = string(data)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, mg := range byVersion {
+		if len(mg.up) == 0 {
+			return nil, fmt.Errorf("migration %d_%s has no up script", mg.version, mg.name)
+		}
+		migrations = append(migrations, *mg)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	return migrations, nil
+}
+
+func parseName(fileName string) (version int64, name string, direction string, err error) {
+	base := strings.TrimSuffix(fileName, ".sql")
+
+	dot := strings.LastIndex(base, ".")
+	if dot < 0 {
+		return 0, "", "", fmt.Errorf("invalid migration file name %s", fileName)
+	}
+	base, direction = base[:dot], base[dot+1:]
+	if direction != DirectionUp && direction != DirectionDown {
+		return 0, "", "", fmt.Errorf("invalid migration direction in %s", fileName)
+	}
+
+	num, name, ok := strings.Cut(base, "_")
+	if !ok || len(name) == 0 {
+		return 0, "", "", fmt.Errorf("invalid migration file name %s", fileName)
+	}
+
+	version, err = strconv.ParseInt(num, 10, 64)
+	if err != nil {
+		return 0, "", "", fmt.Errorf("invalid migration version in %s: %w", fileName, err)
+	}
+
+	return version, name, direction, nil
+}
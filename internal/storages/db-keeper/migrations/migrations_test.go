@@ -0,0 +1,93 @@
+package migrations
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	cases := []struct {
+		name             string
+		fsys             fstest.MapFS
+		expectedVersions []int64
+		isError          bool
+	}{
+		{
+			name: "sorted by version",
+			fsys: fstest.MapFS{
+				"0002_second.up.sql":   {Data: []byte("SELECT 2;")},
+				"0002_second.down.sql": {Data: []byte("SELECT -2;")},
+				"0001_first.up.sql":    {Data: []byte("SELECT 1;")},
+				"README.md":            {Data: []byte("docs")},
+			},
+			expectedVersions: []int64{1, 2},
+		},
+		{
+			name: "no up script",
+			fsys: fstest.MapFS{
+				"0001_first.down.sql": {Data: []byte("SELECT 1;")},
+			},
+			isError: true,
+		},
+		{
+			name: "invalid version",
+			fsys: fstest.MapFS{
+				"first.up.sql": {Data: []byte("SELECT 1;")},
+			},
+			isError: true,
+		},
+		{
+			name: "invalid direction",
+			fsys: fstest.MapFS{
+				"0001_first.sideways.sql": {Data: []byte("SELECT 1;")},
+			},
+			isError: true,
+		},
+		{
+			name: "different names for one version",
+			fsys: fstest.MapFS{
+				"0001_first.up.sql":   {Data: []byte("SELECT 1;")},
+				"0001_other.down.sql": {Data: []byte("SELECT 1;")},
+			},
+			isError: true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			migrations, err := load(tc.fsys)
+			if tc.isError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			versions := make([]int64, 0, len(migrations))
+			for _, mg := range migrations {
+				versions = append(versions, mg.version)
+			}
+			assert.Equal(t, tc.expectedVersions, versions)
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	sqlFS, err := fs.Sub(embedded, "sql")
+	require.NoError(t, err)
+
+	migrations, err := load(sqlFS)
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, mg := range migrations {
+		assert.Equal(t, int64(i+1), mg.version, "migrations must be numbered without gaps")
+		assert.NotEmpty(t, mg.down, "migration %d_%s has no down script", mg.version, mg.name)
+	}
+}
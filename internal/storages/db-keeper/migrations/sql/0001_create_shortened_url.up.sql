@@ -0,0 +1,7 @@
+CREATE TABLE
+    IF NOT EXISTS shortened_url (
+        short_url VARCHAR(10) PRIMARY KEY,
+        original_url VARCHAR(4000) NOT NULL
+    );
+
+CREATE UNIQUE INDEX IF NOT EXISTS orig_url_idx ON shortened_url (original_url);
@@ -0,0 +1 @@
+ALTER TABLE shortened_url DROP COLUMN IF EXISTS user_id;
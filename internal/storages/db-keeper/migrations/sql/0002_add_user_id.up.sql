@@ -0,0 +1 @@
+ALTER TABLE shortened_url ADD COLUMN IF NOT EXISTS user_id UUID;
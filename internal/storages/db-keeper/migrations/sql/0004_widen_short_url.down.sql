@@ -0,0 +1 @@
+ALTER TABLE shortened_url ALTER COLUMN short_url TYPE VARCHAR(10);
@@ -0,0 +1,4 @@
+ALTER TABLE shortened_url ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS expires_at_idx ON shortened_url (expires_at)
+WHERE expires_at IS NOT NULL;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS url_clicks (
+    id BIGSERIAL PRIMARY KEY,
+    short_url VARCHAR(64) NOT NULL REFERENCES shortened_url (short_url) ON DELETE CASCADE,
+    clicked_at TIMESTAMPTZ NOT NULL,
+    referrer VARCHAR(255) NOT NULL DEFAULT '',
+    user_agent VARCHAR(16) NOT NULL,
+    country VARCHAR(16) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS url_clicks_short_url_clicked_at_idx ON url_clicks (short_url, clicked_at);
@@ -0,0 +1 @@
+ALTER TABLE shortened_url ADD COLUMN IF NOT EXISTS is_blocked BOOLEAN NOT NULL DEFAULT false;
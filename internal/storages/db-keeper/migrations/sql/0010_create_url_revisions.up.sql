@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS url_revisions (
+    short_url VARCHAR(64) NOT NULL REFERENCES shortened_url (short_url) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    original_url VARCHAR(4000) NOT NULL,
+    user_id UUID NOT NULL,
+    replaced_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (short_url, revision)
+);
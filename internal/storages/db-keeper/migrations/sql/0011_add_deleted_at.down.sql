@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS deleted_at_idx;
+
+ALTER TABLE shortened_url DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,7 @@
+ALTER TABLE shortened_url ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+-- URL, удаленные до появления колонки, получают полный срок восстановления
+UPDATE shortened_url SET deleted_at = now() WHERE is_deleted AND deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS deleted_at_idx ON shortened_url (deleted_at)
+WHERE deleted_at IS NOT NULL;
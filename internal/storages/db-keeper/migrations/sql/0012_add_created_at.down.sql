@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS user_id_created_at_idx;
+
+ALTER TABLE shortened_url DROP COLUMN IF EXISTS created_at;
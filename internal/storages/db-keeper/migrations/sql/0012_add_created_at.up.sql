@@ -0,0 +1,3 @@
+ALTER TABLE shortened_url ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS user_id_created_at_idx ON shortened_url (user_id, created_at, short_url);
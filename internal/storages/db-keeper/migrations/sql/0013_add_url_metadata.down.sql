@@ -0,0 +1,3 @@
+ALTER TABLE shortened_url DROP COLUMN IF EXISTS user_agent;
+ALTER TABLE shortened_url DROP COLUMN IF EXISTS creator_ip_hash;
+ALTER TABLE shortened_url DROP COLUMN IF EXISTS updated_at;
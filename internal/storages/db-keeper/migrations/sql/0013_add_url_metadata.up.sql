@@ -0,0 +1,5 @@
+ALTER TABLE shortened_url ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE shortened_url ADD COLUMN IF NOT EXISTS creator_ip_hash VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE shortened_url ADD COLUMN IF NOT EXISTS user_agent VARCHAR(512) NOT NULL DEFAULT '';
+
+UPDATE shortened_url SET updated_at = created_at;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS url_tags;
+
+DROP INDEX IF EXISTS user_id_folder_idx;
+
+ALTER TABLE shortened_url DROP COLUMN IF EXISTS folder;
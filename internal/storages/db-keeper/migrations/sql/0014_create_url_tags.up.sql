@@ -0,0 +1,12 @@
+ALTER TABLE shortened_url ADD COLUMN IF NOT EXISTS folder VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS user_id_folder_idx ON shortened_url (user_id, folder)
+WHERE folder <> '';
+
+CREATE TABLE IF NOT EXISTS url_tags (
+    short_url VARCHAR(64) NOT NULL REFERENCES shortened_url (short_url) ON DELETE CASCADE,
+    tag VARCHAR(64) NOT NULL,
+    PRIMARY KEY (short_url, tag)
+);
+
+CREATE INDEX IF NOT EXISTS url_tags_tag_idx ON url_tags (tag);
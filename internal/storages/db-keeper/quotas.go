@@ -0,0 +1,62 @@
+package dbkeeper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrQuotaExceeded превышена квота на количество URL пользователя.
+var ErrQuotaExceeded = errors.New("link quota exceeded")
+
+// QuotaError у пользователя стало бы Value URL при квоте Limit.
+type QuotaError struct {
+	Limit int
+	Value int
+}
+
+// Error описание ошибки.
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s: %d exceeds limit %d", ErrQuotaExceeded, e.Value, e.Limit)
+}
+
+// Unwrap позволяет проверить ошибку через errors.Is(err, ErrQuotaExceeded).
+func (e *QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// lockQuota сериализует сохранение URL пользователя до конца транзакции tx.
+// Строки пользователя в users может не быть, поэтому вместо
+// SELECT ... FOR UPDATE используется advisory lock по user_id.
+// maxLinks 0 или анонимный пользователь - без ограничения.
+func lockQuota(ctx context.Context, tx *sql.Tx, userID string, maxLinks int) error {
+	if maxLinks < 1 || len(userID) == 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1));`, userID)
+	return err
+}
+
+// checkQuota проверяет квоту после записи в транзакции tx,
+// поэтому учитываются только действительно созданные URL.
+// Вызывается после lockQuota.
+func checkQuota(ctx context.Context, tx *sql.Tx, userID string, maxLinks int) error {
+	if maxLinks < 1 || len(userID) == 0 {
+		return nil
+	}
+
+	var urls int
+	row := tx.QueryRowContext(ctx,
+		`SELECT count(*) FROM shortened_url WHERE user_id = $1 AND NOT is_deleted;`,
+		userID,
+	)
+	if err := row.Scan(&urls); err != nil {
+		return err
+	}
+
+	if urls > maxLinks {
+		return &QuotaError{Limit: maxLinks, Value: urls}
+	}
+	return nil
+}
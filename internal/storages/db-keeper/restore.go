@@ -0,0 +1,105 @@
+package dbkeeper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RestoreURLS восстанавливает удаленные URL пользователя,
+// удаленные позже deletedAfter. Возвращает восстановленные URL.
+// Квота maxLinks учитывает только восстановленные URL,
+// 0 - без ограничения.
+func (k *DBKeeper) RestoreURLS(
+	ctx context.Context,
+	shortURLS []string,
+	userID string,
+	deletedAfter time.Time,
+	maxLinks int,
+) ([]string, error) {
+	tx, err := k.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			k.log.Error("fail rollback",
+				zap.Error(err),
+			)
+		}
+	}()
+
+	if err := lockQuota(ctx, tx, userID, maxLinks); err != nil {
+		return nil, err
+	}
+
+	sqlStatement := `
+		UPDATE shortened_url
+		SET
+			is_deleted = false,
+			deleted_at = NULL,
+			updated_at = now()
+		WHERE
+			short_url = ANY($1)
+			AND user_id = $2
+			AND is_deleted
+			AND deleted_at > $3
+		RETURNING short_url;`
+
+	rows, err := tx.QueryContext(ctx, sqlStatement, shortURLS, userID, deletedAfter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	restored := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		restored = append(restored, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := checkQuota(ctx, tx, userID, maxLinks); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// PurgeDeleted окончательно удаляет до limit URL,
+// удаленных не позже deletedBefore.
+func (k *DBKeeper) PurgeDeleted(ctx context.Context, deletedBefore time.Time, limit int) (int64, error) {
+	sqlStatement := `
+		DELETE FROM shortened_url
+		WHERE
+			short_url IN (
+				SELECT
+					short_url
+				FROM
+					shortened_url
+				WHERE
+					is_deleted
+					AND deleted_at <= $1
+				LIMIT
+					$2
+			);`
+
+	res, err := k.db.ExecContext(ctx, sqlStatement, deletedBefore, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,145 @@
+package dbkeeper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+)
+
+// UpdateURL заменяет оригинальный URL пользователя,
+// прежнее значение сохраняется в истории изменений.
+// Теги и папка из labels меняются в той же транзакции.
+// Возвращает URL после изменения.
+// Оригинальный URL, уже сокращенный другим URL, - ErrAlreadyExists.
+func (k *DBKeeper) UpdateURL(
+	ctx context.Context,
+	shortURL string,
+	userID string,
+	originalURL string,
+	labels models.URLLabels,
+) (models.MassURL, error) {
+	tx, err := k.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.MassURL{}, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			k.log.Error("fail rollback",
+				zap.Error(err),
+			)
+		}
+	}()
+
+	url := models.MassURL{ShortURL: shortURL}
+	var current string
+	var owner sql.NullString
+	var deleted bool
+	row := tx.QueryRowContext(ctx,
+		`SELECT original_url, folder, user_id, is_deleted FROM shortened_url WHERE short_url = $1 FOR UPDATE;`,
+		shortURL,
+	)
+	if err := row.Scan(&current, &url.Folder, &owner, &deleted); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.MassURL{}, ErrNotFound
+		}
+		return models.MassURL{}, err
+	}
+	if !owner.Valid || owner.String != userID {
+		return models.MassURL{}, ErrNotOwner
+	}
+	if deleted {
+		return models.MassURL{}, ErrURLRemoved
+	}
+	url.OriginalURL = originalURL
+
+	if current != originalURL {
+		_, err = tx.ExecContext(ctx,
+			`UPDATE shortened_url SET original_url = $2, updated_at = now() WHERE short_url = $1;`,
+			shortURL, originalURL,
+		)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+				return models.MassURL{}, ErrAlreadyExists
+			}
+			return models.MassURL{}, err
+		}
+
+		sqlStatement := `
+			INSERT INTO url_revisions (short_url, revision, original_url, user_id)
+			SELECT
+				$1, COALESCE(max(revision), 0) + 1, $2, $3
+			FROM
+				url_revisions
+			WHERE
+				short_url = $1;`
+
+		if _, err := tx.ExecContext(ctx, sqlStatement, shortURL, current, userID); err != nil {
+			return models.MassURL{}, err
+		}
+	}
+
+	if err := applyLabels(ctx, tx, &url, labels); err != nil {
+		return models.MassURL{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.MassURL{}, err
+	}
+	return url, nil
+}
+
+// GetURLHistory прежние оригинальные URL, начиная с последнего.
+func (k *DBKeeper) GetURLHistory(ctx context.Context, shortURL string, userID string) ([]models.URLRevision, error) {
+	var owner sql.NullString
+	row := k.db.QueryRowContext(ctx,
+		`SELECT user_id FROM shortened_url WHERE short_url = $1;`,
+		shortURL,
+	)
+	if err := row.Scan(&owner); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if !owner.Valid || owner.String != userID {
+		return nil, ErrNotOwner
+	}
+
+	sqlStatement := `
+		SELECT
+			revision, original_url, replaced_at
+		FROM
+			url_revisions
+		WHERE
+			short_url = $1
+		ORDER BY
+			revision DESC;`
+
+	rows, err := k.db.QueryContext(ctx, sqlStatement, shortURL)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.URLRevision{}
+	for rows.Next() {
+		rev := models.URLRevision{}
+		if err := rows.Scan(&rev.Revision, &rev.OriginalURL, &rev.ReplacedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
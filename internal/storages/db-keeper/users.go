@@ -0,0 +1,123 @@
+package dbkeeper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+)
+
+// Ошибки пользователей
+var (
+	ErrLoginTaken   = errors.New("login is already taken")
+	ErrUserNotFound = errors.New("user not found")
+)
+
+// CreateUser сохраняет зарегистрированного пользователя.
+func (k *DBKeeper) CreateUser(ctx context.Context, user models.User) error {
+	sqlStatement := `
+		INSERT INTO users (id, login, password_hash, created_at)
+		VALUES ($1, $2, $3, $4)`
+
+	_, err := k.db.ExecContext(
+		ctx,
+		sqlStatement,
+		user.ID, user.Login, user.PasswordHash, user.CreatedAt,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return ErrLoginTaken
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GetUserByLogin пользователь по логину.
+func (k *DBKeeper) GetUserByLogin(ctx context.Context, login string) (models.User, error) {
+	sqlStatement := `
+		SELECT
+			id,
+			login,
+			password_hash,
+			created_at
+		FROM
+			users
+		WHERE
+			login = $1;`
+
+	user := models.User{}
+	row := k.db.QueryRowContext(ctx, sqlStatement, login)
+	if err := row.Scan(&user.ID, &user.Login, &user.PasswordHash, &user.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.User{}, ErrUserNotFound
+		}
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+// ClaimURLS переносит URL анонимного пользователя fromUserID к toUserID.
+// URL зарегистрированного пользователя не переносятся.
+// Если toUserID превысил бы квоту maxLinks, не переносится ни один URL,
+// 0 - без ограничения.
+func (k *DBKeeper) ClaimURLS(ctx context.Context, fromUserID, toUserID string, maxLinks int) (int64, error) {
+	tx, err := k.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			k.log.Error("fail rollback",
+				zap.Error(err),
+			)
+		}
+	}()
+
+	if err := lockQuota(ctx, tx, toUserID, maxLinks); err != nil {
+		return 0, err
+	}
+
+	sqlStatement := `
+		UPDATE shortened_url
+		SET
+			user_id = $2,
+			updated_at = now()
+		WHERE
+			user_id = $1
+			AND NOT EXISTS (
+				SELECT
+					1
+				FROM
+					users
+				WHERE
+					id = $1
+			);`
+
+	res, err := tx.ExecContext(ctx, sqlStatement, fromUserID, toUserID)
+	if err != nil {
+		return 0, err
+	}
+
+	claimed, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := checkQuota(ctx, tx, toUserID, maxLinks); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return claimed, nil
+}
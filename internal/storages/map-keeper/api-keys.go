@@ -0,0 +1,130 @@
+package mapkeeper
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	dbkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/db-keeper"
+)
+
+// CreateAPIKey сохраняет ключ API.
+func (k *Keeper) CreateAPIKey(ctx context.Context, key models.APIKey) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		k.mutex.Lock()
+		defer k.mutex.Unlock()
+
+		return k.putAPIKey(key)
+	}
+}
+
+// GetAPIKeys ключи API пользователя.
+func (k *Keeper) GetAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		k.mutex.RLock()
+		defer k.mutex.RUnlock()
+
+		keys := []models.APIKey{}
+		for _, key := range k.apiKeys {
+			if key.UserID == userID {
+				keys = append(keys, key)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		})
+
+		return keys, nil
+	}
+}
+
+// GetAPIKeyByHash ключ API по хешу.
+func (k *Keeper) GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error) {
+	select {
+	case <-ctx.Done():
+		return models.APIKey{}, ctx.Err()
+	default:
+		k.mutex.RLock()
+		defer k.mutex.RUnlock()
+
+		key, ok := k.apiKeys[keyHash]
+		if !ok {
+			return models.APIKey{}, dbkeeper.ErrAPIKeyNotFound
+		}
+		return key, nil
+	}
+}
+
+// RevokeAPIKey удаляет ключ API пользователя.
+func (k *Keeper) RevokeAPIKey(ctx context.Context, id string, userID string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		k.mutex.Lock()
+		defer k.mutex.Unlock()
+
+		for _, key := range k.apiKeys {
+			if key.ID != id || key.UserID != userID {
+				continue
+			}
+			key.Revoked = true
+			return k.putAPIKey(key)
+		}
+
+		return dbkeeper.ErrAPIKeyNotFound
+	}
+}
+
+// TouchAPIKey обновляет время последнего использования ключа API.
+func (k *Keeper) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		k.mutex.Lock()
+		defer k.mutex.Unlock()
+
+		for _, key := range k.apiKeys {
+			if key.ID != id {
+				continue
+			}
+			key.LastUsedAt = &usedAt
+			return k.putAPIKey(key)
+		}
+
+		return nil
+	}
+}
+
+// putAPIKey записывает ключ в журнал и в память.
+// Вызывается под блокировкой mutex.
+func (k *Keeper) putAPIKey(key models.APIKey) error {
+	if k.keysProducer != nil {
+		if err := k.keysProducer.Write(&key); err != nil {
+			return fmt.Errorf("failed to write to file: %w", err)
+		}
+	}
+	k.applyAPIKey(key)
+	return nil
+}
+
+func (k *Keeper) applyAPIKey(key models.APIKey) {
+	if key.Revoked {
+		delete(k.apiKeys, key.KeyHash)
+		return
+	}
+	k.apiKeys[key.KeyHash] = key
+}
+
+func (k *Keeper) replayAPIKeys() error {
+	return replayJournal(k, k.keysPath, k.applyAPIKey)
+}
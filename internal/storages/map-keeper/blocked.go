@@ -0,0 +1,35 @@
+package mapkeeper
+
+import (
+	"context"
+	"time"
+)
+
+// BlockURLS блокирует (blocked = true) или разблокирует URL.
+// Возвращает количество измененных URL.
+func (k *Keeper) BlockURLS(ctx context.Context, shortURLS []string, blocked bool) (int64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+		k.mutex.Lock()
+		defer k.mutex.Unlock()
+
+		var updated int64
+		for _, id := range shortURLS {
+			rec, ok := k.storage[id]
+			if !ok || rec.Blocked == blocked {
+				continue
+			}
+			now := time.Now().UTC()
+			rec.Blocked = blocked
+			rec.UpdatedAt = &now
+			if err := k.put(rec); err != nil {
+				return updated, err
+			}
+			updated++
+		}
+
+		return updated, nil
+	}
+}
@@ -0,0 +1,130 @@
+package mapkeeper
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	dbkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/db-keeper"
+)
+
+// clicksRetention сколько хранятся почасовые счетчики переходов.
+const clicksRetention = 31 * 24 * time.Hour
+
+// clickCounter счетчики переходов по одному URL.
+type clickCounter struct {
+	total int64
+	// hourly количество переходов по началу часа (unix, UTC).
+	hourly map[int64]int64
+}
+
+// SaveClicks запись переходов по URL.
+// Переходы по неизвестным URL пропускаются.
+func (k *Keeper) SaveClicks(ctx context.Context, clicks []models.Click) {
+	select {
+	case <-ctx.Done():
+		return
+	default:
+		k.mutex.Lock()
+		defer k.mutex.Unlock()
+
+		touched := map[string]*clickCounter{}
+		for _, click := range clicks {
+			if _, ok := k.storage[click.ShortURL]; !ok {
+				continue
+			}
+
+			counter, ok := k.clicks[click.ShortURL]
+			if !ok {
+				counter = &clickCounter{hourly: map[int64]int64{}}
+				k.clicks[click.ShortURL] = counter
+			}
+
+			counter.total++
+			counter.hourly[click.Timestamp.UTC().Truncate(time.Hour).Unix()]++
+			touched[click.ShortURL] = counter
+		}
+
+		border := time.Now().Add(-clicksRetention).Unix()
+		for _, counter := range touched {
+			for hour := range counter.hourly {
+				if hour < border {
+					delete(counter.hourly, hour)
+				}
+			}
+		}
+	}
+}
+
+// GetStats статистика переходов по URL:
+// общее количество, по часам начиная с hourlyFrom и по дням начиная с dailyFrom.
+func (k *Keeper) GetStats(
+	ctx context.Context,
+	shortURL string,
+	userID string,
+	hourlyFrom, dailyFrom time.Time,
+) (models.URLStats, error) {
+	select {
+	case <-ctx.Done():
+		return models.URLStats{}, ctx.Err()
+	default:
+		k.mutex.RLock()
+		defer k.mutex.RUnlock()
+
+		url, ok := k.storage[shortURL]
+		if !ok {
+			return models.URLStats{}, dbkeeper.ErrNotFound
+		}
+		if len(url.UserID) == 0 || url.UserID != userID {
+			return models.URLStats{}, dbkeeper.ErrNotOwner
+		}
+
+		stats := models.URLStats{
+			ShortURL: shortURL,
+			Hourly:   []models.StatsBucket{},
+			Daily:    []models.StatsBucket{},
+		}
+
+		counter, ok := k.clicks[shortURL]
+		if !ok {
+			return stats, nil
+		}
+		stats.Total = counter.total
+
+		hourlyBorder := hourlyFrom.Truncate(time.Hour).Unix()
+		dailyBorder := dailyFrom.UTC().Truncate(24 * time.Hour)
+		daily := map[int64]int64{}
+		for hour, clicks := range counter.hourly {
+			if hour >= hourlyBorder {
+				stats.Hourly = append(stats.Hourly, models.StatsBucket{
+					Start:  time.Unix(hour, 0).UTC(),
+					Clicks: clicks,
+				})
+			}
+
+			day := time.Unix(hour, 0).UTC().Truncate(24 * time.Hour)
+			if !day.Before(dailyBorder) {
+				daily[day.Unix()] += clicks
+			}
+		}
+
+		for day, clicks := range daily {
+			stats.Daily = append(stats.Daily, models.StatsBucket{
+				Start:  time.Unix(day, 0).UTC(),
+				Clicks: clicks,
+			})
+		}
+
+		sortBuckets(stats.Hourly)
+		sortBuckets(stats.Daily)
+
+		return stats, nil
+	}
+}
+
+func sortBuckets(buckets []models.StatsBucket) {
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Start.Before(buckets[j].Start)
+	})
+}
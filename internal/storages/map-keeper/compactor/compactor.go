@@ -0,0 +1,145 @@
+// compactor отвечает за фоновое сжатие журнала файлового хранилища
+package compactor
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// sizeCheckPeriod период проверки размера журнала.
+const sizeCheckPeriod = 10 * time.Second
+
+// Storage хранилище с поддержкой сжатия журнала.
+type Storage interface {
+	Compact() error
+	FileSize() (int64, error)
+}
+
+// Compactor запускает сжатие журнала по размеру, по интервалу
+// и по запросу администратора.
+type Compactor struct {
+	context  context.Context
+	log      *zap.Logger
+	storage  Storage
+	maxSize  int64
+	interval time.Duration
+	trigger  chan struct{}
+	// baseSize размер журнала после последнего сжатия.
+	baseSize int64
+	// newTicker подменяется в тестах.
+	newTicker func(d time.Duration) (<-chan time.Time, func())
+}
+
+// NewCompactor конструктор Compactor.
+// maxSize <= 0 отключает сжатие по размеру, interval <= 0 - по интервалу.
+func NewCompactor(
+	ctx context.Context,
+	log *zap.Logger,
+	storage Storage,
+	maxSize int64,
+	interval time.Duration,
+) *Compactor {
+	c := newCompactor(ctx, log, storage, maxSize, interval)
+
+	go c.run()
+
+	return c
+}
+
+func newCompactor(
+	ctx context.Context,
+	log *zap.Logger,
+	storage Storage,
+	maxSize int64,
+	interval time.Duration,
+) *Compactor {
+	return &Compactor{
+		context:   ctx,
+		log:       log,
+		storage:   storage,
+		maxSize:   maxSize,
+		interval:  interval,
+		trigger:   make(chan struct{}, 1),
+		newTicker: newTicker,
+	}
+}
+
+func newTicker(d time.Duration) (<-chan time.Time, func()) {
+	ticker := time.NewTicker(d)
+	return ticker.C, ticker.Stop
+}
+
+// Trigger запрашивает внеочередное сжатие.
+func (c *Compactor) Trigger() {
+	select {
+	case c.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (c *Compactor) run() {
+	var sizeCh, intervalCh <-chan time.Time
+
+	if c.maxSize > 0 {
+		ch, stop := c.newTicker(sizeCheckPeriod)
+		defer stop()
+		sizeCh = ch
+	}
+
+	if c.interval > 0 {
+		ch, stop := c.newTicker(c.interval)
+		defer stop()
+		intervalCh = ch
+	}
+
+	for {
+		select {
+		case <-c.context.Done():
+			return
+		case <-c.trigger:
+			c.compact("trigger")
+		case <-intervalCh:
+			c.compact("interval")
+		case <-sizeCh:
+			if c.exceeded() {
+				c.compact("size")
+			}
+		}
+	}
+}
+
+// exceeded журнал превысил порог и вырос минимум вдвое с последнего сжатия.
+func (c *Compactor) exceeded() bool {
+	size, err := c.storage.FileSize()
+	if err != nil {
+		c.log.Error("failed to read file size", zap.Error(err))
+		return false
+	}
+
+	return size >= c.maxSize && size >= 2*c.baseSize
+}
+
+func (c *Compactor) compact(reason string) {
+	t := time.Now()
+	if err := c.storage.Compact(); err != nil {
+		c.log.Error("failed to compact file",
+			zap.String("reason", reason),
+			zap.Error(err),
+		)
+		return
+	}
+
+	size, err := c.storage.FileSize()
+	if err != nil {
+		c.log.Error("failed to read file size", zap.Error(err))
+	}
+	c.baseSize = size
+
+	c.log.Info("file compacted",
+		zap.String("reason", reason),
+		zap.Int64("size", size),
+		zap.Duration("duration", time.Since(t)),
+	)
+}
@@ -0,0 +1,143 @@
+package compactor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+)
+
+// fakeStorage размер журнала задает тест: FileSize ждет значение из sizes,
+// поэтому тест точно знает, когда Compactor проверил размер.
+type fakeStorage struct {
+	mutex      sync.Mutex
+	compacted  int
+	compactErr error
+	sizes      chan int64
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{sizes: make(chan int64)}
+}
+
+func (s *fakeStorage) Compact() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.compacted++
+	return s.compactErr
+}
+
+func (s *fakeStorage) FileSize() (int64, error) {
+	return <-s.sizes, nil
+}
+
+func (s *fakeStorage) compactions() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.compacted
+}
+
+func send[T any](t *testing.T, ch chan<- T, v T) {
+	t.Helper()
+	select {
+	case ch <- v:
+	case <-time.After(5 * time.Second):
+		t.Fatal("compactor is not responding")
+	}
+}
+
+// fakeTickers тикеры по периоду, тест сам решает, когда они срабатывают.
+type fakeTickers map[time.Duration]chan time.Time
+
+func (ft fakeTickers) newTicker(d time.Duration) (<-chan time.Time, func()) {
+	return ft[d], func() {}
+}
+
+func TestCompactor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const interval = time.Minute
+	tickers := fakeTickers{
+		sizeCheckPeriod: make(chan time.Time),
+		interval:        make(chan time.Time),
+	}
+	storage := newFakeStorage()
+
+	c := newCompactor(ctx, zaptest.NewLogger(t), storage, 100, interval)
+	c.newTicker = tickers.newTicker
+	go c.run()
+
+	// меньше порога - без сжатия
+	send(t, tickers[sizeCheckPeriod], time.Now())
+	send(t, storage.sizes, 50)
+
+	// порог превышен - сжатие, после него журнал 60 байт
+	send(t, tickers[sizeCheckPeriod], time.Now())
+	send(t, storage.sizes, 150)
+	send(t, storage.sizes, 60)
+	assert.Equal(t, 1, storage.compactions())
+
+	// выше порога, но не вдвое больше размера после сжатия
+	send(t, tickers[sizeCheckPeriod], time.Now())
+	send(t, storage.sizes, 110)
+
+	send(t, tickers[sizeCheckPeriod], time.Now())
+	send(t, storage.sizes, 120)
+	send(t, storage.sizes, 60)
+	assert.Equal(t, 2, storage.compactions())
+
+	// по интервалу размер не важен
+	send(t, tickers[interval], time.Now())
+	send(t, storage.sizes, 60)
+	assert.Equal(t, 3, storage.compactions())
+
+	c.Trigger()
+	send(t, storage.sizes, 60)
+	assert.Equal(t, 4, storage.compactions())
+}
+
+func TestCompactorFailed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tickers := fakeTickers{sizeCheckPeriod: make(chan time.Time)}
+	storage := newFakeStorage()
+	storage.compactErr = errors.New("disk is full")
+
+	c := newCompactor(ctx, zaptest.NewLogger(t), storage, 100, 0)
+	c.newTicker = tickers.newTicker
+	go c.run()
+
+	// после ошибки размер не перечитывается, следующая проверка снова сжимает
+	send(t, tickers[sizeCheckPeriod], time.Now())
+	send(t, storage.sizes, 150)
+	send(t, tickers[sizeCheckPeriod], time.Now())
+	send(t, storage.sizes, 150)
+	send(t, tickers[sizeCheckPeriod], time.Now())
+	send(t, storage.sizes, 50)
+	assert.Equal(t, 2, storage.compactions())
+}
+
+func TestCompactorDisabled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storage := newFakeStorage()
+
+	c := newCompactor(ctx, zaptest.NewLogger(t), storage, 0, 0)
+	c.newTicker = func(d time.Duration) (<-chan time.Time, func()) {
+		t.Errorf("unexpected ticker %s", d)
+		return nil, func() {}
+	}
+	go c.run()
+
+	// по запросу сжатие работает и без порога и интервала
+	c.Trigger()
+	send(t, storage.sizes, 10)
+	assert.Equal(t, 1, storage.compactions())
+}
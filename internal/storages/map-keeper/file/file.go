@@ -2,49 +2,161 @@
 package file
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
 
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
 )
 
+// ErrTornRecord последняя запись в файле записана не полностью.
+var ErrTornRecord = errors.New("torn record at the end of file")
+
+// SyncPolicy политика сброса записей на диск.
+type SyncPolicy string
+
+// Политики сброса записей на диск
+const (
+	SyncAlways   SyncPolicy = "always"   // fsync после каждой записи
+	SyncInterval SyncPolicy = "interval" // fsync с заданным интервалом
+	SyncNever    SyncPolicy = "never"    // fsync выполняет ОС
+)
+
+// ParseSyncPolicy политика сброса по строковому значению.
+func ParseSyncPolicy(policy string) (SyncPolicy, error) {
+	switch SyncPolicy(policy) {
+	case SyncAlways, SyncInterval, SyncNever:
+		return SyncPolicy(policy), nil
+	case "":
+		return SyncAlways, nil
+	default:
+		return "", fmt.Errorf("unknown sync policy %q", policy)
+	}
+}
+
 // Producer хранит параметры для записи в файл.
 type Producer struct {
-	file    *os.File
-	encoder *json.Encoder
+	log       *zap.Logger
+	file      *os.File
+	encoder   *json.Encoder
+	policy    SyncPolicy
+	dirty     atomic.Bool
+	done      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
 }
 
 // NewProducer конструктор Producer.
-func NewProducer(path string) (*Producer, error) {
+// Записи добавляются в конец файла.
+// Для SyncInterval запускается фоновый сброс на диск с периодом interval,
+// его ошибки пишутся в log.
+func NewProducer(log *zap.Logger, path string, policy SyncPolicy, interval time.Duration) (*Producer, error) {
 
-	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Producer{
+	p := &Producer{
+		log:     log,
 		file:    file,
 		encoder: json.NewEncoder(file),
-	}, nil
+		policy:  policy,
+		done:    make(chan struct{}),
+	}
+
+	if policy == SyncInterval {
+		if interval <= 0 {
+			file.Close()
+			return nil, fmt.Errorf("sync interval must be positive")
+		}
+		go p.syncer(interval)
+	}
+
+	return p, nil
 }
 
 // Write запись в файл
-func (p *Producer) Write(shortURL *models.FileURL) error {
-	return p.encoder.Encode(shortURL)
+func (p *Producer) Write(record any) error {
+	if err := p.encoder.Encode(record); err != nil {
+		return err
+	}
+
+	switch p.policy {
+	case SyncAlways:
+		return p.file.Sync()
+	case SyncInterval:
+		p.dirty.Store(true)
+	}
+
+	return nil
+}
+
+// Sync сбрасывает записанные данные на диск.
+func (p *Producer) Sync() error {
+	p.dirty.Store(false)
+	return p.file.Sync()
 }
 
-// Close закрывает файл
+// Close закрывает файл.
+// Повторный вызов возвращает результат первого.
 func (p *Producer) Close() error {
-	if p.file != nil {
-		return p.file.Close()
+	if p.file == nil {
+		return nil
+	}
+
+	p.closeOnce.Do(func() {
+		close(p.done)
+
+		if p.policy != SyncNever {
+			if err := p.Sync(); err != nil {
+				p.file.Close()
+				p.closeErr = err
+				return
+			}
+		}
+
+		p.closeErr = p.file.Close()
+	})
+
+	return p.closeErr
+}
+
+func (p *Producer) syncer(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			if p.dirty.Load() {
+				if err := p.Sync(); err != nil {
+					p.log.Error("failed to sync file",
+						zap.String("file", p.file.Name()),
+						zap.Error(err),
+					)
+				}
+			}
+		}
 	}
-	return nil
 }
 
 // Consumer хранит параметры для чтения из файла.
 type Consumer struct {
-	file    *os.File
-	decoder *json.Decoder
+	file   *os.File
+	reader *bufio.Reader
+	offset int64
 }
 
 // NewConsumer конструктор Consumer.
@@ -55,23 +167,120 @@ func NewConsumer(path string) (*Consumer, error) {
 	}
 
 	return &Consumer{
-		file:    file,
-		decoder: json.NewDecoder(file),
+		file:   file,
+		reader: bufio.NewReader(file),
 	}, nil
 }
 
-// More сообщает, есть ли еще один элемент в
-// текущем массиве или анализируемом объекте.
-func (c *Consumer) More() bool {
-	return c.decoder.More()
+// Decode читает из файла следующую запись в record.
+// record должен быть пустым: отсутствующие в записи поля не обнуляются.
+// Возвращает io.EOF, если записей больше нет,
+// и ErrTornRecord, если последняя запись оборвана.
+func (c *Consumer) Decode(record any) error {
+	for {
+		line, err := c.reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		// строка без перевода строки в конце файла - незавершенная запись
+		if errors.Is(err, io.EOF) {
+			if len(bytes.TrimSpace(line)) == 0 {
+				return io.EOF
+			}
+			return ErrTornRecord
+		}
+
+		if len(bytes.TrimSpace(line)) == 0 {
+			c.offset += int64(len(line))
+			continue
+		}
+
+		if err := json.Unmarshal(line, record); err != nil {
+			if _, errPeek := c.reader.Peek(1); errors.Is(errPeek, io.EOF) {
+				return ErrTornRecord
+			}
+			return fmt.Errorf("corrupted record at offset %d: %w", c.offset, err)
+		}
+
+		c.offset += int64(len(line))
+		return nil
+	}
 }
 
-// Decode читает из файла
-func (c *Consumer) Decode(url *models.FileURL) error {
-	return c.decoder.Decode(url)
+// Offset смещение в файле сразу за последней прочитанной записью.
+func (c *Consumer) Offset() int64 {
+	return c.offset
 }
 
 // Close закрывает файл.
 func (c *Consumer) Close() error {
 	return c.file.Close()
 }
+
+// Snapshot временный файл для записи снимка хранилища.
+// После записи снимок атомарно заменяет основной файл.
+type Snapshot struct {
+	file    *os.File
+	encoder *json.Encoder
+	target  string
+}
+
+// NewSnapshot создает временный файл рядом с target.
+func NewSnapshot(target string) (*Snapshot, error) {
+	dir, name := filepath.Split(target)
+
+	file, err := os.CreateTemp(dir, name+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{
+		file:    file,
+		encoder: json.NewEncoder(file),
+		target:  target,
+	}, nil
+}
+
+// Path путь к временному файлу.
+func (s *Snapshot) Path() string {
+	return s.file.Name()
+}
+
+// Write запись в снимок.
+func (s *Snapshot) Write(shortURL *models.FileURL) error {
+	return s.encoder.Encode(shortURL)
+}
+
+// Close сбрасывает снимок на диск и закрывает файл.
+func (s *Snapshot) Close() error {
+	if err := s.file.Sync(); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+// Rename заменяет основной файл снимком.
+// Вызывается после Close.
+func (s *Snapshot) Rename() error {
+	return os.Rename(s.file.Name(), s.target)
+}
+
+// SyncDir сбрасывает на диск каталог файла,
+// чтобы зафиксировать переименование.
+func SyncDir(path string) error {
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	return dir.Sync()
+}
+
+// Remove удаляет временный файл.
+func (s *Snapshot) Remove() error {
+	s.file.Close()
+	return os.Remove(s.file.Name())
+}
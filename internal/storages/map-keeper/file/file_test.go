@@ -1,12 +1,16 @@
 package file
 
 import (
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
 
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
 )
@@ -27,7 +31,7 @@ func TestReadWriteFile(t *testing.T) {
 		require.NoError(t, err)
 	})
 
-	p, err := NewProducer(name)
+	p, err := NewProducer(zaptest.NewLogger(t), name, SyncAlways, 0)
 	require.NoError(t, err)
 	saveData := models.FileURL{
 		ShortURL:    "jsjkfkjdf",
@@ -42,10 +46,84 @@ func TestReadWriteFile(t *testing.T) {
 	fileData := models.FileURL{}
 	c, err := NewConsumer(name)
 	require.NoError(t, err)
-	c.Decode(&fileData)
+	err = c.Decode(&fileData)
+	require.NoError(t, err)
+	assert.ErrorIs(t, c.Decode(&models.FileURL{}), io.EOF)
 	err = c.Close()
 	require.NoError(t, err)
 
 	assert.EqualValues(t, saveData, fileData)
 
 }
+
+func TestProducerDoubleClose(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "urls.json")
+
+	p, err := NewProducer(zaptest.NewLogger(t), name, SyncInterval, time.Millisecond)
+	require.NoError(t, err)
+	require.NoError(t, p.Write(&models.FileURL{ShortURL: "jsjkfkjdf", OriginalURL: "https://ya.ru/"}))
+
+	require.NoError(t, p.Close())
+	// повторное закрытие не паникует на закрытом канале
+	require.NoError(t, p.Close())
+}
+
+func TestConsumerTornRecord(t *testing.T) {
+	absDir, err := filepath.Abs("")
+	require.NoError(t, err)
+	dir, err := os.MkdirTemp(absDir, "testfile*")
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		t.Helper()
+		err = os.RemoveAll(dir)
+		require.NoError(t, err)
+	})
+
+	record := `{"shortUrl":"jsjkfkjdf","originalUrl":"https://ya.ru/"}` + "\n"
+
+	cases := []struct {
+		name        string
+		data        string
+		expectedErr error
+	}{
+		{
+			name:        "no line break at the end",
+			data:        record + `{"shortUrl":"jsjkfkjdf","originalUrl":"https://ya.ru/"}`,
+			expectedErr: ErrTornRecord,
+		},
+		{
+			name:        "broken json in the last line",
+			data:        record + `{"shortUrl":"jsjk` + "\n",
+			expectedErr: ErrTornRecord,
+		},
+		{
+			name: "broken json in the middle",
+			data: `{"shortUrl":"jsjk` + "\n" + record,
+		},
+	}
+
+	for i, tc := range cases {
+		tc := tc
+		name := filepath.Join(dir, fmt.Sprintf("torn%d.json", i))
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, os.WriteFile(name, []byte(tc.data), 0644))
+
+			c, err := NewConsumer(name)
+			require.NoError(t, err)
+			defer c.Close()
+
+			url := models.FileURL{}
+			if tc.expectedErr == nil {
+				err = c.Decode(&url)
+				require.Error(t, err)
+				assert.NotErrorIs(t, err, ErrTornRecord)
+				return
+			}
+
+			require.NoError(t, c.Decode(&url))
+			assert.Equal(t, int64(len(record)), c.Offset())
+			assert.ErrorIs(t, c.Decode(&url), tc.expectedErr)
+		})
+	}
+}
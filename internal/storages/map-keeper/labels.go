@@ -0,0 +1,97 @@
+package mapkeeper
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	dbkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/db-keeper"
+)
+
+// UpdateLabels заменяет теги и папку URL пользователя.
+// Возвращает URL после изменения.
+func (k *Keeper) UpdateLabels(ctx context.Context, shortURL string, userID string, labels models.URLLabels) (models.MassURL, error) {
+	select {
+	case <-ctx.Done():
+		return models.MassURL{}, ctx.Err()
+	default:
+		k.mutex.Lock()
+		defer k.mutex.Unlock()
+
+		rec, ok := k.storage[shortURL]
+		switch {
+		case !ok:
+			return models.MassURL{}, dbkeeper.ErrNotFound
+		case len(rec.UserID) == 0 || rec.UserID != userID:
+			return models.MassURL{}, dbkeeper.ErrNotOwner
+		case rec.DeletedFlag:
+			return models.MassURL{}, dbkeeper.ErrURLRemoved
+		}
+
+		applyLabels(&rec, labels)
+		now := time.Now().UTC()
+		rec.UpdatedAt = &now
+
+		if err := k.put(rec); err != nil {
+			return models.MassURL{}, err
+		}
+
+		return massURL(rec), nil
+	}
+}
+
+// applyLabels заменяет теги и папку rec, заданные в labels.
+func applyLabels(rec *models.FileURL, labels models.URLLabels) {
+	if labels.Tags != nil {
+		rec.Tags = slices.Clone(*labels.Tags)
+	}
+	if labels.Folder != nil {
+		rec.Folder = *labels.Folder
+	}
+}
+
+// massURL URL с тегами и папкой для ответа пользователю.
+func massURL(rec models.FileURL) models.MassURL {
+	return models.MassURL{
+		ShortURL:    rec.ShortURL,
+		OriginalURL: rec.OriginalURL,
+		Tags:        rec.Tags,
+		Folder:      rec.Folder,
+	}
+}
+
+// GetTags теги неудаленных URL пользователя с количеством URL,
+// начиная с самых частых.
+func (k *Keeper) GetTags(ctx context.Context, userID string) ([]models.TagCount, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		k.mutex.RLock()
+		counts := map[string]int64{}
+		for _, url := range k.storage {
+			if url.UserID != userID || url.DeletedFlag {
+				continue
+			}
+			for _, tag := range url.Tags {
+				counts[tag]++
+			}
+		}
+		k.mutex.RUnlock()
+
+		tags := make([]models.TagCount, 0, len(counts))
+		for tag, count := range counts {
+			tags = append(tags, models.TagCount{Tag: tag, Count: count})
+		}
+		sort.Slice(tags, func(i, j int) bool {
+			if tags[i].Count != tags[j].Count {
+				return tags[i].Count > tags[j].Count
+			}
+			return tags[i].Tag < tags[j].Tag
+		})
+
+		return tags, nil
+	}
+}
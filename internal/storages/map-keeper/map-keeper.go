@@ -3,48 +3,154 @@ package mapkeeper
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"sort"
 	"sync"
+	"time"
+
+	"go.uber.org/zap"
 
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/lib/cryptoutils"
+	dbkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/db-keeper"
 	"github.com/vladislav-kr/yp-go-url-shortener/internal/storages/map-keeper/file"
 )
 
 // Keeper хранит данные для in-memory хранилища.
+//
+// Если задан путь к файлу, каждое изменение дописывается в конец файла
+// (write-ahead log) до применения в памяти.
 type Keeper struct {
-	mutex    sync.RWMutex
-	storage  map[string]string
-	filePath string
+	log          *zap.Logger
+	mutex        sync.RWMutex
+	storage      map[string]models.FileURL
+	filePath     string
+	syncPolicy   file.SyncPolicy
+	syncInterval time.Duration
+	producer     *file.Producer
+	// compacting во время сжатия изменения дополнительно
+	// копятся в pending, чтобы попасть в новый файл.
+	compacting bool
+	pending    []models.FileURL
+	// clicks счетчики переходов, хранятся только в памяти.
+	clicks map[string]*clickCounter
+	// users зарегистрированные пользователи по логину,
+	// пишутся в отдельный журнал usersPath без сжатия.
+	users         map[string]models.User
+	usersPath     string
+	usersProducer *file.Producer
+	// apiKeys ключи API по хешу, журнал keysPath.
+	apiKeys      map[string]models.APIKey
+	keysPath     string
+	keysProducer *file.Producer
+	// revisions история изменений оригинальных URL, журнал revisionsPath.
+	revisions         map[string][]models.URLRevision
+	revisionsPath     string
+	revisionsProducer *file.Producer
 }
 
 // New конструктор Keeper.
-func New(filePath string) *Keeper {
+func New(
+	log *zap.Logger,
+	filePath string,
+	syncPolicy file.SyncPolicy,
+	syncInterval time.Duration,
+) *Keeper {
 	return &Keeper{
-		storage:  map[string]string{},
-		filePath: filePath,
+		log:           log,
+		storage:       map[string]models.FileURL{},
+		clicks:        map[string]*clickCounter{},
+		users:         map[string]models.User{},
+		filePath:      filePath,
+		usersPath:     journalPath(filePath, ".users"),
+		apiKeys:       map[string]models.APIKey{},
+		keysPath:      journalPath(filePath, ".keys"),
+		revisions:     map[string][]models.URLRevision{},
+		revisionsPath: journalPath(filePath, ".revisions"),
+		syncPolicy:    syncPolicy,
+		syncInterval:  syncInterval,
 	}
 }
 
-// DeleteURLS удаление URL
-func (k *Keeper) DeleteURLS(_ context.Context, _ []models.DeleteURL) {
+// DeleteURLS помечает URL пользователя удаленными.
+func (k *Keeper) DeleteURLS(ctx context.Context, shortURLS []models.DeleteURL) {
+	select {
+	case <-ctx.Done():
+		return
+	default:
+		k.mutex.Lock()
+		defer k.mutex.Unlock()
 
+		now := time.Now().UTC()
+		for _, url := range shortURLS {
+			rec, ok := k.storage[url.ShortURL]
+			if !ok || rec.UserID != url.UserID || rec.DeletedFlag {
+				continue
+			}
+			rec.DeletedFlag = true
+			rec.DeletedAt = &now
+			rec.UpdatedAt = &now
+			if err := k.put(rec); err != nil {
+				k.log.Error("failed to delete url",
+					zap.String("url", url.ShortURL),
+					zap.Error(err),
+				)
+			}
+		}
+	}
 }
 
 // PostURL сохранение сокращенного URL.
-func (k *Keeper) PostURL(ctx context.Context, url string, _ string) (string, error) {
+// Если алиас не задан, он генерируется случайно.
+// Квота maxLinks на URL пользователя проверяется под той же блокировкой,
+// 0 - без ограничения.
+func (k *Keeper) PostURL(ctx context.Context, req models.URLRequest, userID string, maxLinks int) (string, error) {
 	select {
 	case <-ctx.Done():
 		return "", ctx.Err()
 	default:
-		id, err := cryptoutils.GenerateRandomString(10)
+		id, err := aliasOrRandom(req.Alias)
 		if err != nil {
 			return "", err
 		}
 
 		k.mutex.Lock()
-		k.storage[id] = url
-		k.mutex.Unlock()
+		defer k.mutex.Unlock()
+
+		now := time.Now().UTC()
+		// истекший URL освобождает алиас, как в БД
+		if url, ok := k.storage[id]; ok && len(req.Alias) > 0 && !isExpired(url, now) {
+			return "", &dbkeeper.AliasTakenError{Alias: req.Alias}
+		}
+		// уже сокращенный URL возвращается с ErrAlreadyExists, как в БД
+		existing := k.shortURLSByOriginal([]models.BatchRequest{{OriginalURL: req.URL}})
+		if id, ok := existing[req.URL]; ok {
+			return id, dbkeeper.ErrAlreadyExists
+		}
+		if err := k.checkQuota(userID, maxLinks, 1); err != nil {
+			return "", err
+		}
+		if err := k.removeExpired(id, now); err != nil {
+			return "", err
+		}
+
+		if err := k.put(models.FileURL{
+			ShortURL:      id,
+			OriginalURL:   req.URL,
+			UserID:        userID,
+			Tags:          req.Tags,
+			Folder:        req.Folder,
+			CreatedAt:     &now,
+			UpdatedAt:     &now,
+			CreatorIPHash: req.Creator.IPHash,
+			UserAgent:     req.Creator.UserAgent,
+			ExpiresAt:     req.ExpiresAt,
+		}); err != nil {
+			return "", err
+		}
 		return id, nil
 	}
 }
@@ -62,37 +168,150 @@ func (k *Keeper) GetURL(ctx context.Context, id string) (string, error) {
 			return "", fmt.Errorf("not found")
 		}
 
-		return val, nil
+		if val.DeletedFlag {
+			return "", dbkeeper.ErrURLRemoved
+		}
+
+		if val.Blocked {
+			return "", dbkeeper.ErrURLBlocked
+		}
+
+		if isExpired(val, time.Now()) {
+			return "", dbkeeper.ErrURLExpired
+		}
+
+		return val.OriginalURL, nil
 	}
 }
 
 // SaveURLS массовое сохранение URL.
-func (k *Keeper) SaveURLS(ctx context.Context, urls []models.BatchRequest, _ string) ([]models.BatchResponse, error) {
+// Результат для каждого элемента возвращается в порядке urls:
+// уже сокращенный URL - BatchExisting, занятый алиас - BatchInvalid.
+// Квота maxLinks учитывает только созданные URL: при превышении
+// пакет не сохраняется целиком.
+func (k *Keeper) SaveURLS(ctx context.Context, urls []models.BatchRequest, userID string, maxLinks int) ([]models.BatchResponse, error) {
 	select {
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	default:
 		batchResp := make([]models.BatchResponse, 0, len(urls))
+		// created записи новых URL, пишутся после проверки квоты
+		created := make([]models.FileURL, 0, len(urls))
+		aliases := make(map[string]struct{}, len(urls))
+
+		k.mutex.Lock()
+		defer k.mutex.Unlock()
+
+		existing := k.shortURLSByOriginal(urls)
 
 		for _, url := range urls {
-			id, err := cryptoutils.GenerateRandomString(10)
+			resp := models.BatchResponse{CorrelationID: url.CorrelationID}
+			now := time.Now().UTC()
+
+			// алиас, занятый другим оригинальным URL, не пропадает молча,
+			// даже если оригинальный URL уже сокращен
+			id, ok := existing[url.OriginalURL]
+			if len(url.Alias) > 0 && (!ok || id != url.Alias) {
+				_, planned := aliases[url.Alias]
+				if stored, ok := k.storage[url.Alias]; planned || ok && !isExpired(stored, now) {
+					resp.Status = models.BatchInvalid
+					resp.Reason = (&dbkeeper.AliasTakenError{Alias: url.Alias}).Error()
+					batchResp = append(batchResp, resp)
+					continue
+				}
+			}
+
+			if ok {
+				resp.ShortURL, resp.Status = id, models.BatchExisting
+				batchResp = append(batchResp, resp)
+				continue
+			}
+
+			id, err := aliasOrRandom(url.Alias)
 			if err != nil {
 				return nil, err
 			}
-			k.mutex.Lock()
-			k.storage[id] = url.OriginalURL
-			k.mutex.Unlock()
-			batchResp = append(batchResp, models.BatchResponse{
-				CorrelationID: url.CorrelationID,
+			created = append(created, models.FileURL{
 				ShortURL:      id,
+				OriginalURL:   url.OriginalURL,
+				UserID:        userID,
+				Tags:          url.Tags,
+				Folder:        url.Folder,
+				CreatedAt:     &now,
+				UpdatedAt:     &now,
+				CreatorIPHash: url.Creator.IPHash,
+				UserAgent:     url.Creator.UserAgent,
+				ExpiresAt:     url.ExpiresAt,
 			})
+			existing[url.OriginalURL] = id
+			aliases[id] = struct{}{}
+
+			resp.ShortURL, resp.Status = id, models.BatchCreated
+			batchResp = append(batchResp, resp)
+		}
+
+		if err := k.checkQuota(userID, maxLinks, len(created)); err != nil {
+			return nil, err
+		}
+
+		for _, rec := range created {
+			if err := k.removeExpired(rec.ShortURL, *rec.CreatedAt); err != nil {
+				return nil, err
+			}
+			if err := k.put(rec); err != nil {
+				return nil, err
+			}
 		}
 
 		return batchResp, nil
 	}
 }
 
-// LoadFromFile загружает данные из файла.
+// shortURLSByOriginal короткие URL, уже сохраненные для оригинальных URL из urls.
+// Истекшие URL не учитываются: оригинальный URL сокращается заново.
+// Вызывается под блокировкой mutex.
+func (k *Keeper) shortURLSByOriginal(urls []models.BatchRequest) map[string]string {
+	wanted := make(map[string]struct{}, len(urls))
+	for _, url := range urls {
+		wanted[url.OriginalURL] = struct{}{}
+	}
+
+	now := time.Now()
+	existing := make(map[string]string, len(urls))
+	for id, url := range k.storage {
+		if _, ok := wanted[url.OriginalURL]; ok && !isExpired(url, now) {
+			existing[url.OriginalURL] = id
+		}
+	}
+	return existing
+}
+
+// removeExpired окончательно удаляет истекший URL id перед повторным
+// использованием алиаса, чтобы новый URL не унаследовал его клики и историю.
+// Вызывается под блокировкой mutex.
+func (k *Keeper) removeExpired(id string, now time.Time) error {
+	if url, ok := k.storage[id]; !ok || !isExpired(url, now) {
+		return nil
+	}
+	return k.put(models.FileURL{ShortURL: id, Removed: true})
+}
+
+// isExpired срок действия URL истек к моменту now.
+func isExpired(url models.FileURL, now time.Time) bool {
+	return url.ExpiresAt != nil && !url.ExpiresAt.After(now)
+}
+
+// aliasOrRandom пользовательский алиас или случайный, если он не задан.
+func aliasOrRandom(alias string) (string, error) {
+	if len(alias) > 0 {
+		return alias, nil
+	}
+	return cryptoutils.GenerateRandomString(10)
+}
+
+// LoadFromFile восстанавливает данные из журнала
+// и открывает его для дозаписи.
+// Оборванная последняя запись отбрасывается.
 func (k *Keeper) LoadFromFile() error {
 	if len(k.filePath) == 0 {
 		return nil
@@ -101,50 +320,420 @@ func (k *Keeper) LoadFromFile() error {
 	k.mutex.Lock()
 	defer k.mutex.Unlock()
 
+	if err := k.replay(); err != nil {
+		return err
+	}
+
+	if err := k.replayUsers(); err != nil {
+		return err
+	}
+
+	if err := k.replayAPIKeys(); err != nil {
+		return err
+	}
+
+	if err := k.replayRevisions(); err != nil {
+		return err
+	}
+
+	p, err := file.NewProducer(k.log, k.filePath, k.syncPolicy, k.syncInterval)
+	if err != nil {
+		return err
+	}
+
+	up, err := file.NewProducer(k.log, k.usersPath, k.syncPolicy, k.syncInterval)
+	if err != nil {
+		p.Close()
+		return err
+	}
+
+	kp, err := file.NewProducer(k.log, k.keysPath, k.syncPolicy, k.syncInterval)
+	if err != nil {
+		p.Close()
+		up.Close()
+		return err
+	}
+
+	rp, err := file.NewProducer(k.log, k.revisionsPath, k.syncPolicy, k.syncInterval)
+	if err != nil {
+		p.Close()
+		up.Close()
+		kp.Close()
+		return err
+	}
+
+	k.producer = p
+	k.usersProducer = up
+	k.keysProducer = kp
+	k.revisionsProducer = rp
+
+	return nil
+}
+
+// Close закрывает журнал.
+func (k *Keeper) Close() error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if k.producer == nil {
+		return nil
+	}
+
+	err := errors.Join(
+		k.producer.Close(),
+		k.usersProducer.Close(),
+		k.keysProducer.Close(),
+		k.revisionsProducer.Close(),
+	)
+	k.producer = nil
+	k.usersProducer = nil
+	k.keysProducer = nil
+	k.revisionsProducer = nil
+	return err
+}
+
+func (k *Keeper) replay() error {
 	c, err := file.NewConsumer(k.filePath)
 	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
 		return err
 	}
+	defer c.Close()
+
+	// URL, удаленные до появления DeletedAt,
+	// получают полный срок восстановления
+	loadedAt := time.Now().UTC()
+
+	for {
+		url := models.FileURL{}
+		err := c.Decode(&url)
+		switch {
+		case err == nil:
+			if url.DeletedFlag && url.DeletedAt == nil {
+				url.DeletedAt = &loadedAt
+			}
+			k.apply(url)
+			continue
+		case errors.Is(err, io.EOF):
+			return nil
+		case errors.Is(err, file.ErrTornRecord):
+			k.log.Warn("torn record at the end of file, truncating",
+				zap.Int64("offset", c.Offset()),
+			)
+			return os.Truncate(k.filePath, c.Offset())
+		default:
+			return err
+		}
+	}
+}
+
+// put записывает URL в журнал и в память.
+// Вызывается под блокировкой mutex.
+func (k *Keeper) put(url models.FileURL) error {
+	if k.producer != nil {
+		if err := k.producer.Write(&url); err != nil {
+			return fmt.Errorf("failed to write to file: %w", err)
+		}
+	}
+	if k.compacting {
+		k.pending = append(k.pending, url)
+	}
+	k.apply(url)
+	return nil
+}
+
+// apply применяет запись журнала к памяти.
+func (k *Keeper) apply(url models.FileURL) {
+	if url.Removed {
+		delete(k.storage, url.ShortURL)
+		delete(k.clicks, url.ShortURL)
+		delete(k.revisions, url.ShortURL)
+		return
+	}
+	k.storage[url.ShortURL] = url
+}
+
+// DeleteExpired окончательно удаляет до limit истекших URL.
+func (k *Keeper) DeleteExpired(ctx context.Context, limit int) (int64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+		k.mutex.Lock()
+		defer k.mutex.Unlock()
+
+		now := time.Now()
+		var deleted int64
+		for id, url := range k.storage {
+			if deleted >= int64(limit) {
+				break
+			}
+			if !isExpired(url, now) {
+				continue
+			}
+			if err := k.put(models.FileURL{ShortURL: id, Removed: true}); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+
+		return deleted, nil
+	}
+}
+
+// CountURLS количество URL: всего, удаленных и истекших.
+func (k *Keeper) CountURLS(ctx context.Context) (models.URLCounts, error) {
+	select {
+	case <-ctx.Done():
+		return models.URLCounts{}, ctx.Err()
+	default:
+		k.mutex.RLock()
+		defer k.mutex.RUnlock()
+
+		now := time.Now()
+		counts := models.URLCounts{Total: int64(len(k.storage))}
+		for _, url := range k.storage {
+			if url.DeletedFlag {
+				counts.Deleted++
+			}
+			if isExpired(url, now) {
+				counts.Expired++
+			}
+		}
 
-	var url *models.FileURL
-	for c.More() {
-		url = &models.FileURL{}
-		c.Decode(url)
-		k.storage[url.ShortURL] = url.OriginalURL
+		return counts, nil
 	}
+}
+
+// CountUsers количество пользователей, сохранивших хотя бы один URL.
+func (k *Keeper) CountUsers(ctx context.Context) (int64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+		k.mutex.RLock()
+		defer k.mutex.RUnlock()
+
+		users := map[string]struct{}{}
+		for _, url := range k.storage {
+			if len(url.UserID) > 0 {
+				users[url.UserID] = struct{}{}
+			}
+		}
 
-	return c.Close()
+		return int64(len(users)), nil
+	}
+}
+
+// CountUserURLS количество неудаленных URL пользователя.
+func (k *Keeper) CountUserURLS(ctx context.Context, userID string) (int64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+		k.mutex.RLock()
+		defer k.mutex.RUnlock()
+
+		return k.countUserURLS(userID), nil
+	}
+}
+
+// countUserURLS вызывается под блокировкой mutex.
+func (k *Keeper) countUserURLS(userID string) int64 {
+	var urls int64
+	for _, url := range k.storage {
+		if url.UserID == userID && !url.DeletedFlag {
+			urls++
+		}
+	}
+	return urls
+}
+
+// checkQuota проверяет, что пользователь может сохранить еще added URL.
+// maxLinks 0 или анонимный пользователь - без ограничения.
+// Вызывается под блокировкой mutex до записи.
+func (k *Keeper) checkQuota(userID string, maxLinks int, added int) error {
+	if maxLinks < 1 || len(userID) == 0 || added == 0 {
+		return nil
+	}
+	if total := int(k.countUserURLS(userID)) + added; total > maxLinks {
+		return &dbkeeper.QuotaError{Limit: maxLinks, Value: total}
+	}
+	return nil
 }
 
-// SaveToFile сохраняет данные в файл .
-func (k *Keeper) SaveToFile() error {
+// FileSize размер журнала в байтах.
+func (k *Keeper) FileSize() (int64, error) {
 	if len(k.filePath) == 0 {
+		return 0, nil
+	}
+
+	info, err := os.Stat(k.filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return info.Size(), nil
+}
+
+// Compact заменяет журнал снимком текущего состояния хранилища.
+//
+// Снимок пишется во временный файл без блокировки записи,
+// изменения, сделанные за это время, дописываются в конце,
+// после чего временный файл атомарно заменяет журнал.
+func (k *Keeper) Compact() error {
+	k.mutex.Lock()
+	if k.producer == nil || k.compacting {
+		k.mutex.Unlock()
 		return nil
 	}
+	urls := make([]models.FileURL, 0, len(k.storage))
+	for _, url := range k.storage {
+		urls = append(urls, url)
+	}
+	k.compacting = true
+	k.mutex.Unlock()
 
+	snapshot, err := file.NewSnapshot(k.filePath)
+	if err != nil {
+		k.stopCompacting()
+		return err
+	}
+
+	for i := range urls {
+		if err := snapshot.Write(&urls[i]); err != nil {
+			snapshot.Remove()
+			k.stopCompacting()
+			return err
+		}
+	}
+
+	return k.replaceJournal(snapshot)
+}
+
+// replaceJournal дописывает в снимок изменения, накопленные
+// во время сжатия, и заменяет им журнал.
+func (k *Keeper) replaceJournal(snapshot *file.Snapshot) error {
 	k.mutex.Lock()
 	defer k.mutex.Unlock()
+	defer func() {
+		k.compacting = false
+		k.pending = nil
+	}()
 
-	if len(k.storage) < 1 {
+	// хранилище закрыто во время записи снимка,
+	// все изменения уже в старом журнале
+	if k.producer == nil {
+		snapshot.Remove()
 		return nil
 	}
 
-	p, err := file.NewProducer(k.filePath)
+	for i := range k.pending {
+		if err := snapshot.Write(&k.pending[i]); err != nil {
+			snapshot.Remove()
+			return err
+		}
+	}
+
+	if err := snapshot.Close(); err != nil {
+		snapshot.Remove()
+		return err
+	}
+
+	// открываем журнал до переименования: дескриптор
+	// останется валидным после замены файла
+	p, err := file.NewProducer(k.log, snapshot.Path(), k.syncPolicy, k.syncInterval)
 	if err != nil {
+		snapshot.Remove()
 		return err
 	}
 
-	url := &models.FileURL{}
-	for shortURL, originURL := range k.storage {
-		url.ShortURL = shortURL
-		url.OriginalURL = originURL
-		p.Write(url)
+	if err := snapshot.Rename(); err != nil {
+		p.Close()
+		snapshot.Remove()
+		return err
 	}
 
-	return p.Close()
+	if err := k.producer.Close(); err != nil {
+		k.log.Error("failed to close old file", zap.Error(err))
+	}
+	k.producer = p
+
+	if err := file.SyncDir(k.filePath); err != nil {
+		k.log.Warn("failed to sync storage directory", zap.Error(err))
+	}
+
+	return nil
+}
+
+func (k *Keeper) stopCompacting() {
+	k.mutex.Lock()
+	k.compacting = false
+	k.pending = nil
+	k.mutex.Unlock()
 }
 
-// GetURLS список сокращенных URL пользователя.
-func (k *Keeper) GetURLS(_ context.Context, _ string) ([]models.MassURL, error) {
-	return nil, fmt.Errorf("method not implemented")
+// GetURLS страница списка сокращенных URL пользователя.
+// URL упорядочены по времени создания и короткому URL,
+// следующая страница начинается после query.After.
+func (k *Keeper) GetURLS(ctx context.Context, query models.URLQuery) (models.URLPage, error) {
+	select {
+	case <-ctx.Done():
+		return models.URLPage{}, ctx.Err()
+	default:
+		desc := query.Sort != models.SortCreatedAsc
+		// before a предшествует b в порядке сортировки
+		before := func(a, b models.URLCursor) bool {
+			if desc {
+				return cursorLess(b, a)
+			}
+			return cursorLess(a, b)
+		}
+
+		k.mutex.RLock()
+		matched := []models.FileURL{}
+		for _, url := range k.storage {
+			if !matchQuery(url, query) {
+				continue
+			}
+			if query.After != nil && !before(*query.After, urlCursor(url)) {
+				continue
+			}
+			matched = append(matched, url)
+		}
+		k.mutex.RUnlock()
+
+		sort.Slice(matched, func(i, j int) bool {
+			return before(urlCursor(matched[i]), urlCursor(matched[j]))
+		})
+
+		page := models.URLPage{}
+		if query.Limit > 0 && len(matched) > query.Limit {
+			next := urlCursor(matched[query.Limit-1])
+			page.Next = &next
+			matched = matched[:query.Limit]
+		}
+
+		page.URLs = make([]models.MassURL, 0, len(matched))
+		for _, url := range matched {
+			page.URLs = append(page.URLs, models.MassURL{
+				ShortURL:      url.ShortURL,
+				OriginalURL:   url.OriginalURL,
+				Tags:          url.Tags,
+				Folder:        url.Folder,
+				DeletedAt:     url.DeletedAt,
+				CreatedAt:     url.CreatedAt,
+				UpdatedAt:     url.UpdatedAt,
+				CreatorIPHash: url.CreatorIPHash,
+				UserAgent:     url.UserAgent,
+			})
+		}
+
+		return page, nil
+	}
 }
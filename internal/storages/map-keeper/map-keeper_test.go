@@ -2,17 +2,24 @@ package mapkeeper
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	dbkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/db-keeper"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/storages/map-keeper/file"
 )
 
 func TestKeeper(t *testing.T) {
 
-	stor := New("")
+	stor := New(zaptest.NewLogger(t), "", file.SyncNever, 0)
 
 	tests := []struct {
 		name        string
@@ -41,7 +48,7 @@ func TestKeeper(t *testing.T) {
 			)
 
 			if !tt.isError {
-				id, err = stor.PostURL(context.Background(), tt.url, "")
+				id, err = stor.PostURL(context.Background(), models.URLRequest{URL: tt.url}, "", 0)
 				require.NoError(t, err)
 				assert.NotEmpty(t, id)
 			}
@@ -65,6 +72,7 @@ func TestKeeperSaveReadFile(t *testing.T) {
 	absDir, err := filepath.Abs("")
 	require.NoError(t, err)
 	dir, err := os.MkdirTemp(absDir, "testfile*")
+	require.NoError(t, err)
 
 	t.Cleanup(func() {
 		t.Helper()
@@ -72,22 +80,962 @@ func TestKeeperSaveReadFile(t *testing.T) {
 		require.NoError(t, err)
 	})
 
-	saveData := map[string]string{
-		"fdfsfwewq2":  "https://ya.ru/",
-		"fdfdd455654": "https://practicum.yandex.ru/",
-	}
+	ctx := context.Background()
 	path := dir + "/testfileKeeper.json"
-	storage := New(path)
-	storage.storage = saveData
+	const userID = "a6b8b5a4-7d2f-4c36-9cf1-5b9a0c0e3d11"
+
+	storage := New(zaptest.NewLogger(t), path, file.SyncAlways, 0)
+	require.NoError(t, storage.LoadFromFile())
+
+	id, err := storage.PostURL(ctx, models.URLRequest{URL: "https://ya.ru/"}, userID, 0)
+	require.NoError(t, err)
+	_, err = storage.SaveURLS(ctx, []models.BatchRequest{
+		{CorrelationID: "1", OriginalURL: "https://practicum.yandex.ru/"},
+	}, "", 0)
+	require.NoError(t, err)
+	storage.DeleteURLS(ctx, []models.DeleteURL{{ShortURL: id, UserID: userID}})
+
+	// данные записаны в журнал сразу, Close не вызывается
+	restored := New(zaptest.NewLogger(t), path, file.SyncAlways, 0)
+	require.NoError(t, restored.LoadFromFile())
+	defer restored.Close()
+
+	assert.EqualValues(t, storage.storage, restored.storage)
+	require.NoError(t, storage.Close())
+}
+
+func TestKeeperTornRecord(t *testing.T) {
+
+	absDir, err := filepath.Abs("")
+	require.NoError(t, err)
+	dir, err := os.MkdirTemp(absDir, "testfile*")
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		t.Helper()
+		err = os.RemoveAll(dir)
+		require.NoError(t, err)
+	})
+
+	path := dir + "/testfileKeeper.json"
+	data := `{"shortUrl":"fdfsfwewq2","originalUrl":"https://ya.ru/"}` + "\n" +
+		`{"shortUrl":"fdfdd45565","origi`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0644))
+
+	storage := New(zaptest.NewLogger(t), path, file.SyncAlways, 0)
+	require.NoError(t, storage.LoadFromFile())
+
+	assert.Len(t, storage.storage, 1)
+
+	id, err := storage.PostURL(context.Background(), models.URLRequest{URL: "https://go.dev/"}, "", 0)
+	require.NoError(t, err)
+	require.NoError(t, storage.Close())
+
+	restored := New(zaptest.NewLogger(t), path, file.SyncAlways, 0)
+	require.NoError(t, restored.LoadFromFile())
+	defer restored.Close()
+
+	url, err := restored.GetURL(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, "https://go.dev/", url)
+	assert.Len(t, restored.storage, 2)
+}
+
+func TestKeeperUserURLS(t *testing.T) {
+	ctx := context.Background()
+	stor := New(zaptest.NewLogger(t), "", file.SyncNever, 0)
+
+	const (
+		owner = "a6b8b5a4-7d2f-4c36-9cf1-5b9a0c0e3d11"
+		other = "0f0a4b8e-1c2d-4e5f-8a9b-0c1d2e3f4a5b"
+	)
+
+	id, err := stor.PostURL(ctx, models.URLRequest{URL: "https://ya.ru/"}, owner, 0)
+	require.NoError(t, err)
+
+	batch, err := stor.SaveURLS(ctx, []models.BatchRequest{
+		{CorrelationID: "1", OriginalURL: "https://practicum.yandex.ru/"},
+	}, owner, 0)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	_, err = stor.PostURL(ctx, models.URLRequest{URL: "https://go.dev/"}, other, 0)
+	require.NoError(t, err)
+
+	urls := listURLS(t, stor, owner, models.DeletedExclude)
+	assert.ElementsMatch(t, []models.MassURL{
+		{ShortURL: id, OriginalURL: "https://ya.ru/"},
+		{ShortURL: batch[0].ShortURL, OriginalURL: "https://practicum.yandex.ru/"},
+	}, withoutMetadata(urls))
+
+	// чужой пользователь не может удалить URL
+	stor.DeleteURLS(ctx, []models.DeleteURL{{ShortURL: id, UserID: other}})
+	url, err := stor.GetURL(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "https://ya.ru/", url)
+
+	stor.DeleteURLS(ctx, []models.DeleteURL{{ShortURL: id, UserID: owner}})
+	_, err = stor.GetURL(ctx, id)
+	assert.ErrorIs(t, err, dbkeeper.ErrURLRemoved)
+}
+
+func TestKeeperCompact(t *testing.T) {
+
+	absDir, err := filepath.Abs("")
+	require.NoError(t, err)
+	dir, err := os.MkdirTemp(absDir, "testfile*")
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		t.Helper()
+		err = os.RemoveAll(dir)
+		require.NoError(t, err)
+	})
+
+	ctx := context.Background()
+	path := dir + "/testfileKeeper.json"
+	const userID = "a6b8b5a4-7d2f-4c36-9cf1-5b9a0c0e3d11"
+
+	storage := New(zaptest.NewLogger(t), path, file.SyncAlways, 0)
+	require.NoError(t, storage.LoadFromFile())
+
+	for i := 0; i < 10; i++ {
+		id, err := storage.PostURL(ctx, models.URLRequest{URL: fmt.Sprintf("https://ya.ru/%d", i)}, userID, 0)
+		require.NoError(t, err)
+		storage.DeleteURLS(ctx, []models.DeleteURL{{ShortURL: id, UserID: userID}})
+	}
+
+	before, err := storage.FileSize()
+	require.NoError(t, err)
+
+	require.NoError(t, storage.Compact())
+
+	after, err := storage.FileSize()
+	require.NoError(t, err)
+	assert.Less(t, after, before)
+
+	// журнал продолжает писаться в новый файл
+	id, err := storage.PostURL(ctx, models.URLRequest{URL: "https://go.dev/"}, userID, 0)
+	require.NoError(t, err)
+	require.NoError(t, storage.Close())
+
+	restored := New(zaptest.NewLogger(t), path, file.SyncAlways, 0)
+	require.NoError(t, restored.LoadFromFile())
+	defer restored.Close()
+
+	assert.EqualValues(t, storage.storage, restored.storage)
+	url, err := restored.GetURL(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "https://go.dev/", url)
+
+	tmp, err := filepath.Glob(path + ".*.tmp")
+	require.NoError(t, err)
+	assert.Empty(t, tmp)
+}
+
+func TestKeeperCompactAfterClose(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "urls.json")
+	const userID = "a6b8b5a4-7d2f-4c36-9cf1-5b9a0c0e3d11"
+
+	storage := New(zaptest.NewLogger(t), path, file.SyncAlways, 0)
+	require.NoError(t, storage.LoadFromFile())
+	id, err := storage.PostURL(ctx, models.URLRequest{URL: "https://ya.ru/"}, userID, 0)
+	require.NoError(t, err)
+
+	// Close во время записи снимка
+	snapshot, err := file.NewSnapshot(path)
+	require.NoError(t, err)
+	storage.compacting = true
+	require.NoError(t, storage.Close())
 
-	err = storage.SaveToFile()
+	require.NoError(t, storage.replaceJournal(snapshot))
+	assert.Nil(t, storage.producer)
+	assert.False(t, storage.compacting)
+
+	tmp, err := filepath.Glob(path + ".*.tmp")
+	require.NoError(t, err)
+	assert.Empty(t, tmp)
+
+	restored := New(zaptest.NewLogger(t), path, file.SyncAlways, 0)
+	require.NoError(t, restored.LoadFromFile())
+	defer restored.Close()
+
+	url, err := restored.GetURL(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "https://ya.ru/", url)
+}
+
+func TestKeeperAlias(t *testing.T) {
+	ctx := context.Background()
+	stor := New(zaptest.NewLogger(t), "", file.SyncNever, 0)
+
+	id, err := stor.PostURL(ctx, models.URLRequest{URL: "https://ya.ru/", Alias: "spring-sale"}, "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "spring-sale", id)
+
+	_, err = stor.PostURL(ctx, models.URLRequest{URL: "https://go.dev/", Alias: "spring-sale"}, "", 0)
+	assert.ErrorIs(t, err, dbkeeper.ErrAliasTaken)
+
+	batch, err := stor.SaveURLS(ctx, []models.BatchRequest{
+		{CorrelationID: "1", OriginalURL: "https://go.dev/", Alias: "summer-sale"},
+		{CorrelationID: "2", OriginalURL: "https://pkg.go.dev/", Alias: "spring-sale"},
+	}, "", 0)
 	require.NoError(t, err)
 
-	storage.storage = map[string]string{}
+	// занятый алиас не мешает сохранить остальные элементы пакета
+	require.Len(t, batch, 2)
+	assert.Equal(t, models.BatchResponse{
+		CorrelationID: "1", ShortURL: "summer-sale", Status: models.BatchCreated,
+	}, batch[0])
+	assert.Equal(t, "2", batch[1].CorrelationID)
+	assert.Equal(t, models.BatchInvalid, batch[1].Status)
+	assert.Contains(t, batch[1].Reason, "spring-sale")
 
-	err = storage.LoadFromFile()
+	// уже сокращенный URL с чужим алиасом - ошибка алиаса, а не BatchExisting
+	batch, err = stor.SaveURLS(ctx, []models.BatchRequest{
+		{CorrelationID: "1", OriginalURL: "https://go.dev/", Alias: "spring-sale"},
+		{CorrelationID: "2", OriginalURL: "https://go.dev/", Alias: "summer-sale"},
+	}, "", 0)
 	require.NoError(t, err)
+	require.Len(t, batch, 2)
+	assert.Equal(t, models.BatchInvalid, batch[0].Status)
+	assert.Contains(t, batch[0].Reason, "spring-sale")
+	assert.Equal(t, models.BatchResponse{
+		CorrelationID: "2", ShortURL: "summer-sale", Status: models.BatchExisting,
+	}, batch[1])
 
-	assert.EqualValues(t, storage.storage, saveData)
+	url, err := stor.GetURL(ctx, "spring-sale")
+	require.NoError(t, err)
+	assert.Equal(t, "https://ya.ru/", url)
+}
+
+func TestKeeperSaveURLSExisting(t *testing.T) {
+	ctx := context.Background()
+	stor := New(zaptest.NewLogger(t), "", file.SyncNever, 0)
+
+	id, err := stor.PostURL(ctx, models.URLRequest{URL: "https://ya.ru/"}, "", 0)
+	require.NoError(t, err)
+
+	// повторное сокращение возвращает существующий URL, как в БД
+	existing, err := stor.PostURL(ctx, models.URLRequest{URL: "https://ya.ru/"}, "", 0)
+	assert.ErrorIs(t, err, dbkeeper.ErrAlreadyExists)
+	assert.Equal(t, id, existing)
+
+	batch, err := stor.SaveURLS(ctx, []models.BatchRequest{
+		{CorrelationID: "1", OriginalURL: "https://ya.ru/"},
+		{CorrelationID: "2", OriginalURL: "https://go.dev/"},
+		{CorrelationID: "3", OriginalURL: "https://go.dev/"},
+	}, "", 0)
+	require.NoError(t, err)
+	require.Len(t, batch, 3)
+
+	assert.Equal(t, models.BatchResponse{CorrelationID: "1", ShortURL: id, Status: models.BatchExisting}, batch[0])
+	assert.Equal(t, models.BatchCreated, batch[1].Status)
+	// повтор внутри пакета ссылается на созданный URL
+	assert.Equal(t, models.BatchResponse{CorrelationID: "3", ShortURL: batch[1].ShortURL, Status: models.BatchExisting}, batch[2])
+}
 
+func TestKeeperExpired(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "expired.json")
+
+	stor := New(zaptest.NewLogger(t), path, file.SyncAlways, 0)
+	require.NoError(t, stor.LoadFromFile())
+
+	future := time.Now().Add(time.Hour)
+	alive, err := stor.PostURL(ctx, models.URLRequest{URL: "https://ya.ru/", ExpiresAt: &future}, "", 0)
+	require.NoError(t, err)
+
+	past := time.Now().Add(-time.Second)
+	expired, err := stor.PostURL(ctx, models.URLRequest{URL: "https://go.dev/", ExpiresAt: &past}, "", 0)
+	require.NoError(t, err)
+
+	_, err = stor.GetURL(ctx, expired)
+	assert.ErrorIs(t, err, dbkeeper.ErrURLExpired)
+
+	deleted, err := stor.DeleteExpired(ctx, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, deleted)
+	require.NoError(t, stor.Close())
+
+	// удаление переживает перезапуск
+	restored := New(zaptest.NewLogger(t), path, file.SyncAlways, 0)
+	require.NoError(t, restored.LoadFromFile())
+	defer restored.Close()
+
+	assert.Len(t, restored.storage, 1)
+	url, err := restored.GetURL(ctx, alive)
+	require.NoError(t, err)
+	assert.Equal(t, "https://ya.ru/", url)
+}
+
+func TestKeeperReshortenExpired(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "reshorten.json")
+	stor := New(zaptest.NewLogger(t), path, file.SyncAlways, 0)
+	require.NoError(t, stor.LoadFromFile())
+
+	const owner = "a6b8b5a4-7d2f-4c36-9cf1-5b9a0c0e3d11"
+	past := time.Now().Add(-time.Second)
+
+	id, err := stor.PostURL(ctx, models.URLRequest{URL: "https://ya.ru/", Alias: "promo", ExpiresAt: &past}, owner, 0)
+	require.NoError(t, err)
+	_, err = stor.UpdateURL(ctx, id, owner, "https://go.dev/", models.URLLabels{})
+	require.NoError(t, err)
+	expired, err := stor.PostURL(ctx, models.URLRequest{URL: "https://pkg.go.dev/", ExpiresAt: &past}, owner, 0)
+	require.NoError(t, err)
+
+	// истекший алиас занимается заново без истории прежнего URL
+	_, err = stor.PostURL(ctx, models.URLRequest{URL: "https://practicum.yandex.ru/", Alias: "promo"}, owner, 0)
+	require.NoError(t, err)
+
+	// истекший оригинальный URL сокращается заново
+	batch, err := stor.SaveURLS(ctx, []models.BatchRequest{
+		{CorrelationID: "1", OriginalURL: "https://pkg.go.dev/"},
+	}, owner, 0)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	assert.Equal(t, models.BatchCreated, batch[0].Status)
+	assert.NotEqual(t, expired, batch[0].ShortURL)
+	require.NoError(t, stor.Close())
+
+	stor = New(zaptest.NewLogger(t), path, file.SyncAlways, 0)
+	require.NoError(t, stor.LoadFromFile())
+	defer stor.Close()
+
+	url, err := stor.GetURL(ctx, "promo")
+	require.NoError(t, err)
+	assert.Equal(t, "https://practicum.yandex.ru/", url)
+	url, err = stor.GetURL(ctx, batch[0].ShortURL)
+	require.NoError(t, err)
+	assert.Equal(t, "https://pkg.go.dev/", url)
+
+	history, err := stor.GetURLHistory(ctx, "promo", owner)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+func TestKeeperQuota(t *testing.T) {
+	ctx := context.Background()
+	stor := New(zaptest.NewLogger(t), "", file.SyncNever, 0)
+
+	const (
+		owner    = "a6b8b5a4-7d2f-4c36-9cf1-5b9a0c0e3d11"
+		maxLinks = 2
+	)
+
+	id, err := stor.PostURL(ctx, models.URLRequest{URL: "https://ya.ru/"}, owner, maxLinks)
+	require.NoError(t, err)
+
+	// уже сохраненный URL не расходует квоту
+	batch, err := stor.SaveURLS(ctx, []models.BatchRequest{
+		{CorrelationID: "1", OriginalURL: "https://ya.ru/"},
+		{CorrelationID: "2", OriginalURL: "https://go.dev/"},
+	}, owner, maxLinks)
+	require.NoError(t, err)
+	require.Len(t, batch, 2)
+	assert.Equal(t, models.BatchExisting, batch[0].Status)
+	assert.Equal(t, models.BatchCreated, batch[1].Status)
+
+	_, err = stor.PostURL(ctx, models.URLRequest{URL: "https://pkg.go.dev/"}, owner, maxLinks)
+	var quotaErr *dbkeeper.QuotaError
+	require.ErrorAs(t, err, &quotaErr)
+	assert.Equal(t, dbkeeper.QuotaError{Limit: maxLinks, Value: 3}, *quotaErr)
+
+	// пакет сверх квоты не сохраняется целиком
+	_, err = stor.SaveURLS(ctx, []models.BatchRequest{
+		{CorrelationID: "1", OriginalURL: "https://pkg.go.dev/"},
+	}, owner, maxLinks)
+	assert.ErrorIs(t, err, dbkeeper.ErrQuotaExceeded)
+
+	userURLS, err := stor.CountUserURLS(ctx, owner)
+	require.NoError(t, err)
+	assert.EqualValues(t, maxLinks, userURLS)
+
+	// удаленный URL освобождает квоту до восстановления
+	stor.DeleteURLS(ctx, []models.DeleteURL{{ShortURL: id, UserID: owner}})
+	_, err = stor.PostURL(ctx, models.URLRequest{URL: "https://pkg.go.dev/"}, owner, maxLinks)
+	require.NoError(t, err)
+
+	_, err = stor.RestoreURLS(ctx, []string{id}, owner, time.Time{}, maxLinks)
+	assert.ErrorIs(t, err, dbkeeper.ErrQuotaExceeded)
+}
+
+func TestKeeperStats(t *testing.T) {
+	ctx := context.Background()
+	stor := New(zaptest.NewLogger(t), "", file.SyncNever, 0)
+
+	const owner = "a3f1c2d4-0000-0000-0000-000000000001"
+	id, err := stor.PostURL(ctx, models.URLRequest{URL: "https://ya.ru/"}, owner, 0)
+	require.NoError(t, err)
+
+	now := time.Now().UTC()
+	stor.SaveClicks(ctx, []models.Click{
+		{ShortURL: id, Timestamp: now},
+		{ShortURL: id, Timestamp: now},
+		{ShortURL: id, Timestamp: now.Add(-3 * 24 * time.Hour)},
+		{ShortURL: "unknown", Timestamp: now},
+	})
+
+	stats, err := stor.GetStats(ctx, id, owner, now.Add(-24*time.Hour), now.Add(-30*24*time.Hour))
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, stats.Total)
+	require.Len(t, stats.Hourly, 1)
+	assert.EqualValues(t, 2, stats.Hourly[0].Clicks)
+	assert.Equal(t, now.Truncate(time.Hour), stats.Hourly[0].Start)
+	require.Len(t, stats.Daily, 2)
+	assert.EqualValues(t, 1, stats.Daily[0].Clicks)
+	assert.EqualValues(t, 2, stats.Daily[1].Clicks)
+
+	_, err = stor.GetStats(ctx, id, "other", now, now)
+	assert.ErrorIs(t, err, dbkeeper.ErrNotOwner)
+
+	_, err = stor.GetStats(ctx, "unknown", owner, now, now)
+	assert.ErrorIs(t, err, dbkeeper.ErrNotFound)
+}
+
+func TestKeeperCounts(t *testing.T) {
+	ctx := context.Background()
+	stor := New(zaptest.NewLogger(t), "", file.SyncNever, 0)
+
+	const (
+		user1 = "a3f1c2d4-0000-0000-0000-000000000001"
+		user2 = "a3f1c2d4-0000-0000-0000-000000000002"
+	)
+
+	id, err := stor.PostURL(ctx, models.URLRequest{URL: "https://ya.ru/"}, user1, 0)
+	require.NoError(t, err)
+	_, err = stor.PostURL(ctx, models.URLRequest{URL: "https://go.dev/"}, user1, 0)
+	require.NoError(t, err)
+	past := time.Now().Add(-time.Second)
+	_, err = stor.PostURL(ctx, models.URLRequest{URL: "https://pkg.go.dev/", ExpiresAt: &past}, user2, 0)
+	require.NoError(t, err)
+	_, err = stor.PostURL(ctx, models.URLRequest{URL: "https://example.com/"}, "", 0)
+	require.NoError(t, err)
+
+	stor.DeleteURLS(ctx, []models.DeleteURL{{ShortURL: id, UserID: user1}})
+
+	counts, err := stor.CountURLS(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, models.URLCounts{Total: 4, Deleted: 1, Expired: 1}, counts)
+
+	users, err := stor.CountUsers(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, users)
+
+	userURLS, err := stor.CountUserURLS(ctx, user1)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, userURLS)
+}
+
+func TestKeeperUsers(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "users.json")
+
+	const (
+		anon  = "a3f1c2d4-0000-0000-0000-000000000001"
+		other = "a3f1c2d4-0000-0000-0000-000000000002"
+	)
+	user := models.User{
+		ID:           "a3f1c2d4-0000-0000-0000-000000000003",
+		Login:        "gopher",
+		PasswordHash: "hash",
+		CreatedAt:    time.Now().UTC().Truncate(time.Second),
+	}
+
+	stor := New(zaptest.NewLogger(t), path, file.SyncAlways, 0)
+	require.NoError(t, stor.LoadFromFile())
+
+	require.NoError(t, stor.CreateUser(ctx, user))
+	assert.ErrorIs(t, stor.CreateUser(ctx, models.User{ID: other, Login: "gopher"}), dbkeeper.ErrLoginTaken)
+
+	_, err := stor.PostURL(ctx, models.URLRequest{URL: "https://ya.ru/"}, anon, 0)
+	require.NoError(t, err)
+	_, err = stor.PostURL(ctx, models.URLRequest{URL: "https://go.dev/"}, anon, 0)
+	require.NoError(t, err)
+	_, err = stor.PostURL(ctx, models.URLRequest{URL: "https://pkg.go.dev/"}, other, 0)
+	require.NoError(t, err)
+
+	// с ссылками anon пользователь превысил бы квоту, не переносится ни одна
+	claimed, err := stor.ClaimURLS(ctx, anon, user.ID, 1)
+	assert.ErrorIs(t, err, dbkeeper.ErrQuotaExceeded)
+	assert.Zero(t, claimed)
+	assert.Len(t, listURLS(t, stor, anon, models.DeletedExclude), 2)
+
+	claimed, err = stor.ClaimURLS(ctx, anon, user.ID, 2)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, claimed)
+
+	// ссылки зарегистрированного пользователя не переносятся
+	claimed, err = stor.ClaimURLS(ctx, user.ID, other, 0)
+	require.NoError(t, err)
+	assert.Zero(t, claimed)
+	require.NoError(t, stor.Close())
+
+	restored := New(zaptest.NewLogger(t), path, file.SyncAlways, 0)
+	require.NoError(t, restored.LoadFromFile())
+	defer restored.Close()
+
+	got, err := restored.GetUserByLogin(ctx, "gopher")
+	require.NoError(t, err)
+	assert.Equal(t, user, got)
+
+	_, err = restored.GetUserByLogin(ctx, "unknown")
+	assert.ErrorIs(t, err, dbkeeper.ErrUserNotFound)
+
+	urls := listURLS(t, restored, user.ID, models.DeletedExclude)
+	assert.Len(t, urls, 2)
+
+	urls = listURLS(t, restored, anon, models.DeletedExclude)
+	assert.Empty(t, urls)
+}
+
+func TestKeeperAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "keys.json")
+
+	key := models.APIKey{
+		ID:        "key1",
+		UserID:    "user",
+		Name:      "ci",
+		KeyHash:   "hash1",
+		Scopes:    []string{models.ScopeShorten},
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	revoked := models.APIKey{ID: "key2", UserID: "user", Name: "old", KeyHash: "hash2"}
+
+	stor := New(zaptest.NewLogger(t), path, file.SyncAlways, 0)
+	require.NoError(t, stor.LoadFromFile())
+
+	require.NoError(t, stor.CreateAPIKey(ctx, key))
+	require.NoError(t, stor.CreateAPIKey(ctx, revoked))
+
+	usedAt := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, stor.TouchAPIKey(ctx, key.ID, usedAt))
+	require.NoError(t, stor.RevokeAPIKey(ctx, revoked.ID, revoked.UserID))
+	assert.ErrorIs(t, stor.RevokeAPIKey(ctx, key.ID, "other"), dbkeeper.ErrAPIKeyNotFound)
+	require.NoError(t, stor.Close())
+
+	restored := New(zaptest.NewLogger(t), path, file.SyncAlways, 0)
+	require.NoError(t, restored.LoadFromFile())
+	defer restored.Close()
+
+	got, err := restored.GetAPIKeyByHash(ctx, "hash1")
+	require.NoError(t, err)
+	key.LastUsedAt = &usedAt
+	assert.Equal(t, key, got)
+
+	_, err = restored.GetAPIKeyByHash(ctx, "hash2")
+	assert.ErrorIs(t, err, dbkeeper.ErrAPIKeyNotFound)
+
+	keys, err := restored.GetAPIKeys(ctx, "user")
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+}
+
+func TestKeeperBlockURLS(t *testing.T) {
+	ctx := context.Background()
+	filePath := filepath.Join(t.TempDir(), "urls.json")
+	stor := New(zaptest.NewLogger(t), filePath, file.SyncAlways, 0)
+	require.NoError(t, stor.LoadFromFile())
+
+	id, err := stor.PostURL(ctx, models.URLRequest{URL: "https://ya.ru/"}, "", 0)
+	require.NoError(t, err)
+
+	updated, err := stor.BlockURLS(ctx, []string{id, "missing"}, true)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, updated)
+
+	// повторная блокировка ничего не меняет
+	updated, err = stor.BlockURLS(ctx, []string{id}, true)
+	require.NoError(t, err)
+	assert.Zero(t, updated)
+
+	_, err = stor.GetURL(ctx, id)
+	assert.ErrorIs(t, err, dbkeeper.ErrURLBlocked)
+	require.NoError(t, stor.Close())
+
+	// блокировка восстанавливается из журнала
+	stor = New(zaptest.NewLogger(t), filePath, file.SyncAlways, 0)
+	require.NoError(t, stor.LoadFromFile())
+	_, err = stor.GetURL(ctx, id)
+	assert.ErrorIs(t, err, dbkeeper.ErrURLBlocked)
+
+	_, err = stor.BlockURLS(ctx, []string{id}, false)
+	require.NoError(t, err)
+	url, err := stor.GetURL(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "https://ya.ru/", url)
+	require.NoError(t, stor.Close())
+}
+
+func TestKeeperUpdateURL(t *testing.T) {
+	ctx := context.Background()
+	filePath := filepath.Join(t.TempDir(), "urls.json")
+	stor := New(zaptest.NewLogger(t), filePath, file.SyncAlways, 0)
+	require.NoError(t, stor.LoadFromFile())
+
+	const (
+		owner = "a6b8b5a4-7d2f-4c36-9cf1-5b9a0c0e3d11"
+		other = "0f0a4b8e-1c2d-4e5f-8a9b-0c1d2e3f4a5b"
+	)
+
+	id, err := stor.PostURL(ctx, models.URLRequest{URL: "https://ya.ru/"}, owner, 0)
+	require.NoError(t, err)
+	otherID, err := stor.PostURL(ctx, models.URLRequest{URL: "https://go.dev/"}, other, 0)
+	require.NoError(t, err)
+
+	update := func(id string, url string) error {
+		_, err := stor.UpdateURL(ctx, id, owner, url, models.URLLabels{})
+		return err
+	}
+
+	assert.ErrorIs(t, update("missing", "https://pkg.go.dev/"), dbkeeper.ErrNotFound)
+	assert.ErrorIs(t, update(otherID, "https://pkg.go.dev/"), dbkeeper.ErrNotOwner)
+
+	// при ошибке URL теги и папка не меняются
+	folder := "work"
+	_, err = stor.UpdateURL(ctx, id, owner, "https://go.dev/", models.URLLabels{Folder: &folder})
+	assert.ErrorIs(t, err, dbkeeper.ErrAlreadyExists)
+	assert.Empty(t, stor.storage[id].Folder)
+
+	updated, err := stor.UpdateURL(ctx, id, owner, "https://pkg.go.dev/", models.URLLabels{
+		Tags:   &[]string{"docs"},
+		Folder: &folder,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.MassURL{
+		ShortURL:    id,
+		OriginalURL: "https://pkg.go.dev/",
+		Tags:        []string{"docs"},
+		Folder:      "work",
+	}, updated)
+
+	require.NoError(t, update(id, "https://practicum.yandex.ru/"))
+	// тот же URL не создает новую ревизию
+	require.NoError(t, update(id, "https://practicum.yandex.ru/"))
+
+	url, err := stor.GetURL(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "https://practicum.yandex.ru/", url)
+	require.NoError(t, stor.Close())
+
+	// история восстанавливается из журнала
+	stor = New(zaptest.NewLogger(t), filePath, file.SyncAlways, 0)
+	require.NoError(t, stor.LoadFromFile())
+	defer stor.Close()
+
+	history, err := stor.GetURLHistory(ctx, id, owner)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, int64(2), history[0].Revision)
+	assert.Equal(t, "https://pkg.go.dev/", history[0].OriginalURL)
+	assert.Equal(t, int64(1), history[1].Revision)
+	assert.Equal(t, "https://ya.ru/", history[1].OriginalURL)
+
+	_, err = stor.GetURLHistory(ctx, id, other)
+	assert.ErrorIs(t, err, dbkeeper.ErrNotOwner)
+
+	stor.DeleteURLS(ctx, []models.DeleteURL{{ShortURL: id, UserID: owner}})
+	assert.ErrorIs(t, update(id, "https://ya.ru/"), dbkeeper.ErrURLRemoved)
+}
+
+func TestKeeperRestoreURLS(t *testing.T) {
+	ctx := context.Background()
+	filePath := filepath.Join(t.TempDir(), "urls.json")
+	stor := New(zaptest.NewLogger(t), filePath, file.SyncAlways, 0)
+	require.NoError(t, stor.LoadFromFile())
+
+	const (
+		owner = "a6b8b5a4-7d2f-4c36-9cf1-5b9a0c0e3d11"
+		other = "0f0a4b8e-1c2d-4e5f-8a9b-0c1d2e3f4a5b"
+	)
+
+	id, err := stor.PostURL(ctx, models.URLRequest{URL: "https://ya.ru/"}, owner, 0)
+	require.NoError(t, err)
+	keptID, err := stor.PostURL(ctx, models.URLRequest{URL: "https://go.dev/"}, owner, 0)
+	require.NoError(t, err)
+
+	stor.DeleteURLS(ctx, []models.DeleteURL{{ShortURL: id, UserID: owner}})
+
+	urls := listURLS(t, stor, owner, models.DeletedExclude)
+	assert.Equal(t, []models.MassURL{{ShortURL: keptID, OriginalURL: "https://go.dev/"}}, withoutMetadata(urls))
+
+	urls = listURLS(t, stor, owner, models.DeletedInclude)
+	require.Len(t, urls, 2)
+	for _, url := range urls {
+		assert.Equal(t, url.ShortURL == id, url.DeletedAt != nil, url.ShortURL)
+		assert.NotNil(t, url.CreatedAt, url.ShortURL)
+	}
+
+	// срок восстановления истек
+	restored, err := stor.RestoreURLS(ctx, []string{id}, owner, time.Now().Add(time.Minute), 0)
+	require.NoError(t, err)
+	assert.Empty(t, restored)
+
+	// чужие и неудаленные URL пропускаются
+	restored, err = stor.RestoreURLS(ctx, []string{id, keptID}, other, time.Time{}, 0)
+	require.NoError(t, err)
+	assert.Empty(t, restored)
+
+	restored, err = stor.RestoreURLS(ctx, []string{id, keptID, "missing"}, owner, time.Now().Add(-time.Minute), 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{id}, restored)
+
+	url, err := stor.GetURL(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "https://ya.ru/", url)
+
+	stor.DeleteURLS(ctx, []models.DeleteURL{{ShortURL: id, UserID: owner}})
+	require.NoError(t, stor.Close())
+
+	// время удаления восстанавливается из журнала
+	stor = New(zaptest.NewLogger(t), filePath, file.SyncAlways, 0)
+	require.NoError(t, stor.LoadFromFile())
+	defer stor.Close()
+
+	purged, err := stor.PurgeDeleted(ctx, time.Now().Add(-time.Minute), 10)
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+
+	purged, err = stor.PurgeDeleted(ctx, time.Now(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	urls = listURLS(t, stor, owner, models.DeletedInclude)
+	assert.Equal(t, []models.MassURL{{ShortURL: keptID, OriginalURL: "https://go.dev/"}}, withoutMetadata(urls))
+}
+
+// listURLS весь список URL пользователя от новых к старым.
+func listURLS(t *testing.T, stor *Keeper, userID string, deleted string) []models.MassURL {
+	t.Helper()
+
+	page, err := stor.GetURLS(context.Background(), models.URLQuery{
+		UserID:  userID,
+		Sort:    models.SortCreatedDesc,
+		Deleted: deleted,
+	})
+	require.NoError(t, err)
+	require.Nil(t, page.Next)
+	return page.URLs
+}
+
+func TestKeeperGetURLSPages(t *testing.T) {
+	ctx := context.Background()
+	stor := New(zaptest.NewLogger(t), "", file.SyncNever, 0)
+
+	const userID = "a6b8b5a4-7d2f-4c36-9cf1-5b9a0c0e3d11"
+
+	originals := []string{
+		"https://ya.ru/",
+		"https://go.dev/",
+		"https://pkg.go.dev/",
+		"https://practicum.yandex.ru/",
+	}
+	ids := make([]string, 0, len(originals))
+	for _, original := range originals {
+		id, err := stor.PostURL(ctx, models.URLRequest{URL: original}, userID, 0)
+		require.NoError(t, err)
+		ids = append(ids, id)
+		// время создания должно различаться
+		time.Sleep(time.Millisecond)
+	}
+	_, err := stor.PostURL(ctx, models.URLRequest{URL: "https://example.com/"}, "", 0)
+	require.NoError(t, err)
+	stor.DeleteURLS(ctx, []models.DeleteURL{{ShortURL: ids[1], UserID: userID}})
+
+	readAll := func(query models.URLQuery) []string {
+		got := []string{}
+		for {
+			page, err := stor.GetURLS(ctx, query)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(page.URLs), query.Limit)
+			for _, url := range page.URLs {
+				got = append(got, url.ShortURL)
+			}
+			if page.Next == nil {
+				return got
+			}
+			query.After = page.Next
+		}
+	}
+
+	assert.Equal(t, []string{ids[3], ids[2], ids[0]}, readAll(models.URLQuery{
+		UserID: userID,
+		Limit:  2,
+		Sort:   models.SortCreatedDesc,
+	}))
+
+	assert.Equal(t, []string{ids[0], ids[1], ids[2], ids[3]}, readAll(models.URLQuery{
+		UserID:  userID,
+		Limit:   1,
+		Sort:    models.SortCreatedAsc,
+		Deleted: models.DeletedInclude,
+	}))
+
+	assert.Equal(t, []string{ids[1]}, readAll(models.URLQuery{
+		UserID:  userID,
+		Limit:   10,
+		Deleted: models.DeletedOnly,
+	}))
+
+	assert.Equal(t, []string{ids[2], ids[1]}, readAll(models.URLQuery{
+		UserID:   userID,
+		Limit:    10,
+		Contains: "GO.DEV",
+		Deleted:  models.DeletedInclude,
+	}))
+}
+
+// withoutMetadata URL без времени создания, изменения и сведений о создателе.
+func withoutMetadata(urls []models.MassURL) []models.MassURL {
+	result := make([]models.MassURL, 0, len(urls))
+	for _, url := range urls {
+		result = append(result, models.MassURL{
+			ShortURL:    url.ShortURL,
+			OriginalURL: url.OriginalURL,
+			DeletedAt:   url.DeletedAt,
+		})
+	}
+	return result
+}
+
+func TestKeeperMetadata(t *testing.T) {
+	ctx := context.Background()
+	filePath := filepath.Join(t.TempDir(), "urls.json")
+
+	const userID = "a6b8b5a4-7d2f-4c36-9cf1-5b9a0c0e3d11"
+
+	// запись в формате без времени создания и сведений о создателе
+	require.NoError(t, os.WriteFile(filePath,
+		[]byte(`{"shortUrl":"legacy","originalUrl":"https://go.dev/","userId":"`+userID+`"}`+"\n"),
+		0o644,
+	))
+
+	stor := New(zaptest.NewLogger(t), filePath, file.SyncAlways, 0)
+	require.NoError(t, stor.LoadFromFile())
+
+	creator := models.Creator{IPHash: "5d41402abc4b2a76b9719d911017c592", UserAgent: "curl/8.5.0"}
+	id, err := stor.PostURL(ctx, models.URLRequest{URL: "https://ya.ru/", Creator: creator}, userID, 0)
+	require.NoError(t, err)
+
+	urls := listURLS(t, stor, userID, models.DeletedExclude)
+	require.Len(t, urls, 2)
+	created := urls[0]
+	assert.Equal(t, id, created.ShortURL)
+	require.NotNil(t, created.CreatedAt)
+	assert.Equal(t, created.CreatedAt, created.UpdatedAt)
+	assert.Equal(t, creator.IPHash, created.CreatorIPHash)
+	assert.Equal(t, creator.UserAgent, created.UserAgent)
+
+	legacy := urls[1]
+	assert.Equal(t, "legacy", legacy.ShortURL)
+	assert.Nil(t, legacy.CreatedAt)
+	assert.Nil(t, legacy.UpdatedAt)
+
+	_, err = stor.UpdateURL(ctx, id, userID, "https://pkg.go.dev/", models.URLLabels{})
+	require.NoError(t, err)
+	require.NoError(t, stor.Close())
+
+	// сведения восстанавливаются из журнала
+	stor = New(zaptest.NewLogger(t), filePath, file.SyncAlways, 0)
+	require.NoError(t, stor.LoadFromFile())
+	defer stor.Close()
+
+	urls = listURLS(t, stor, userID, models.DeletedExclude)
+	require.Len(t, urls, 2)
+	updated := urls[0]
+	assert.True(t, created.CreatedAt.Equal(*updated.CreatedAt))
+	assert.True(t, updated.UpdatedAt.After(*updated.CreatedAt))
+	assert.Equal(t, creator.IPHash, updated.CreatorIPHash)
+	assert.Equal(t, creator.UserAgent, updated.UserAgent)
+}
+
+func TestKeeperLabels(t *testing.T) {
+	ctx := context.Background()
+	filePath := filepath.Join(t.TempDir(), "urls.json")
+	stor := New(zaptest.NewLogger(t), filePath, file.SyncAlways, 0)
+	require.NoError(t, stor.LoadFromFile())
+
+	const (
+		owner = "a6b8b5a4-7d2f-4c36-9cf1-5b9a0c0e3d11"
+		other = "0f0a4b8e-1c2d-4e5f-8a9b-0c1d2e3f4a5b"
+	)
+
+	id, err := stor.PostURL(ctx, models.URLRequest{
+		URL:    "https://ya.ru/",
+		Tags:   []string{"news", "search"},
+		Folder: "work",
+	}, owner, 0)
+	require.NoError(t, err)
+	batch, err := stor.SaveURLS(ctx, []models.BatchRequest{
+		{CorrelationID: "1", OriginalURL: "https://go.dev/", Tags: []string{"go"}},
+		{CorrelationID: "2", OriginalURL: "https://pkg.go.dev/", Tags: []string{"go", "news"}, Folder: "work"},
+	}, owner, 0)
+	require.NoError(t, err)
+	require.Len(t, batch, 2)
+	goID, pkgID := batch[0].ShortURL, batch[1].ShortURL
+	_, err = stor.PostURL(ctx, models.URLRequest{URL: "https://example.com/", Tags: []string{"news"}}, other, 0)
+	require.NoError(t, err)
+
+	filter := func(tag, folder string) []string {
+		page, err := stor.GetURLS(ctx, models.URLQuery{UserID: owner, Tag: tag, Folder: folder})
+		require.NoError(t, err)
+		got := []string{}
+		for _, url := range page.URLs {
+			got = append(got, url.ShortURL)
+		}
+		return got
+	}
+
+	assert.ElementsMatch(t, []string{id, pkgID}, filter("news", ""))
+	assert.ElementsMatch(t, []string{goID, pkgID}, filter("go", ""))
+	assert.ElementsMatch(t, []string{id, pkgID}, filter("", "work"))
+	assert.ElementsMatch(t, []string{pkgID}, filter("go", "work"))
+	assert.Empty(t, filter("missing", ""))
+
+	_, err = stor.UpdateLabels(ctx, "missing", owner, models.URLLabels{})
+	assert.ErrorIs(t, err, dbkeeper.ErrNotFound)
+	_, err = stor.UpdateLabels(ctx, id, other, models.URLLabels{})
+	assert.ErrorIs(t, err, dbkeeper.ErrNotOwner)
+
+	// папка не задана и не меняется
+	tags := []string{"search"}
+	url, err := stor.UpdateLabels(ctx, id, owner, models.URLLabels{Tags: &tags})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"search"}, url.Tags)
+	assert.Equal(t, "work", url.Folder)
+
+	counts, err := stor.GetTags(ctx, owner)
+	require.NoError(t, err)
+	assert.Equal(t, []models.TagCount{
+		{Tag: "go", Count: 2},
+		{Tag: "news", Count: 1},
+		{Tag: "search", Count: 1},
+	}, counts)
+
+	// удаленные URL не учитываются
+	stor.DeleteURLS(ctx, []models.DeleteURL{{ShortURL: goID, UserID: owner}})
+	_, err = stor.UpdateLabels(ctx, goID, owner, models.URLLabels{})
+	assert.ErrorIs(t, err, dbkeeper.ErrURLRemoved)
+
+	noFolder := ""
+	_, err = stor.UpdateLabels(ctx, pkgID, owner, models.URLLabels{Folder: &noFolder})
+	require.NoError(t, err)
+	require.NoError(t, stor.Close())
+
+	// теги и папки восстанавливаются из журнала
+	stor = New(zaptest.NewLogger(t), filePath, file.SyncAlways, 0)
+	require.NoError(t, stor.LoadFromFile())
+	defer stor.Close()
+
+	counts, err = stor.GetTags(ctx, owner)
+	require.NoError(t, err)
+	assert.Equal(t, []models.TagCount{
+		{Tag: "go", Count: 1},
+		{Tag: "news", Count: 1},
+		{Tag: "search", Count: 1},
+	}, counts)
+	assert.Equal(t, []string{id}, filter("", "work"))
 }
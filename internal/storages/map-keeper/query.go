@@ -0,0 +1,61 @@
+package mapkeeper
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+)
+
+// urlCursor позиция URL в списке.
+// URL без времени создания считаются самыми старыми.
+func urlCursor(url models.FileURL) models.URLCursor {
+	var createdAt time.Time
+	if url.CreatedAt != nil {
+		createdAt = *url.CreatedAt
+	}
+	return models.URLCursor{CreatedAt: createdAt, ShortURL: url.ShortURL}
+}
+
+// cursorLess порядок по возрастанию времени создания и короткого URL.
+func cursorLess(a, b models.URLCursor) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ShortURL < b.ShortURL
+}
+
+// matchQuery URL пользователя из query, прошедший фильтры.
+func matchQuery(url models.FileURL, query models.URLQuery) bool {
+	if url.UserID != query.UserID {
+		return false
+	}
+
+	switch query.Deleted {
+	case models.DeletedInclude:
+	case models.DeletedOnly:
+		if !url.DeletedFlag {
+			return false
+		}
+	default:
+		if url.DeletedFlag {
+			return false
+		}
+	}
+
+	if len(query.Contains) > 0 &&
+		!strings.Contains(strings.ToLower(url.OriginalURL), strings.ToLower(query.Contains)) {
+		return false
+	}
+
+	if len(query.Tag) > 0 && !slices.Contains(url.Tags, query.Tag) {
+		return false
+	}
+
+	if len(query.Folder) > 0 && url.Folder != query.Folder {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,91 @@
+package mapkeeper
+
+import (
+	"context"
+	"time"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+)
+
+// RestoreURLS восстанавливает удаленные URL пользователя,
+// удаленные позже deletedAfter. Возвращает восстановленные URL.
+// Квота maxLinks учитывает только восстановленные URL,
+// 0 - без ограничения.
+func (k *Keeper) RestoreURLS(
+	ctx context.Context,
+	shortURLS []string,
+	userID string,
+	deletedAfter time.Time,
+	maxLinks int,
+) ([]string, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		k.mutex.Lock()
+		defer k.mutex.Unlock()
+
+		restorable := make([]models.FileURL, 0, len(shortURLS))
+		seen := make(map[string]struct{}, len(shortURLS))
+		for _, id := range shortURLS {
+			rec, ok := k.storage[id]
+			if !ok || rec.UserID != userID || !rec.DeletedFlag {
+				continue
+			}
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			if rec.DeletedAt == nil || !rec.DeletedAt.After(deletedAfter) {
+				continue
+			}
+			restorable = append(restorable, rec)
+		}
+
+		if err := k.checkQuota(userID, maxLinks, len(restorable)); err != nil {
+			return nil, err
+		}
+
+		restored := []string{}
+		for _, rec := range restorable {
+			now := time.Now().UTC()
+			rec.DeletedFlag = false
+			rec.DeletedAt = nil
+			rec.UpdatedAt = &now
+			if err := k.put(rec); err != nil {
+				return restored, err
+			}
+			restored = append(restored, rec.ShortURL)
+		}
+
+		return restored, nil
+	}
+}
+
+// PurgeDeleted окончательно удаляет до limit URL,
+// удаленных не позже deletedBefore.
+func (k *Keeper) PurgeDeleted(ctx context.Context, deletedBefore time.Time, limit int) (int64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+		k.mutex.Lock()
+		defer k.mutex.Unlock()
+
+		var deleted int64
+		for id, url := range k.storage {
+			if deleted >= int64(limit) {
+				break
+			}
+			if !url.DeletedFlag || url.DeletedAt == nil || url.DeletedAt.After(deletedBefore) {
+				continue
+			}
+			if err := k.put(models.FileURL{ShortURL: id, Removed: true}); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+
+		return deleted, nil
+	}
+}
@@ -0,0 +1,125 @@
+package mapkeeper
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	dbkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/db-keeper"
+)
+
+// revisionRecord запись журнала истории изменений.
+type revisionRecord struct {
+	ShortURL string `json:"shortUrl"`
+	models.URLRevision
+}
+
+// UpdateURL заменяет оригинальный URL пользователя,
+// прежнее значение сохраняется в истории изменений.
+// Теги и папка из labels меняются под той же блокировкой одной записью.
+// Возвращает URL после изменения.
+// Оригинальный URL, уже сокращенный другим URL, - ErrAlreadyExists.
+func (k *Keeper) UpdateURL(
+	ctx context.Context,
+	shortURL string,
+	userID string,
+	originalURL string,
+	labels models.URLLabels,
+) (models.MassURL, error) {
+	select {
+	case <-ctx.Done():
+		return models.MassURL{}, ctx.Err()
+	default:
+		k.mutex.Lock()
+		defer k.mutex.Unlock()
+
+		rec, ok := k.storage[shortURL]
+		switch {
+		case !ok:
+			return models.MassURL{}, dbkeeper.ErrNotFound
+		case len(rec.UserID) == 0 || rec.UserID != userID:
+			return models.MassURL{}, dbkeeper.ErrNotOwner
+		case rec.DeletedFlag:
+			return models.MassURL{}, dbkeeper.ErrURLRemoved
+		}
+
+		changed := rec.OriginalURL != originalURL
+		if changed {
+			for id, url := range k.storage {
+				if id != shortURL && url.OriginalURL == originalURL {
+					return models.MassURL{}, dbkeeper.ErrAlreadyExists
+				}
+			}
+		}
+		if !changed && labels.Tags == nil && labels.Folder == nil {
+			return massURL(rec), nil
+		}
+
+		now := time.Now().UTC()
+		if changed {
+			revisions := k.revisions[shortURL]
+			revision := revisionRecord{
+				ShortURL: shortURL,
+				URLRevision: models.URLRevision{
+					Revision:    int64(len(revisions)) + 1,
+					OriginalURL: rec.OriginalURL,
+					ReplacedAt:  now,
+				},
+			}
+			if k.revisionsProducer != nil {
+				if err := k.revisionsProducer.Write(&revision); err != nil {
+					return models.MassURL{}, fmt.Errorf("failed to write to file: %w", err)
+				}
+			}
+			k.revisions[shortURL] = append(revisions, revision.URLRevision)
+			rec.OriginalURL = originalURL
+		}
+
+		applyLabels(&rec, labels)
+		rec.UpdatedAt = &now
+		if err := k.put(rec); err != nil {
+			return models.MassURL{}, err
+		}
+		return massURL(rec), nil
+	}
+}
+
+// GetURLHistory прежние оригинальные URL, начиная с последнего.
+func (k *Keeper) GetURLHistory(ctx context.Context, shortURL string, userID string) ([]models.URLRevision, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+		k.mutex.RLock()
+		defer k.mutex.RUnlock()
+
+		rec, ok := k.storage[shortURL]
+		if !ok {
+			return nil, dbkeeper.ErrNotFound
+		}
+		if len(rec.UserID) == 0 || rec.UserID != userID {
+			return nil, dbkeeper.ErrNotOwner
+		}
+
+		revisions := k.revisions[shortURL]
+		history := make([]models.URLRevision, 0, len(revisions))
+		for i := len(revisions) - 1; i >= 0; i-- {
+			history = append(history, revisions[i])
+		}
+		return history, nil
+	}
+}
+
+// replayRevisions история изменений URL, окончательно удаленных
+// до перезапуска, пропускается. История прежнего URL с тем же
+// коротким URL (истекший алиас занят заново) тоже пропускается.
+func (k *Keeper) replayRevisions() error {
+	return replayJournal(k, k.revisionsPath, func(rec revisionRecord) {
+		url, ok := k.storage[rec.ShortURL]
+		if !ok || (url.CreatedAt != nil && rec.ReplacedAt.Before(*url.CreatedAt)) {
+			return
+		}
+		k.revisions[rec.ShortURL] = append(k.revisions[rec.ShortURL], rec.URLRevision)
+	})
+}
@@ -0,0 +1,152 @@
+package mapkeeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/domain/models"
+	dbkeeper "github.com/vladislav-kr/yp-go-url-shortener/internal/storages/db-keeper"
+	"github.com/vladislav-kr/yp-go-url-shortener/internal/storages/map-keeper/file"
+)
+
+// journalPath путь к дополнительному журналу рядом с журналом URL.
+func journalPath(filePath string, suffix string) string {
+	if len(filePath) == 0 {
+		return ""
+	}
+	return filePath + suffix
+}
+
+// CreateUser сохраняет зарегистрированного пользователя.
+func (k *Keeper) CreateUser(ctx context.Context, user models.User) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		k.mutex.Lock()
+		defer k.mutex.Unlock()
+
+		if _, ok := k.users[user.Login]; ok {
+			return dbkeeper.ErrLoginTaken
+		}
+
+		if k.usersProducer != nil {
+			if err := k.usersProducer.Write(&user); err != nil {
+				return fmt.Errorf("failed to write to file: %w", err)
+			}
+		}
+		k.users[user.Login] = user
+
+		return nil
+	}
+}
+
+// GetUserByLogin пользователь по логину.
+func (k *Keeper) GetUserByLogin(ctx context.Context, login string) (models.User, error) {
+	select {
+	case <-ctx.Done():
+		return models.User{}, ctx.Err()
+	default:
+		k.mutex.RLock()
+		defer k.mutex.RUnlock()
+
+		user, ok := k.users[login]
+		if !ok {
+			return models.User{}, dbkeeper.ErrUserNotFound
+		}
+		return user, nil
+	}
+}
+
+// ClaimURLS переносит URL анонимного пользователя fromUserID к toUserID.
+// URL зарегистрированного пользователя не переносятся.
+// Если toUserID превысил бы квоту maxLinks, не переносится ни один URL,
+// 0 - без ограничения.
+func (k *Keeper) ClaimURLS(ctx context.Context, fromUserID, toUserID string, maxLinks int) (int64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+		k.mutex.Lock()
+		defer k.mutex.Unlock()
+
+		if len(fromUserID) == 0 || k.isRegistered(fromUserID) {
+			return 0, nil
+		}
+
+		if err := k.checkQuota(toUserID, maxLinks, int(k.countUserURLS(fromUserID))); err != nil {
+			return 0, err
+		}
+
+		var claimed int64
+		for _, url := range k.storage {
+			if url.UserID != fromUserID {
+				continue
+			}
+			now := time.Now().UTC()
+			url.UserID = toUserID
+			url.UpdatedAt = &now
+			if err := k.put(url); err != nil {
+				return claimed, err
+			}
+			claimed++
+		}
+
+		return claimed, nil
+	}
+}
+
+// isRegistered вызывается под блокировкой mutex.
+func (k *Keeper) isRegistered(userID string) bool {
+	for _, user := range k.users {
+		if user.ID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func (k *Keeper) replayUsers() error {
+	return replayJournal(k, k.usersPath, func(user models.User) {
+		k.users[user.Login] = user
+	})
+}
+
+// replayJournal применяет записи дополнительного журнала path.
+// Оборванная последняя запись отбрасывается.
+func replayJournal[T any](k *Keeper, path string, apply func(T)) error {
+	c, err := file.NewConsumer(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer c.Close()
+
+	for {
+		var record T
+		err := c.Decode(&record)
+		switch {
+		case err == nil:
+			apply(record)
+			continue
+		case errors.Is(err, io.EOF):
+			return nil
+		case errors.Is(err, file.ErrTornRecord):
+			k.log.Warn("torn record at the end of file, truncating",
+				zap.String("path", path),
+				zap.Int64("offset", c.Offset()),
+			)
+			return os.Truncate(path, c.Offset())
+		default:
+			return err
+		}
+	}
+}